@@ -49,6 +49,21 @@ type Ast struct {
 	Name         string
 	Parent       *Ast
 	CPreliminary string
+	// Block : Set for the scopes of if and loop bodies
+	Block bool
+	// Compilation : What compiles the scope. Scopes that don't set it share their parent's
+	Compilation interface{}
+}
+
+// FindCompilation : Returns the compilation of a or of the closest parent that has one
+func (a *Ast) FindCompilation() interface{} {
+	for s := a; s != nil; s = s.Parent {
+		if s.Compilation != nil {
+			return s.Compilation
+		}
+	}
+
+	return nil
 }
 
 // Initialize : Initialize default Ast fields
@@ -142,10 +157,10 @@ func (a *Ast) MergeImport(m *Ast) {
 
 func (a *Ast) GetFullPath() string {
 	if a.Parent != nil {
-		return a.Parent.GetFullPath() + "__" + strings.ReplaceAll(a.Name, ".", "__")
+		return a.Parent.GetFullPath() + Separator + MangleName(a.Name)
 	}
 
-	return strings.ReplaceAll(a.Name, ".", "__")
+	return MangleName(a.Name)
 }
 
 func (a *Ast) MergeWithParents() {
@@ -164,7 +179,7 @@ func (t *Type) Initialize() {
 
 func (t *Type) GetFullPath() string {
 	if t.Scope != nil {
-		return t.Scope.GetFullPath() + "__" + t.Name
+		return t.Scope.GetFullPath() + Separator + MangleName(t.Name)
 	}
 
 	return t.Name
@@ -176,6 +191,17 @@ func (v *Variable) FromToken(tok *tokens.Field) {
 	v.Type = tok.Type
 	v.Value = tok.Value
 	v.Visibility = tok.Visibility
+	v.Mutability = tok.Mutability
+}
+
+// IsConst : Checks if the variable is a compile time constant
+func (v *Variable) IsConst() bool {
+	return v.Mutability == "const"
+}
+
+// IsImmutable : Checks if the variable can't be assigned to after it is declared
+func (v *Variable) IsImmutable() bool {
+	return v.Mutability == "const" || v.Mutability == "let"
 }
 
 func (v *Variable) FromTypeField(tok *tokens.TypeField) {
@@ -186,7 +212,7 @@ func (v *Variable) FromTypeField(tok *tokens.TypeField) {
 }
 
 func (v *Variable) GetFullPath() string {
-	return v.Scope.GetFullPath() + "__" + v.Name
+	return v.Scope.GetFullPath() + Separator + MangleName(v.Name)
 }
 
 func (m *Method) FromToken(tok *tokens.Method) {
@@ -220,5 +246,5 @@ func (m *Method) ToAst() *Ast {
 }
 
 func (m *Method) GetFullPath() string {
-	return m.Scope.GetFullPath() + "__" + m.Name
+	return m.Scope.GetFullPath() + Separator + MangleName(m.Name)
 }
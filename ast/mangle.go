@@ -0,0 +1,83 @@
+package ast
+
+import "strings"
+
+/*
+	Symbol mangling
+
+	A gecko name becomes a C symbol by joining it to the names of the scopes
+	around it with "__", count in the method Main of the package Main becomes
+	Main__Main__count. So that no name can produce the same symbol as a scope
+	path, an underscore inside a name is written as "_1" when it ends the name or
+	is followed by another underscore or a 1. my_count stays my_count but a__b
+	becomes a_1_b, which leaves every "__" in a symbol a separator. Temporaries
+	the compiler declares get a segment that starts with a number, which no gecko
+	name can, like the loop counter Main__Main__0counter.
+*/
+
+// Separator : Joins the segments of a mangled symbol
+const Separator = "__"
+
+// MangleName : Encodes name as part of a C symbol, every part of a dotted name is a segment of its own
+func MangleName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = mangleSegment(part)
+	}
+
+	return strings.Join(parts, Separator)
+}
+
+func mangleSegment(segment string) string {
+	r := ""
+	for i := 0; i < len(segment); i++ {
+		if segment[i] == '_' && (i == len(segment)-1 || segment[i+1] == '_' || segment[i+1] == '1') {
+			r += "_1"
+		} else {
+			r += segment[i : i+1]
+		}
+	}
+
+	return r
+}
+
+func isSymbolChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// Demangle : Splits a symbol made by MangleName or GetFullPath back into the gecko names it
+// was made from. Reports false for symbols gecko can't have made
+func Demangle(symbol string) ([]string, bool) {
+	segments := []string{}
+	current := ""
+
+	for i := 0; i < len(symbol); i++ {
+		c := symbol[i]
+		if c != '_' {
+			if !isSymbolChar(c) {
+				return nil, false
+			}
+			current += string(c)
+		} else if i+1 == len(symbol) {
+			return nil, false
+		} else if symbol[i+1] == '_' {
+			if len(current) == 0 {
+				return nil, false
+			}
+			segments = append(segments, current)
+			current = ""
+			i++
+		} else if symbol[i+1] == '1' {
+			current += "_"
+			i++
+		} else {
+			current += "_"
+		}
+	}
+
+	if len(current) == 0 {
+		return nil, false
+	}
+
+	return append(segments, current), true
+}
@@ -0,0 +1,58 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMangleName(t *testing.T) {
+	tests := []struct {
+		name   string
+		symbol string
+	}{
+		{"count", "count"},
+		{"my_count", "my_count"},
+		{"a__b", "a_1_b"},
+		{"b_", "b_1"},
+		{"a_1", "a_11"},
+		{"a.b", "a__b"},
+		{"list_.len", "list_1__len"},
+	}
+
+	for _, test := range tests {
+		if symbol := MangleName(test.name); symbol != test.symbol {
+			t.Errorf("MangleName(%q) = %q, want %q", test.name, symbol, test.symbol)
+		}
+	}
+}
+
+func TestDemangleRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"Main", "Main", "count"},
+		{"Main", "a__b"},
+		{"Main", "b_"},
+		{"Main", "a_1"},
+		{"Main", "my_count", "_x"},
+		{"Main", "Main", "0counter"},
+	}
+
+	for _, names := range tests {
+		symbol := MangleName(names[0])
+		for _, name := range names[1:] {
+			symbol += Separator + MangleName(name)
+		}
+
+		demangled, ok := Demangle(symbol)
+		if !ok || !reflect.DeepEqual(demangled, names) {
+			t.Errorf("Demangle(%q) = %q, %v, want %q", symbol, demangled, ok, names)
+		}
+	}
+}
+
+func TestDemangleRejects(t *testing.T) {
+	for _, symbol := range []string{"", "a_", "__a", "a____b", "a__", "a-b"} {
+		if names, ok := Demangle(symbol); ok {
+			t.Errorf("Demangle(%q) = %q, want it rejected", symbol, names)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+// Package bytecode defines gecko's portable bytecode, compiles lowered modules
+// to it and lists it.
+//
+// A .gbc file holds one program: the tables of strings, types, structs,
+// constants and globals it uses and the code of its functions. The code is
+// for a stack machine, instructions push the values they compute and pop their
+// operands, so a program runs wherever gecko itself runs, without a C
+// compiler. Files start with Magic and the Version of the format they were
+// written in, readers refuse versions they don't know.
+package bytecode
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ir"
+)
+
+/*
+	Layout
+
+	Numbers are unsigned varints unless said otherwise, integer constants are
+	signed varints, float constants the 8 little endian bytes of a float64 and
+	jump targets 4 little endian bytes, so they can be patched once the code
+	they jump to was placed. Strings, types, structs, constants, globals and
+	functions are referred to by their index in their table, references that
+	can be missing by index+1 with 0 for none.
+
+		magic     "GBC\x00"
+		version   2 little endian bytes
+		name      string
+		strings   count, then the length and bytes of each
+		types     count, then kind, name, gecko name, bits, flags, elem+1, key+1 and struct+1
+		structs   count, then name, gecko name, field count and the name and type of each field
+		constants count, then type and value
+		globals   count, then name and type
+		functions count, then name, result type, param count, variables, code and positions
+		main      index+1 of the function programs start in, 0 for libraries
+		init      index+1 of the function that initialises the globals, 0 if there is none
+
+	A function's variables are its params followed by its locals, each a name
+	and a type. Positions map code offsets to the gecko source, each is an
+	offset, a file name, a line and a column and holds until the next one.
+*/
+
+// Magic : The bytes every bytecode file starts with
+const Magic = "GBC\x00"
+
+// Version : The version of the format this package reads and writes
+const Version = 1
+
+// Flags of types
+const (
+	flagSigned = 1 << iota
+	flagPointer
+)
+
+// Program : A compiled module
+type Program struct {
+	Name      string
+	Strings   []string
+	Types     []*ir.Type
+	Structs   []*ir.Struct
+	Constants []*ir.Const
+	Globals   []*ir.Variable
+	Functions []*Function
+	// Main and Init are indexes of Functions, -1 if there is no such function
+	Main int
+	Init int
+}
+
+// Function : The code of a function
+type Function struct {
+	Name   string
+	Result *ir.Type
+	Params int
+	// Vars : The params followed by the locals
+	Vars      []*ir.Variable
+	Code      []byte
+	Positions []Position
+}
+
+// Position : Where the code from Offset up to the next position was compiled from
+type Position struct {
+	Offset int
+	Pos    lexer.Position
+}
+
+// Opcode : Names an instruction
+type Opcode byte
+
+// The instructions. Operands are written after the opcode, values are taken from and pushed onto the stack
+const (
+	// OpConst : (const) pushes a constant
+	OpConst Opcode = iota + 1
+	// OpNull : pushes the nil pointer
+	OpNull
+	// OpLoad : (var) pushes a copy of a variable of the function
+	OpLoad
+	// OpLoadGlobal : (global) pushes a copy of a global
+	OpLoadGlobal
+	// OpExtern : (string) pushes the C symbol named by the string
+	OpExtern
+	// OpAddr : (var) pushes a reference to a variable of the function
+	OpAddr
+	// OpAddrGlobal : (global) pushes a reference to a global
+	OpAddrGlobal
+	// OpAddrField : (string) pops a reference to an instance and pushes a reference to its field
+	OpAddrField
+	// OpStore : pops a reference and a value and stores the value
+	OpStore
+	// OpField : (string) pops a value and pushes its field, or its len or cap
+	OpField
+	// OpUnary : (string type) pops X and pushes op X, type is the type of X
+	OpUnary
+	// OpBinary : (string type type type) pops Y and X and pushes X op Y, the types are those of X, Y and the result
+	OpBinary
+	// OpConvert : (type type) pops a value of the first type and pushes it converted to the second
+	OpConvert
+	// OpFormat : (count string [type string]...) pops count values and pushes the string of the parts with the values in between
+	OpFormat
+	// OpCall : (function) pops the arguments of the function, calls it and pushes its result
+	OpCall
+	// OpCallC : (string count) pops count arguments, calls the C function and pushes its result
+	OpCallC
+	// OpIntrinsic : (string type type count) pops count arguments and a reference to a list or map of the
+	// first type, runs the intrinsic and pushes its result, of the second type
+	OpIntrinsic
+	// OpList : (type count) pops count items and pushes a list of them
+	OpList
+	// OpMap : (type count) pops count keys and values, keys first, and pushes a map of them
+	OpMap
+	// OpStruct : (type count [string]...) pops count values and pushes an instance with the named fields set to them
+	OpStruct
+	// OpPop : drops a value
+	OpPop
+	// OpJump : (target) continues at the target
+	OpJump
+	// OpJumpIfFalse : (target) pops a value and continues at the target if it is false
+	OpJumpIfFalse
+	// OpReturn : pops a value and returns it
+	OpReturn
+	// OpReturnVoid : returns without a value
+	OpReturnVoid
+	// OpUnreachable : stops the program, execution never gets here
+	OpUnreachable
+)
+
+// Operand : What an operand refers to
+type Operand int
+
+// The kinds of operands
+const (
+	Const Operand = iota
+	Var
+	Global
+	String
+	Type
+	Func
+	Count
+	Target
+)
+
+// opcode : How an instruction is written
+type opcode struct {
+	name     string
+	operands []Operand
+	// repeat : Operands that follow the others once for every item counted by the first Count operand
+	repeat []Operand
+}
+
+var opcodes = map[Opcode]opcode{
+	OpConst:       {name: "const", operands: []Operand{Const}},
+	OpNull:        {name: "null"},
+	OpLoad:        {name: "load", operands: []Operand{Var}},
+	OpLoadGlobal:  {name: "load.global", operands: []Operand{Global}},
+	OpExtern:      {name: "extern", operands: []Operand{String}},
+	OpAddr:        {name: "addr", operands: []Operand{Var}},
+	OpAddrGlobal:  {name: "addr.global", operands: []Operand{Global}},
+	OpAddrField:   {name: "addr.field", operands: []Operand{String}},
+	OpStore:       {name: "store"},
+	OpField:       {name: "field", operands: []Operand{String}},
+	OpUnary:       {name: "unary", operands: []Operand{String, Type}},
+	OpBinary:      {name: "binary", operands: []Operand{String, Type, Type, Type}},
+	OpConvert:     {name: "convert", operands: []Operand{Type, Type}},
+	OpFormat:      {name: "format", operands: []Operand{Count, String}, repeat: []Operand{Type, String}},
+	OpCall:        {name: "call", operands: []Operand{Func}},
+	OpCallC:       {name: "call.c", operands: []Operand{String, Count}},
+	OpIntrinsic:   {name: "intrinsic", operands: []Operand{String, Type, Type, Count}},
+	OpList:        {name: "list", operands: []Operand{Type, Count}},
+	OpMap:         {name: "map", operands: []Operand{Type, Count}},
+	OpStruct:      {name: "struct", operands: []Operand{Type, Count}, repeat: []Operand{String}},
+	OpPop:         {name: "pop"},
+	OpJump:        {name: "jump", operands: []Operand{Target}},
+	OpJumpIfFalse: {name: "jump.false", operands: []Operand{Target}},
+	OpReturn:      {name: "return"},
+	OpReturnVoid:  {name: "return.void"},
+	OpUnreachable: {name: "unreachable"},
+}
+
+// String : Returns the name of the instruction
+func (op Opcode) String() string {
+	if o, ok := opcodes[op]; ok {
+		return o.name
+	}
+
+	return fmt.Sprintf("op%d", byte(op))
+}
+
+// Instruction : A decoded instruction
+type Instruction struct {
+	Offset   int
+	Op       Opcode
+	Operands []int
+	Pos      lexer.Position
+}
+
+// Error : A module that can't be compiled to bytecode, a file that isn't bytecode this package can read, or code that doesn't decode
+type Error struct {
+	Message string
+	// Pos : Where the code that couldn't be compiled is, empty for programs that can't be decoded
+	Pos lexer.Position
+}
+
+func (e *Error) Error() string {
+	return "bytecode: " + e.Message
+}
+
+func errorf(format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// Instructions : Decodes the code of f, checking that its operands refer to things p has
+func (f *Function) Instructions(p *Program) ([]Instruction, error) {
+	limits := map[Operand]int{
+		Const:  len(p.Constants),
+		Var:    len(f.Vars),
+		Global: len(p.Globals),
+		String: len(p.Strings),
+		Type:   len(p.Types),
+		Func:   len(p.Functions),
+		Target: len(f.Code) + 1,
+	}
+
+	r := []Instruction{}
+	starts := map[int]bool{}
+	position := 0
+
+	for offset := 0; offset < len(f.Code); {
+		op := Opcode(f.Code[offset])
+		o, ok := opcodes[op]
+		if !ok {
+			return nil, errorf("%s has an unknown opcode %d at %04x", f.Name, op, offset)
+		}
+
+		for position+1 < len(f.Positions) && f.Positions[position+1].Offset <= offset {
+			position++
+		}
+
+		i := Instruction{Offset: offset, Op: op}
+		if position < len(f.Positions) {
+			i.Pos = f.Positions[position].Pos
+		}
+		starts[offset] = true
+		offset++
+
+		count := -1
+		read := func(kind Operand) error {
+			var n int
+			if kind == Target {
+				if offset+4 > len(f.Code) {
+					return errorf("%s ends in the middle of the instruction at %04x", f.Name, i.Offset)
+				}
+				n = int(binary.LittleEndian.Uint32(f.Code[offset:]))
+				offset += 4
+			} else {
+				v, size := binary.Uvarint(f.Code[offset:])
+				if size <= 0 || v > 1<<31 {
+					return errorf("%s has a malformed operand at %04x", f.Name, offset)
+				}
+				n = int(v)
+				offset += size
+			}
+
+			if limit, ok := limits[kind]; ok && n >= limit {
+				return errorf("%s refers to a missing %s %d at %04x", f.Name, kind, n, i.Offset)
+			} else if kind == Count && count < 0 {
+				count = n
+			}
+
+			i.Operands = append(i.Operands, n)
+			return nil
+		}
+
+		for _, kind := range o.operands {
+			if err := read(kind); err != nil {
+				return nil, err
+			}
+		}
+		for j := 0; j < count && len(o.repeat) > 0; j++ {
+			for _, kind := range o.repeat {
+				if err := read(kind); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		r = append(r, i)
+	}
+
+	for _, i := range r {
+		if i.Op == OpJump || i.Op == OpJumpIfFalse {
+			if target := i.Operands[0]; !starts[target] {
+				return nil, errorf("%s jumps to %04x, which isn't the start of an instruction", f.Name, target)
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// String : Returns what the operand refers to
+func (o Operand) String() string {
+	return []string{"constant", "variable", "global", "string", "type", "function", "count", "target"}[o]
+}
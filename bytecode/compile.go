@@ -0,0 +1,492 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ir"
+)
+
+/*
+	Compiling
+
+	Functions are compiled block by block in the order of the IR. Values are
+	trees, so they become the code that pushes their operands followed by the
+	instruction that combines them. Conversions the IR leaves implicit, like
+	storing an int in an i64, are written out as convert instructions, so the
+	machine running the code never needs to know the type of a destination.
+	Jumps to the block that follows are left out.
+
+	Globals are initialised by a function of their own, Program.Init, which
+	runs before Main. Constants of functions are stored when the function
+	starts, like the static constants they become in C.
+*/
+
+type compilation struct {
+	p         *Program
+	strings   map[string]int
+	types     map[string]int
+	structs   map[*ir.Struct]int
+	constants map[string]int
+	globals   map[*ir.Variable]int
+	functions map[string]int
+	err       error
+}
+
+// function : The state of the function being compiled
+type function struct {
+	c    *compilation
+	f    *Function
+	vars map[*ir.Variable]int
+	// blocks : Where blocks start, patches are the jumps to blocks that aren't placed yet
+	blocks  map[*ir.Block]int
+	patches map[int]*ir.Block
+	pos     lexer.Position
+}
+
+// Compile : Compiles m. Calls to functions of other modules can't be compiled, a program has to
+// be a single module to be run from its bytecode
+func Compile(m *ir.Module) (*Program, error) {
+	c := &compilation{
+		p:         &Program{Main: -1, Init: -1},
+		strings:   map[string]int{},
+		types:     map[string]int{},
+		structs:   map[*ir.Struct]int{},
+		constants: map[string]int{},
+		globals:   map[*ir.Variable]int{},
+		functions: map[string]int{},
+	}
+	c.p.Name = m.Name
+	c.str(m.Name)
+
+	for _, g := range m.Globals {
+		c.globals[g] = len(c.p.Globals)
+		c.p.Globals = append(c.p.Globals, &ir.Variable{Name: g.Name, Type: g.Type})
+		c.str(g.Name)
+		c.typ(g.Type)
+	}
+
+	// Every function is declared before any is compiled, calls need to know what the functions they call take
+	for i, f := range m.Functions {
+		c.functions[f.Name] = i
+		c.p.Functions = append(c.p.Functions, &Function{Name: f.Name, Result: f.Result, Params: len(f.Params)})
+		for _, v := range variables(f) {
+			c.p.Functions[i].Vars = append(c.p.Functions[i].Vars, &ir.Variable{Name: v.Name, Type: v.Type})
+			c.str(v.Name)
+			c.typ(v.Type)
+		}
+		if f == m.Main {
+			c.p.Main = i
+		}
+	}
+
+	for i, f := range m.Functions {
+		c.function(f, c.p.Functions[i])
+	}
+
+	if init := c.init(m); init != nil {
+		c.p.Init = len(c.p.Functions)
+		c.p.Functions = append(c.p.Functions, init)
+	}
+
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.p, nil
+}
+
+func (c *compilation) fail(pos lexer.Position, format string, args ...interface{}) {
+	if c.err == nil {
+		c.err = errorf(format, args...)
+		c.err.(*Error).Pos = pos
+	}
+}
+
+func (c *compilation) str(s string) int {
+	if i, ok := c.strings[s]; ok {
+		return i
+	}
+
+	c.strings[s] = len(c.p.Strings)
+	c.p.Strings = append(c.p.Strings, s)
+	return c.strings[s]
+}
+
+// typeKey : Identifies t, types the lowering created separately but that are the same share an index
+func typeKey(t *ir.Type) string {
+	if t == nil {
+		return ""
+	}
+
+	return strconv.Itoa(int(t.Kind)) + " " + strconv.Quote(t.Name) + " " + strconv.Quote(t.GeckoName) + " " +
+		strconv.Itoa(int(t.Bits)) + " " + strconv.FormatBool(t.Signed) + " " + strconv.FormatBool(t.Pointer) + " " +
+		structName(t.Struct) + " (" + typeKey(t.Elem) + ") (" + typeKey(t.Key) + ")"
+}
+
+func structName(s *ir.Struct) string {
+	if s == nil {
+		return ""
+	}
+
+	return strconv.Quote(s.Name)
+}
+
+// field : Returns the field of s called name or nil
+func field(s *ir.Struct, name string) *ir.Field {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// variables : Returns the params of f followed by its locals
+func variables(f *ir.Function) []*ir.Variable {
+	return append(append([]*ir.Variable{}, f.Params...), f.Locals...)
+}
+
+func (c *compilation) typ(t *ir.Type) int {
+	key := typeKey(t)
+	if i, ok := c.types[key]; ok {
+		return i
+	}
+
+	i := len(c.p.Types)
+	c.types[key] = i
+	c.p.Types = append(c.p.Types, t)
+	c.str(t.Name)
+	c.str(t.GeckoName)
+
+	if t.Elem != nil {
+		c.typ(t.Elem)
+	}
+	if t.Key != nil {
+		c.typ(t.Key)
+	}
+	if t.Struct != nil {
+		c.structure(t.Struct)
+	}
+	return i
+}
+
+func (c *compilation) structure(s *ir.Struct) {
+	if _, ok := c.structs[s]; ok {
+		return
+	}
+
+	c.structs[s] = len(c.p.Structs)
+	c.p.Structs = append(c.p.Structs, s)
+	c.str(s.Name)
+	c.str(s.GeckoName)
+	for _, f := range s.Fields {
+		c.str(f.Name)
+		c.typ(f.Type)
+	}
+}
+
+func (c *compilation) constant(v *ir.Const) int {
+	key := typeKey(v.T) + " " + strconv.FormatInt(v.Int, 10) + " " + strconv.FormatUint(math.Float64bits(v.Float), 16) + " " + strconv.Quote(v.Str)
+	if i, ok := c.constants[key]; ok {
+		return i
+	}
+
+	c.typ(v.T)
+	if v.T.Kind == ir.String {
+		c.str(v.Str)
+	}
+
+	c.constants[key] = len(c.p.Constants)
+	c.p.Constants = append(c.p.Constants, v)
+	return c.constants[key]
+}
+
+// init : Compiles the initialisation of m's globals, nil if none has a value
+func (c *compilation) init(m *ir.Module) *Function {
+	f := &Function{Name: "init", Result: ir.VoidType}
+	fn := c.newFunction(f)
+
+	for _, g := range m.Globals {
+		if g.Value != nil {
+			fn.at(g.Pos)
+			fn.valueAs(g.Value, g.Type)
+			fn.emit(OpAddrGlobal, c.globals[g])
+			fn.emit(OpStore)
+		}
+	}
+
+	if len(f.Code) == 0 {
+		return nil
+	}
+
+	c.typ(ir.VoidType)
+	fn.emit(OpReturnVoid)
+	return f
+}
+
+func (c *compilation) newFunction(f *Function) *function {
+	c.str(f.Name)
+	c.typ(f.Result)
+	return &function{c: c, f: f, vars: map[*ir.Variable]int{}, blocks: map[*ir.Block]int{}, patches: map[int]*ir.Block{}}
+}
+
+func (c *compilation) function(irf *ir.Function, f *Function) {
+	fn := c.newFunction(f)
+
+	for i, v := range variables(irf) {
+		fn.vars[v] = i
+	}
+
+	fn.at(irf.Pos)
+	for _, l := range irf.Locals {
+		if l.Value != nil {
+			fn.valueAs(l.Value, l.Type)
+			fn.emit(OpAddr, fn.vars[l])
+			fn.emit(OpStore)
+		}
+	}
+
+	for i, b := range irf.Blocks {
+		fn.blocks[b] = len(f.Code)
+		for _, instr := range b.Instrs {
+			fn.instr(instr)
+		}
+
+		var next *ir.Block
+		if i+1 < len(irf.Blocks) {
+			next = irf.Blocks[i+1]
+		}
+		fn.terminator(b.Term, irf, next)
+	}
+
+	for at, b := range fn.patches {
+		binary.LittleEndian.PutUint32(f.Code[at:], uint32(fn.blocks[b]))
+	}
+}
+
+// at : Makes the code that follows belong to pos
+func (fn *function) at(pos lexer.Position) {
+	if pos.Line == 0 || pos == fn.pos {
+		return
+	}
+
+	fn.pos = pos
+	fn.c.str(pos.Filename)
+	fn.f.Positions = append(fn.f.Positions, Position{Offset: len(fn.f.Code), Pos: pos})
+}
+
+func (fn *function) emit(op Opcode, operands ...int) {
+	fn.f.Code = append(fn.f.Code, byte(op))
+	for _, n := range operands {
+		var b [binary.MaxVarintLen64]byte
+		fn.f.Code = append(fn.f.Code, b[:binary.PutUvarint(b[:], uint64(n))]...)
+	}
+}
+
+// jump : Emits a jump to b, which is patched once every block was placed
+func (fn *function) jump(op Opcode, b *ir.Block) {
+	fn.f.Code = append(fn.f.Code, byte(op))
+	fn.patches[len(fn.f.Code)] = b
+	fn.f.Code = append(fn.f.Code, 0, 0, 0, 0)
+}
+
+// valueAs : Pushes v converted to type to. Numbers are converted even if v has that type already,
+// arithmetic leaves them wider than their type
+func (fn *function) valueAs(v ir.Value, to *ir.Type) {
+	fn.value(v)
+
+	_, unary := v.(*ir.Unary)
+	_, binary := v.(*ir.Binary)
+	if typeKey(v.Type()) != typeKey(to) || unary || binary {
+		fn.emit(OpConvert, fn.c.typ(v.Type()), fn.c.typ(to))
+	}
+}
+
+func (fn *function) instr(i ir.Instr) {
+	fn.at(i.Position())
+
+	switch i := i.(type) {
+	case *ir.Assign:
+		fn.valueAs(i.Value, i.Dest.Type())
+		fn.address(i.Dest)
+		fn.emit(OpStore)
+	case *ir.Eval:
+		fn.value(i.Value)
+		fn.emit(OpPop)
+	}
+}
+
+func (fn *function) terminator(t ir.Terminator, irf *ir.Function, next *ir.Block) {
+	if t == nil {
+		fn.emit(OpUnreachable)
+		return
+	}
+	fn.at(t.Position())
+
+	switch t := t.(type) {
+	case *ir.Jump:
+		if t.Target != next {
+			fn.jump(OpJump, t.Target)
+		}
+	case *ir.Branch:
+		fn.value(t.Cond)
+		fn.jump(OpJumpIfFalse, t.Else)
+		if t.Then != next {
+			fn.jump(OpJump, t.Then)
+		}
+	case *ir.Return:
+		if t.Value == nil {
+			fn.emit(OpReturnVoid)
+			return
+		}
+		fn.valueAs(t.Value, irf.Result)
+		fn.emit(OpReturn)
+	default:
+		fn.emit(OpUnreachable)
+	}
+}
+
+// variable : Emits op with the index of v, or global with it if v is a global
+func (fn *function) variable(op Opcode, global Opcode, v *ir.Variable) {
+	if i, ok := fn.vars[v]; ok {
+		fn.emit(op, i)
+	} else if i, ok := fn.c.globals[v]; ok {
+		fn.emit(global, i)
+	} else {
+		fn.c.fail(fn.pos, "'%s' isn't a variable of %s", v.Name, fn.f.Name)
+	}
+}
+
+// address : Pushes a reference to the variable or field v
+func (fn *function) address(v ir.Value) {
+	switch v := v.(type) {
+	case *ir.Ref:
+		fn.variable(OpAddr, OpAddrGlobal, v.Var)
+	case *ir.Member:
+		fn.address(v.X)
+		fn.emit(OpAddrField, fn.c.str(v.Name))
+	default:
+		fn.c.fail(fn.pos, "%s can't be assigned to", ir.Repr(v))
+	}
+}
+
+// values : Pushes vs, each converted to the type types returns for its index
+func (fn *function) values(vs []ir.Value, types func(int) *ir.Type) {
+	for i, v := range vs {
+		fn.valueAs(v, types(i))
+	}
+}
+
+func (fn *function) value(v ir.Value) {
+	c := fn.c
+
+	switch v := v.(type) {
+	case *ir.Const:
+		fn.emit(OpConst, c.constant(v))
+	case *ir.Null:
+		fn.emit(OpNull)
+	case *ir.Ref:
+		fn.variable(OpLoad, OpLoadGlobal, v.Var)
+	case *ir.Extern:
+		fn.emit(OpExtern, c.str(v.Name))
+	case *ir.Member:
+		fn.value(v.X)
+		fn.emit(OpField, c.str(v.Name))
+	case *ir.Unary:
+		fn.value(v.X)
+		fn.emit(OpUnary, c.str(v.Op), c.typ(v.X.Type()))
+	case *ir.Binary:
+		fn.value(v.X)
+		fn.value(v.Y)
+		fn.emit(OpBinary, c.str(v.Op), c.typ(v.X.Type()), c.typ(v.Y.Type()), c.typ(v.T))
+	case *ir.Cast:
+		fn.value(v.X)
+		fn.emit(OpConvert, c.typ(v.X.Type()), c.typ(v.T))
+	case *ir.Format:
+		operands := []int{len(v.Args), c.str(v.Parts[0])}
+		for i, arg := range v.Args {
+			fn.value(arg)
+			operands = append(operands, c.typ(arg.Type()), c.str(v.Parts[i+1]))
+		}
+		fn.emit(OpFormat, operands...)
+	case *ir.CString:
+		fn.value(v.X)
+	case *ir.Call:
+		fn.call(v)
+	case *ir.Intrinsic:
+		t := v.Args[0].Type()
+		fn.address(v.Args[0])
+		for i, arg := range v.Args[1:] {
+			// The item pushed onto a list and the value a map key is set to are stored in the list or map
+			if v.Op == ir.ListPush || v.Op == ir.MapSet && i == 1 {
+				fn.valueAs(arg, t.Elem)
+			} else {
+				fn.value(arg)
+			}
+		}
+		fn.emit(OpIntrinsic, c.str(string(v.Op)), c.typ(t), c.typ(v.T), len(v.Args)-1)
+	case *ir.ListLit:
+		fn.values(v.Items, func(int) *ir.Type { return v.T.Elem })
+		fn.emit(OpList, c.typ(v.T), len(v.Items))
+	case *ir.MapLit:
+		for i := range v.Keys {
+			fn.value(v.Keys[i])
+			fn.valueAs(v.Values[i], v.T.Elem)
+		}
+		fn.emit(OpMap, c.typ(v.T), len(v.Keys))
+	case *ir.StructLit:
+		if v.T.Struct == nil {
+			c.fail(fn.pos, "%s isn't a class", v.T)
+			return
+		}
+
+		operands := []int{c.typ(v.T), len(v.Fields)}
+		for i, name := range v.Fields {
+			field := field(v.T.Struct, name)
+			if field == nil {
+				c.fail(fn.pos, "%s has no field '%s'", v.T.Struct.GeckoName, name)
+				return
+			}
+
+			fn.valueAs(v.Values[i], field.Type)
+			operands = append(operands, c.str(name))
+		}
+		fn.emit(OpStruct, operands...)
+	default:
+		c.fail(fn.pos, "%s can't be compiled", ir.Repr(v))
+	}
+}
+
+func (fn *function) call(v *ir.Call) {
+	c := fn.c
+
+	if v.External {
+		// C functions get their arguments as they are, like the varargs of printf
+		for _, arg := range v.Args {
+			fn.value(arg)
+		}
+		fn.emit(OpCallC, c.str(v.Func), len(v.Args))
+		if v.T.Kind != ir.Void {
+			fn.emit(OpConvert, c.typ(ir.UnknownType), c.typ(v.T))
+		}
+		return
+	}
+
+	i, ok := c.functions[v.Func]
+	if !ok {
+		c.fail(fn.pos, "'%s' belongs to a module built on its own, a program has to be a single module to be compiled to bytecode", v.Func)
+		return
+	}
+
+	f := c.p.Functions[i]
+	if len(v.Args) != f.Params {
+		c.fail(fn.pos, "%s takes %d arguments but is called with %d", f.Name, f.Params, len(v.Args))
+		return
+	}
+
+	fn.values(v.Args, func(i int) *ir.Type { return f.Vars[i].Type })
+	fn.emit(OpCall, i)
+}
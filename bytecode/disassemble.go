@@ -0,0 +1,148 @@
+package bytecode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/neutrino2211/Gecko/ir"
+)
+
+/*
+	Disassembling
+
+	`gecko disassemble` lists a program's tables and the instructions of its
+	functions, one per line with its offset and operands. Operands that refer
+	to a table are followed by what they refer to, so the listing can be read
+	without looking the indexes up. Like the IR listing it is for people.
+*/
+
+// Disassemble : Lists p, it fails if the code of a function doesn't decode
+func Disassemble(p *Program) (string, error) {
+	r := "program " + p.Name + ", bytecode version " + strconv.Itoa(Version) + "\n"
+
+	if len(p.Types) > 0 {
+		r += "\ntypes\n"
+	}
+	for i, t := range p.Types {
+		r += fmt.Sprintf("\t%d\t%s\n", i, typeString(t))
+	}
+
+	for _, s := range p.Structs {
+		r += "\nstruct " + s.Name + " {\n"
+		for _, f := range s.Fields {
+			r += "\t" + f.Name + " " + typeString(f.Type) + "\n"
+		}
+		r += "}\n"
+	}
+
+	if len(p.Constants) > 0 {
+		r += "\nconstants\n"
+	}
+	for i, c := range p.Constants {
+		r += fmt.Sprintf("\t%d\t%s %s\n", i, typeString(c.T), ir.Repr(c))
+	}
+
+	if len(p.Globals) > 0 {
+		r += "\nglobals\n"
+	}
+	for i, g := range p.Globals {
+		r += fmt.Sprintf("\t%d\t%s %s\n", i, g.Name, typeString(g.Type))
+	}
+
+	for i, f := range p.Functions {
+		code, err := f.Instructions(p)
+		if err != nil {
+			return "", err
+		}
+
+		r += "\n" + functionHeader(p, i) + "\n"
+		for j, v := range f.Vars {
+			kind := "local"
+			if j < f.Params {
+				kind = "param"
+			}
+			r += fmt.Sprintf("\t%s %d %s %s\n", kind, j, v.Name, typeString(v.Type))
+		}
+
+		pos := ""
+		for _, instr := range code {
+			if at := instr.Pos.String(); instr.Pos.Line != 0 && at != pos {
+				pos = at
+				r += "; " + at + "\n"
+			}
+			r += instructionString(p, f, instr) + "\n"
+		}
+	}
+
+	return r, nil
+}
+
+func functionHeader(p *Program, i int) string {
+	f := p.Functions[i]
+
+	params := []string{}
+	for _, v := range f.Vars[:f.Params] {
+		params = append(params, v.Name+" "+typeString(v.Type))
+	}
+
+	r := "func " + f.Name + "(" + strings.Join(params, ", ") + ") " + typeString(f.Result)
+	if i == p.Main {
+		r += " main"
+	} else if i == p.Init {
+		r += " init"
+	}
+	return r
+}
+
+// typeString : Returns t as gecko writes it, followed by its C name if that says more
+func typeString(t *ir.Type) string {
+	if t.Kind == ir.Opaque && t.Name != "" && t.Name != t.GeckoName {
+		return t.String() + " (" + t.Name + ")"
+	}
+
+	return t.String()
+}
+
+func instructionString(p *Program, f *Function, i Instruction) string {
+	o := opcodes[i.Op]
+	kinds := o.operands
+	for len(kinds) < len(i.Operands) {
+		kinds = append(kinds, o.repeat...)
+	}
+
+	operands := []string{}
+	notes := []string{}
+	for j, n := range i.Operands {
+		switch kinds[j] {
+		case Target:
+			operands = append(operands, fmt.Sprintf("%04x", n))
+			continue
+		case Count:
+			operands = append(operands, strconv.Itoa(n))
+			continue
+		}
+
+		operands = append(operands, strconv.Itoa(n))
+		switch kinds[j] {
+		case Const:
+			notes = append(notes, ir.Repr(p.Constants[n]))
+		case Var:
+			notes = append(notes, f.Vars[n].Name)
+		case Global:
+			notes = append(notes, p.Globals[n].Name)
+		case String:
+			notes = append(notes, strconv.Quote(p.Strings[n]))
+		case Type:
+			notes = append(notes, typeString(p.Types[n]))
+		case Func:
+			notes = append(notes, p.Functions[n].Name)
+		}
+	}
+
+	r := strings.TrimRight(fmt.Sprintf("\t%04x\t%-12s%s", i.Offset, i.Op, strings.Join(operands, " ")), " ")
+	if len(notes) > 0 {
+		r += "\t; " + strings.Join(notes, ", ")
+	}
+	return r
+}
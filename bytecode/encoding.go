@@ -0,0 +1,378 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ir"
+)
+
+/*
+	Encoding
+
+	Encode writes a program in the layout described in bytecode.go and Decode
+	reads it back. Types and structs become ir types again, so whatever runs
+	the program can use the same conversions as the rest of gecko.
+*/
+
+type encoder struct {
+	p       *Program
+	buf     []byte
+	strings map[string]int
+	types   map[string]int
+	structs map[*ir.Struct]int
+}
+
+func (e *encoder) uvarint(n int) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf = append(e.buf, b[:binary.PutUvarint(b[:], uint64(n))]...)
+}
+
+func (e *encoder) varint(n int64) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf = append(e.buf, b[:binary.PutVarint(b[:], n)]...)
+}
+
+// str : Writes the index of s, which has to be in the program's strings
+func (e *encoder) str(s string) {
+	e.uvarint(e.strings[s])
+}
+
+func (e *encoder) typ(t *ir.Type) {
+	e.uvarint(e.types[typeKey(t)])
+}
+
+// ref : Writes index+1, or 0 if there is no such thing
+func (e *encoder) ref(index int, ok bool) {
+	if !ok {
+		e.uvarint(0)
+		return
+	}
+
+	e.uvarint(index + 1)
+}
+
+// Encode : Returns p in the bytecode format
+func (p *Program) Encode() []byte {
+	e := &encoder{p: p, strings: map[string]int{}, types: map[string]int{}, structs: map[*ir.Struct]int{}}
+	for i, s := range p.Strings {
+		if _, ok := e.strings[s]; !ok {
+			e.strings[s] = i
+		}
+	}
+	// Types that are the same share an index, whichever of them a type refers to
+	for i, t := range p.Types {
+		if _, ok := e.types[typeKey(t)]; !ok {
+			e.types[typeKey(t)] = i
+		}
+	}
+	for i, s := range p.Structs {
+		e.structs[s] = i
+	}
+
+	e.buf = append(e.buf, Magic...)
+	e.buf = append(e.buf, byte(Version), byte(Version>>8))
+	e.str(p.Name)
+
+	e.uvarint(len(p.Strings))
+	for _, s := range p.Strings {
+		e.uvarint(len(s))
+		e.buf = append(e.buf, s...)
+	}
+
+	e.uvarint(len(p.Types))
+	for _, t := range p.Types {
+		flags := 0
+		if t.Signed {
+			flags |= flagSigned
+		}
+		if t.Pointer {
+			flags |= flagPointer
+		}
+
+		e.uvarint(int(t.Kind))
+		e.str(t.Name)
+		e.str(t.GeckoName)
+		e.uvarint(int(t.Bits))
+		e.uvarint(flags)
+		elem, ok := e.types[typeKey(t.Elem)]
+		e.ref(elem, ok && t.Elem != nil)
+		key, ok := e.types[typeKey(t.Key)]
+		e.ref(key, ok && t.Key != nil)
+		s, ok := e.structs[t.Struct]
+		e.ref(s, ok)
+	}
+
+	e.uvarint(len(p.Structs))
+	for _, s := range p.Structs {
+		e.str(s.Name)
+		e.str(s.GeckoName)
+		e.uvarint(len(s.Fields))
+		for _, f := range s.Fields {
+			e.str(f.Name)
+			e.typ(f.Type)
+		}
+	}
+
+	e.uvarint(len(p.Constants))
+	for _, c := range p.Constants {
+		e.typ(c.T)
+		switch c.T.Kind {
+		case ir.Float:
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], math.Float64bits(c.Float))
+			e.buf = append(e.buf, b[:]...)
+		case ir.String:
+			e.str(c.Str)
+		default:
+			e.varint(c.Int)
+		}
+	}
+
+	e.uvarint(len(p.Globals))
+	for _, g := range p.Globals {
+		e.str(g.Name)
+		e.typ(g.Type)
+	}
+
+	e.uvarint(len(p.Functions))
+	for _, f := range p.Functions {
+		e.str(f.Name)
+		e.typ(f.Result)
+		e.uvarint(f.Params)
+		e.uvarint(len(f.Vars))
+		for _, v := range f.Vars {
+			e.str(v.Name)
+			e.typ(v.Type)
+		}
+
+		e.uvarint(len(f.Code))
+		e.buf = append(e.buf, f.Code...)
+
+		e.uvarint(len(f.Positions))
+		for _, pos := range f.Positions {
+			e.uvarint(pos.Offset)
+			e.str(pos.Pos.Filename)
+			e.uvarint(pos.Pos.Line)
+			e.uvarint(pos.Pos.Column)
+		}
+	}
+
+	e.ref(p.Main, p.Main >= 0)
+	e.ref(p.Init, p.Init >= 0)
+	return e.buf
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+	err  error
+	p    *Program
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = errorf(format, args...)
+	}
+}
+
+func (d *decoder) uvarint() int {
+	if d.err != nil {
+		return 0
+	}
+
+	n, size := binary.Uvarint(d.data[d.pos:])
+	if size <= 0 || n > 1<<31 {
+		d.fail("malformed number at byte %d", d.pos)
+		return 0
+	}
+
+	d.pos += size
+	return int(n)
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+
+	n, size := binary.Varint(d.data[d.pos:])
+	if size <= 0 {
+		d.fail("malformed number at byte %d", d.pos)
+		return 0
+	}
+
+	d.pos += size
+	return n
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	} else if n > len(d.data)-d.pos {
+		d.fail("the file ends early, %d bytes are missing", n-(len(d.data)-d.pos))
+		return nil
+	}
+
+	d.pos += n
+	return d.data[d.pos-n : d.pos]
+}
+
+// index : Reads an index into a table of n items
+func (d *decoder) index(n int, table string) int {
+	i := d.uvarint()
+	if i >= n {
+		d.fail("%s %d doesn't exist, there are %d", table, i, n)
+		return 0
+	}
+
+	return i
+}
+
+// ref : Reads an index+1 into a table of n items, returns -1 for 0
+func (d *decoder) ref(n int, table string) int {
+	return d.index(n+1, table) - 1
+}
+
+func (d *decoder) str() string {
+	if len(d.p.Strings) == 0 {
+		d.fail("string %d doesn't exist, there are none", d.uvarint())
+		return ""
+	}
+
+	return d.p.Strings[d.index(len(d.p.Strings), "string")]
+}
+
+func (d *decoder) typ() *ir.Type {
+	if len(d.p.Types) == 0 {
+		d.fail("type %d doesn't exist, there are none", d.uvarint())
+		return ir.UnknownType
+	}
+
+	return d.p.Types[d.index(len(d.p.Types), "type")]
+}
+
+// count : Reads the length of a table, each item of which takes at least one byte
+func (d *decoder) count() int {
+	n := d.uvarint()
+	if n > len(d.data)-d.pos {
+		d.fail("a table of %d items doesn't fit in the rest of the file", n)
+		return 0
+	}
+
+	return n
+}
+
+// Decode : Reads a program written by Encode
+func Decode(data []byte) (*Program, error) {
+	if len(data) < len(Magic)+2 || string(data[:len(Magic)]) != Magic {
+		return nil, errorf("not a gecko bytecode file")
+	} else if version := int(data[len(Magic)]) | int(data[len(Magic)+1])<<8; version != Version {
+		return nil, errorf("the file is bytecode version %d, this gecko reads version %d", version, Version)
+	}
+
+	p := &Program{}
+	d := &decoder{data: data, pos: len(Magic) + 2, p: p}
+	name := d.uvarint()
+
+	for i, n := 0, d.count(); i < n; i++ {
+		p.Strings = append(p.Strings, string(d.bytes(d.count())))
+	}
+	if name < len(p.Strings) {
+		p.Name = p.Strings[name]
+	}
+
+	// Types refer to structs, which are only read after them, and to types that may come later
+	for i, n := 0, d.count(); i < n; i++ {
+		p.Types = append(p.Types, &ir.Type{})
+	}
+	structRefs := map[*ir.Type]int{}
+	for _, t := range p.Types {
+		t.Kind = ir.Kind(d.uvarint())
+		t.Name = d.str()
+		t.GeckoName = d.str()
+		t.Bits = uint(d.uvarint())
+		flags := d.uvarint()
+		t.Signed = flags&flagSigned != 0
+		t.Pointer = flags&flagPointer != 0
+		if elem := d.ref(len(p.Types), "type"); elem >= 0 {
+			t.Elem = p.Types[elem]
+		}
+		if key := d.ref(len(p.Types), "type"); key >= 0 {
+			t.Key = p.Types[key]
+		}
+		structRefs[t] = d.uvarint() - 1
+	}
+
+	for i, n := 0, d.count(); i < n; i++ {
+		s := &ir.Struct{Name: d.str(), GeckoName: d.str()}
+		for j, fields := 0, d.count(); j < fields; j++ {
+			s.Fields = append(s.Fields, &ir.Field{Name: d.str(), Type: d.typ()})
+		}
+		p.Structs = append(p.Structs, s)
+	}
+
+	for i, t := range p.Types {
+		if s := structRefs[t]; s >= len(p.Structs) {
+			d.fail("struct %d doesn't exist, there are %d", s, len(p.Structs))
+		} else if s >= 0 {
+			t.Struct = p.Structs[s]
+		}
+
+		if (t.Kind == ir.List || t.Kind == ir.Map) && t.Elem == nil || t.Kind == ir.Map && t.Key == nil || t.Kind == ir.Class && t.Struct == nil {
+			d.fail("type %d is missing the types it is made of", i)
+		}
+	}
+
+	for i, n := 0, d.count(); i < n; i++ {
+		c := &ir.Const{T: d.typ()}
+		switch c.T.Kind {
+		case ir.Float:
+			if b := d.bytes(8); b != nil {
+				c.Float = math.Float64frombits(binary.LittleEndian.Uint64(b))
+			}
+		case ir.String:
+			c.Str = d.str()
+		default:
+			c.Int = d.varint()
+		}
+		p.Constants = append(p.Constants, c)
+	}
+
+	for i, n := 0, d.count(); i < n; i++ {
+		p.Globals = append(p.Globals, &ir.Variable{Name: d.str(), Type: d.typ()})
+	}
+
+	for i, n := 0, d.count(); i < n; i++ {
+		f := &Function{Name: d.str(), Result: d.typ(), Params: d.uvarint()}
+		for j, vars := 0, d.count(); j < vars; j++ {
+			f.Vars = append(f.Vars, &ir.Variable{Name: d.str(), Type: d.typ()})
+		}
+		if f.Params > len(f.Vars) {
+			d.fail("%s has %d params but only %d variables", f.Name, f.Params, len(f.Vars))
+		}
+
+		f.Code = d.bytes(d.count())
+
+		for j, positions := 0, d.count(); j < positions; j++ {
+			pos := Position{Offset: d.uvarint()}
+			pos.Pos = lexer.Position{Filename: d.str(), Line: d.uvarint(), Column: d.uvarint()}
+			f.Positions = append(f.Positions, pos)
+		}
+
+		p.Functions = append(p.Functions, f)
+	}
+
+	p.Main = d.ref(len(p.Functions), "function")
+	p.Init = d.ref(len(p.Functions), "function")
+
+	if d.err == nil && d.pos != len(data) {
+		d.fail("%d bytes follow the end of the program", len(data)-d.pos)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,78 @@
+package bytecode_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/bytecode"
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+)
+
+// compile : Compiles source to bytecode the way `gecko compile --emit bytecode` does
+func compile(t *testing.T, source string) *bytecode.Program {
+	t.Helper()
+
+	p, err := bytecode.Compile(compilertest.Module(t, source, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for _, program := range compilertest.Programs {
+		p := compile(t, program.Source)
+		encoded := p.Encode()
+
+		decoded, err := bytecode.Decode(encoded)
+		if err != nil {
+			t.Errorf("%s: Decode failed: %v", program.Name, err)
+			continue
+		}
+
+		if again := decoded.Encode(); !bytes.Equal(again, encoded) {
+			t.Errorf("%s: encoding the decoded program gives other bytes", program.Name)
+		}
+
+		want, err := bytecode.Disassemble(p)
+		if err != nil {
+			t.Fatalf("%s: %v", program.Name, err)
+		}
+		got, err := bytecode.Disassemble(decoded)
+		if err != nil {
+			t.Fatalf("%s: %v", program.Name, err)
+		}
+		if got != want {
+			t.Errorf("%s: the decoded program disassembles to\n%s\nwant\n%s", program.Name, got, want)
+		}
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	valid := compile(t, compilertest.Programs[0].Source).Encode()
+	version := func(v int) []byte {
+		data := append([]byte{}, valid...)
+		data[len(bytecode.Magic)] = byte(v)
+		data[len(bytecode.Magic)+1] = byte(v >> 8)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"not bytecode", []byte("package Main\n")},
+		{"only magic", []byte(bytecode.Magic)},
+		{"older version", version(bytecode.Version - 1)},
+		{"newer version", version(bytecode.Version + 1)},
+		{"truncated", valid[:len(valid)/2]},
+	}
+
+	for _, test := range tests {
+		if _, err := bytecode.Decode(test.data); err == nil {
+			t.Errorf("%s: Decode succeeded, want an error", test.name)
+		}
+	}
+}
@@ -55,6 +55,12 @@ func fetchType(variable interface{}) string {
 	return r
 }
 
+// isInt : Reports if v is a value for an int option
+func isInt(v string) bool {
+	_, err := strconv.ParseInt(v, 0, 32)
+	return err == nil
+}
+
 func getValue(v string) interface{} {
 	var r interface{}
 	r, err := strconv.ParseInt(v, 0, 32)
@@ -174,6 +180,20 @@ func (c *Command) GetBool(key string) bool {
 	return r
 }
 
+// IsFlag : Checks if option is a bool optional, which can be passed without a value
+func (c *Command) IsFlag(option string) bool {
+	return c.OptionType(option) == "bool"
+}
+
+// OptionType : Returns the type of the optional option, or "" if the command has no such optional
+func (c *Command) OptionType(option string) string {
+	if c.Optionals[option] == nil {
+		return ""
+	}
+
+	return c.Optionals[option].Type
+}
+
 func (c *Command) Name() string {
 	return c.CommandName
 }
@@ -216,6 +236,8 @@ type Commandable interface {
 	SetName(string)
 	RegisterOptional(string, string)
 	RegisterPositionals([]string)
+	IsFlag(string) bool
+	OptionType(string) string
 }
 
 //Commander : Command line parser
@@ -298,10 +320,25 @@ func (c *Commander) Parse(cmds []string) {
 
 	for i := 2; i < len(cmds); i++ {
 		cmd := cmds[i]
-		if !strings.HasPrefix(cmd, "-") {
+		if cmd == "--" {
+			// Everything after -- is positional, even if it looks like an option
+			positionals = append(positionals, cmds[i+1:]...)
+			break
+		} else if !strings.HasPrefix(cmd, "-") {
 			positionals = append(positionals, cmd)
 		} else if strings.HasPrefix(cmd, "--") {
 			option := cmd[2:len(cmd)]
+
+			// Values can also be given as --option=value
+			if parts := strings.SplitN(option, "=", 2); len(parts) == 2 {
+				option = parts[0]
+				registeredCmd.RegisterOptional(option, parts[1])
+				if listener := c.listeners[option]; listener != nil {
+					listener.Method(getValue(parts[1]))
+				}
+				continue
+			}
+
 			listener := c.listeners[option]
 
 			if listener != nil && listener.Option.Type == "bool" {
@@ -309,6 +346,18 @@ func (c *Commander) Parse(cmds []string) {
 				continue
 			}
 
+			// Numbers are never taken from an argument that isn't one, like the source after the option
+			if listener != nil && listener.Option.Type == "int" && (i+1 >= len(cmds) || !isInt(cmds[i+1])) {
+				c.LogString("--" + option + " needs a number, like --" + option + "=1")
+				os.Exit(1)
+			}
+
+			// Flags never take the next argument as their value, only --flag=value
+			if registeredCmd.IsFlag(option) {
+				registeredCmd.RegisterOptional(option, "true")
+				continue
+			}
+
 			i++
 
 			if len(cmds) > i {
@@ -320,6 +369,23 @@ func (c *Commander) Parse(cmds []string) {
 			if listener != nil && len(cmds) > i {
 				listener.Method(getValue(cmds[i]))
 			}
+		} else if len(cmd) > 1 {
+			// Single letter options take their value in the same argument, like -O2, or in the next one
+			option, value := cmd[1:2], cmd[2:]
+			optionType := registeredCmd.OptionType(option)
+			if value == "" && optionType == "bool" {
+				value = "true"
+			} else if value == "" && optionType == "" {
+				continue
+			} else if value == "" {
+				if i+1 >= len(cmds) || optionType == "int" && !isInt(cmds[i+1]) || strings.HasPrefix(cmds[i+1], "-") {
+					c.LogString("-" + option + " needs a level like -" + option + "2")
+					os.Exit(1)
+				}
+				i++
+				value = cmds[i]
+			}
+			registeredCmd.RegisterOptional(option, value)
 		}
 	}
 
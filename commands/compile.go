@@ -10,7 +10,10 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/thoas/go-funk"
+
 	"github.com/neutrino2211/Gecko/config"
+	"github.com/neutrino2211/Gecko/errors"
 	"github.com/neutrino2211/Gecko/logger"
 	"github.com/neutrino2211/Gecko/utils"
 
@@ -61,6 +64,21 @@ func (c *CompileCommand) Init() {
 			Type:        "string",
 			Description: "Output type for program. (executable | library)",
 		},
+		"diagnostics-format": &commander.Optional{
+			Type:        "string",
+			Description: "Format errors and warnings are printed in. (text | json | sarif)",
+		},
+		"emit": &commander.Optional{
+			Type:        "string",
+			Description: "Write an intermediate artefact to the output path instead of building. (c | header | ir | ast | tokens)",
+		},
+		"debug-info": debugInfoOption,
+		"g":          debugInfoOption,
+		"O":          optimisationOption,
+		"werror": &commander.Optional{
+			Type:        "bool",
+			Description: "Treat warnings as errors",
+		},
 	}
 
 	c.Usage = "gecko compile sources... [options]"
@@ -72,9 +90,54 @@ func (c *CompileCommand) Init() {
 	c.Description = c.BuildHelp(compileHelp)
 }
 
+// printDiagnostics : Prints diagnostics to w as text or in one of errors.Formats
+func printDiagnostics(c *commander.Command, w io.Writer, format string, diagnostics []*errors.Error) {
+	if format == "text" {
+		for _, d := range diagnostics {
+			fmt.Fprintln(w, d.String())
+		}
+		return
+	}
+
+	out, err := errors.Format(format, diagnostics)
+	if err != nil {
+		c.Fatal(err.Error())
+	}
+	fmt.Fprintln(w, out)
+}
+
+// exitIfFailed : Exits when diagnostics stop the build and says so when --werror is why
+func exitIfFailed(c *commander.Command, session *compiler.Session, diagnostics []*errors.Error) {
+	if session.FailedByWarnings(diagnostics) {
+		c.Error("warnings are treated as errors because --werror is set")
+	}
+	if session.Failed(diagnostics) {
+		os.Exit(1)
+	}
+}
+
+// checkOptimisation : Stops with an error if the O option isn't an optimisation level
+func checkOptimisation(c *commander.Command) {
+	if level := c.Values["O"]; level != "" && !funk.ContainsString([]string{"0", "1", "2"}, level) {
+		c.Fatal("unknown optimisation level '" + level + "', expected 0, 1 or 2")
+	}
+}
+
 func (c *CompileCommand) Run() {
 	cfg := &config.BuildConfig{}
-	compiler.Init()
+
+	format := c.Values["diagnostics-format"]
+	if format == "" {
+		format = "text"
+	} else if format != "text" && !funk.ContainsString(errors.Formats, format) {
+		c.Fatal("unknown diagnostics format '" + format + "', expected text, " + strings.Join(errors.Formats, " or "))
+	}
+
+	if emit := c.Values["emit"]; emit != "" && !funk.ContainsString(compiler.EmitKinds, emit) {
+		c.Fatal("unknown artefact '" + emit + "', expected " + strings.Join(compiler.EmitKinds, ", "))
+	}
+
+	checkOptimisation(&c.Command)
 
 	cfg.Platform = runtime.GOOS
 	cfg.Arch = runtime.GOARCH
@@ -83,7 +146,9 @@ func (c *CompileCommand) Run() {
 	cfg.Type = "executable"
 
 	if len(c.Values["build"]) != 0 {
-		compiler.ReadBuildJson(c.Values["build"], cfg)
+		if err := compiler.ReadBuildJson(c.Values["build"], cfg); err != nil {
+			c.Fatal(err.Error())
+		}
 	} else if len(c.Positionals) == 0 {
 		c.Help()
 		return
@@ -99,6 +164,10 @@ func (c *CompileCommand) Run() {
 		cfg.Type = c.Values["type"]
 	}
 
+	if c.Values["debug-info"] == "true" || c.Values["g"] == "true" {
+		cfg.Debug = true
+	}
+
 	if cfg.Toolchain != "" {
 		cfg.Toolchain += "-"
 	}
@@ -106,9 +175,12 @@ func (c *CompileCommand) Run() {
 	c.DebugLog(cfg)
 	cfg.Root = true
 
-	outputs := compiler.Build(c.Positionals, cfg, c.Values)
+	session := compiler.NewSession(cfg, c.Values)
+	outputs, diagnostics := session.Build(c.Positionals)
 
 	c.DebugLog(outputs)
+	printDiagnostics(&c.Command, os.Stdout, format, diagnostics)
+	exitIfFailed(&c.Command, session, diagnostics)
 
 	if len(outputs) > 0 && utils.FileExists(outputs[len(outputs)-1]) {
 		color.Set(color.FgGreen)
@@ -122,6 +194,14 @@ func (c *CompileCommand) Run() {
 }
 
 var (
+	debugInfoOption = &commander.Optional{
+		Type:        "bool",
+		Description: "Build with debug information for gdb and lldb and write a gdb script next to the output, -g for short",
+	}
+	optimisationOption = &commander.Optional{
+		Type:        "int",
+		Description: "Optimisation level, given as -O0, -O1 or -O2. 0 turns the optimiser off, 1 folds constants and drops branches that never run, 2 also inlines, propagates constants and removes unused code. (default 1)",
+	}
 	compileHelp          = `compiles a gecko source file or a gecko project`
 	compileCommandLogger = &logger.Logger{}
 	invokeDir, _         = os.Getwd()
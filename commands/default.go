@@ -9,8 +9,12 @@ import (
 )
 
 var GeckoCommands = map[string]commander.Commandable{
-	"compile": &CompileCommand{},
-	"version": &VersionCommand{},
+	"compile":     &CompileCommand{},
+	"demangle":    &DemangleCommand{},
+	"disassemble": &DisassembleCommand{},
+	"explain":     &ExplainCommand{},
+	"run":         &RunCommand{},
+	"version":     &VersionCommand{},
 }
 
 func buildCommandsList(c *DefaultCommand) string {
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/commander"
+)
+
+type DemangleCommand struct {
+	commander.Command
+}
+
+func (d *DemangleCommand) Init() {
+	d.Logger.Init(d.CommandName, 0)
+	d.Usage = "gecko demangle [symbols...]"
+	d.Description = d.BuildHelp(demangleHelp)
+}
+
+// geckoName : Returns the gecko name of a C symbol, temporaries are shown as <kind number>
+func geckoName(symbol string) (string, bool) {
+	segments, ok := ast.Demangle(symbol)
+	if !ok || len(segments) < 2 {
+		return symbol, false
+	}
+
+	for i, segment := range segments {
+		digits := strings.IndexFunc(segment, func(r rune) bool {
+			return r < '0' || r > '9'
+		})
+		if digits > 0 {
+			segments[i] = "<" + segment[digits:] + " " + segment[:digits] + ">"
+		}
+	}
+
+	return strings.Join(segments, "."), true
+}
+
+func (d *DemangleCommand) Run() {
+	if len(d.Positionals) > 0 {
+		for _, symbol := range d.Positionals {
+			name, ok := geckoName(symbol)
+			if !ok {
+				d.Fatal("'" + symbol + "' is not a symbol generated by gecko")
+			}
+			fmt.Println(name)
+		}
+		return
+	}
+
+	// Without symbols the input is copied with every symbol in it demangled, compiler errors can be piped through
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fmt.Println(symbolPattern.ReplaceAllStringFunc(scanner.Text(), func(symbol string) string {
+			name, _ := geckoName(symbol)
+			return name
+		}))
+	}
+}
+
+var (
+	demangleHelp  = `turns C symbols generated by gecko back into gecko names, reads from stdin when no symbols are given`
+	symbolPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+)
@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/neutrino2211/Gecko/bytecode"
+	"github.com/neutrino2211/Gecko/commander"
+)
+
+type DisassembleCommand struct {
+	commander.Command
+}
+
+func (d *DisassembleCommand) Init() {
+	d.Logger.Init(d.CommandName, 0)
+	d.Usage = "gecko disassemble file.gbc"
+	d.Description = d.BuildHelp(disassembleHelp)
+}
+
+func (d *DisassembleCommand) Run() {
+	if len(d.Positionals) == 0 {
+		d.Help()
+		return
+	}
+
+	listing, err := bytecode.Disassemble(readBytecode(&d.Command, d.Positionals[0]))
+	if err != nil {
+		d.Fatal(d.Positionals[0] + ": " + err.Error())
+	}
+	fmt.Print(listing)
+}
+
+var (
+	disassembleHelp = `lists the bytecode in a .gbc file, which gecko compile --emit bytecode writes`
+)
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/neutrino2211/Gecko/commander"
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+type ExplainCommand struct {
+	commander.Command
+}
+
+func (e *ExplainCommand) Init() {
+	e.Logger.Init(e.CommandName, 0)
+	e.Usage = "gecko explain <code>"
+	e.Description = e.BuildHelp(explainHelp)
+}
+
+func indent(s string) string {
+	return "    " + strings.ReplaceAll(s, "\n", "\n    ")
+}
+
+func (e *ExplainCommand) Run() {
+	if len(e.Positionals) == 0 {
+		for _, code := range errors.Codes {
+			fmt.Println(code.ID, "-", code.Title)
+		}
+		return
+	}
+
+	code := errors.Lookup(strings.ToUpper(e.Positionals[0]))
+	if code == nil {
+		e.Fatal("unknown error code '" + e.Positionals[0] + "', run 'gecko explain' to list every code")
+	}
+
+	fmt.Println(color.HiYellowString("%s: %s", code.ID, code.Title))
+	fmt.Println()
+	fmt.Println(code.Explanation)
+	fmt.Println()
+	// Codes for problems in the compiler itself have no code that causes them
+	if code.Example != "" {
+		fmt.Println("For example:")
+		fmt.Println()
+		fmt.Println(indent(code.Example))
+		fmt.Println()
+	}
+	fmt.Println("To fix it:", code.Fix)
+}
+
+var (
+	explainHelp = `explains an error code, or lists every code when none is given`
+)
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/logger"
+)
+
+// explain : Runs gecko explain with positionals and returns what it prints
+func explain(t *testing.T, positionals ...string) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	e := &ExplainCommand{}
+	e.Init()
+	e.RegisterPositionals(positionals)
+	e.Run()
+
+	w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(out)
+}
+
+func TestExplainLists(t *testing.T) {
+	out := explain(t)
+	for _, code := range errors.Codes {
+		if !strings.Contains(out, code.ID+" - "+code.Title+"\n") {
+			t.Errorf("%s isn't listed in\n%s", code.ID, out)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	for _, id := range []string{errors.TypeMismatch, "g0006"} {
+		out := explain(t, id)
+		code := errors.Lookup(errors.TypeMismatch)
+		for _, part := range []string{code.Title, code.Explanation, "For example:", "    func square(n: int): int {", code.Fix} {
+			if !strings.Contains(out, part) {
+				t.Errorf("explain %s doesn't print %q:\n%s", id, part, out)
+			}
+		}
+	}
+
+	if out := explain(t, errors.CompilerFailure); strings.Contains(out, "For example:") {
+		t.Errorf("explain %s prints an example:\n%s", errors.CompilerFailure, out)
+	}
+}
+
+func TestExplainUnknownCode(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(*logger.FatalError); !ok {
+			t.Error("explaining an unknown code doesn't fail")
+		}
+	}()
+
+	explain(t, "G9999")
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/neutrino2211/Gecko/bytecode"
+	"github.com/neutrino2211/Gecko/commander"
+	"github.com/neutrino2211/Gecko/compiler"
+	"github.com/neutrino2211/Gecko/config"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/interpreter"
+	"github.com/neutrino2211/Gecko/logger"
+)
+
+type RunCommand struct {
+	commander.Command
+}
+
+func (r *RunCommand) Init() {
+	r.Optionals = map[string]*commander.Optional{
+		"O": optimisationOption,
+		"werror": &commander.Optional{
+			Type:        "bool",
+			Description: "Treat warnings as errors",
+		},
+	}
+
+	r.Usage = "gecko run file.g|file.gbc [-- arguments...]"
+
+	r.Values = map[string]string{}
+
+	runCommandLogger.Init(r.CommandName, 2)
+	r.Logger = *runCommandLogger
+	r.Description = r.BuildHelp(runHelp)
+}
+
+// program : A program that can be run, checked source or bytecode
+type program interface {
+	Run(args []string) (int, error)
+}
+
+// readBytecode : Reads a .gbc file, stopping with an error if it can't be read
+func readBytecode(c *commander.Command, file string) *bytecode.Program {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		c.Fatal(err.Error())
+	}
+
+	p, err := bytecode.Decode(data)
+	if err != nil {
+		c.Fatal(file + ": " + err.Error())
+	}
+	return p
+}
+
+// source : Checks a gecko source file and returns its program, diagnostics are printed and errors stop gecko
+func (r *RunCommand) source(path string) program {
+	cfg := &config.BuildConfig{
+		Platform: runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Command:  r,
+		Compiler: config.GeckoConfig.DefaultCompiler,
+		Type:     "executable",
+		Root:     true,
+	}
+
+	session := compiler.NewSession(cfg, r.Values)
+	file, diagnostics := session.Parse(path)
+
+	var pkg *compiler.Package
+	if !session.Failed(diagnostics) {
+		var checked []*errors.Error
+		pkg, checked = session.Check(file)
+		diagnostics = append(diagnostics, checked...)
+	}
+
+	// Diagnostics go to stderr to keep them apart from what the program prints
+	printDiagnostics(&r.Command, os.Stderr, "text", diagnostics)
+	exitIfFailed(&r.Command, session, diagnostics)
+	if pkg.Module == nil {
+		os.Exit(1)
+	}
+
+	return interpreter.New(pkg.Module)
+}
+
+func (r *RunCommand) Run() {
+	if len(r.Positionals) == 0 {
+		r.Help()
+		return
+	}
+
+	checkOptimisation(&r.Command)
+
+	var p program
+	if strings.HasSuffix(r.Positionals[0], ".gbc") {
+		vm, err := interpreter.NewVM(readBytecode(&r.Command, r.Positionals[0]))
+		if err != nil {
+			r.Fatal(r.Positionals[0] + ": " + err.Error())
+		}
+		p = vm
+	} else {
+		p = r.source(r.Positionals[0])
+	}
+
+	// The program sees the file it runs as its name, like a built program sees its path
+	status, err := p.Run(r.Positionals)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+	os.Exit(status)
+}
+
+var (
+	runHelp          = `runs a gecko program or a program compiled with --emit bytecode without a C compiler, the arguments after -- are passed to the program`
+	runCommandLogger = &logger.Logger{}
+)
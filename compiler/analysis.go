@@ -0,0 +1,311 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/tokens"
+)
+
+/*
+	Dead code analysis
+
+	Looks for code that has no effect on the program: locals that are never read,
+	imports and private ("_" prefixed) functions that are never used and
+	statements that follow a return. Everything found is reported as a warning.
+	The analysis runs on the tokens of a file before they are compiled, because
+	compiling folds constants and rewrites symbols into their C names.
+*/
+
+type declaration struct {
+	name string
+	pos  lexer.Position
+}
+
+// usages : Collects every name referenced by a piece of source. Dotted names are
+// recorded whole as well as by their first segment, `list.push` uses `list`
+type usages map[string]bool
+
+func (u usages) add(name string) {
+	if len(name) == 0 {
+		return
+	}
+
+	u[name] = true
+	u[strings.Split(name, ".")[0]] = true
+}
+
+// Analyse : Reports unused locals, imports and private functions and unreachable code in file
+func Analyse(file *tokens.File, c *Compilation) {
+	scope := &ast.Ast{Name: file.PackageName, Compilation: c}
+	used := usages{}
+
+	for _, entry := range file.Entries {
+		used.entry(entry)
+	}
+
+	c.session.resolveImports(file)
+	imported := 0
+
+	for _, entry := range file.Entries {
+		if len(entry.Import) > 0 {
+			if !importIsUsed(entry.Import, file.Imports[imported], file.PackageName, used) {
+				errors.AddWarning(errors.NewError(errors.UnusedImport, entry.Pos, "package '"+entry.Import+"' is imported but never used", scope))
+			}
+			imported++
+		} else if entry.Method != nil {
+			if strings.HasPrefix(entry.Method.Name, "_") && !used[entry.Method.Name] {
+				errors.AddWarning(errors.NewError(errors.UnusedFunction, entry.Method.Pos, "private function '"+entry.Method.Name+"' is never called", scope))
+			}
+			analyseMethod(entry.Method, scope)
+		} else if entry.Class != nil {
+			for _, field := range entry.Class.Fields {
+				if field.Method != nil {
+					analyseMethod(field.Method, scope)
+				}
+			}
+		}
+	}
+}
+
+// importIsUsed : Checks if the package at path is referenced. Files of the same package are
+// merged into it so any use of one of their declarations counts
+func importIsUsed(path string, imported *tokens.File, packageName string, used usages) bool {
+	if imported.PackageName == packageName {
+		for _, entry := range imported.Entries {
+			if entry.Method != nil && used[entry.Method.Name] || entry.Class != nil && used[entry.Class.Name] || entry.Field != nil && used[entry.Field.Name] {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	segments := strings.Split(path, ".")
+	prefixes := []string{path + ".", segments[len(segments)-1] + ".", imported.PackageName + "."}
+
+	for name := range used {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func analyseMethod(method *tokens.Method, parent *ast.Ast) {
+	scope := &ast.Ast{Name: method.Name, Parent: parent}
+	used := usages{}
+	declared := []declaration{}
+
+	for _, entry := range method.Value {
+		used.entry(entry)
+	}
+
+	checkReachability(method.Value, scope)
+	collectDeclarations(method.Value, &declared)
+
+	for _, d := range declared {
+		if !strings.HasPrefix(d.name, "_") && !used[d.name] {
+			errors.AddWarning(errors.NewError(errors.UnusedVariable, d.pos, "variable '"+d.name+"' is declared but never used", scope))
+		}
+	}
+}
+
+func collectDeclarations(entries []*tokens.Entry, declared *[]declaration) {
+	for _, entry := range entries {
+		if entry.Field != nil && entry.Field.Visibility != "external" {
+			*declared = append(*declared, declaration{entry.Field.Name, entry.Field.Pos})
+		} else if entry.If != nil {
+			collectDeclarations(entry.If.Value, declared)
+		} else if entry.ElseIf != nil {
+			collectDeclarations(entry.ElseIf.Value, declared)
+		} else if entry.Else != nil {
+			collectDeclarations(entry.Else.Value, declared)
+		} else if entry.Loop != nil {
+			collectDeclarations(entry.Loop.Value, declared)
+		}
+	}
+}
+
+// checkReachability : Warns about the first statement after a return in entries and in every block inside it
+func checkReachability(entries []*tokens.Entry, scope *ast.Ast) {
+	returned := false
+
+	for _, entry := range entries {
+		if returned {
+			errors.AddWarning(errors.NewError(errors.UnreachableCode, entry.Pos, "unreachable code after return", scope))
+			return
+		}
+
+		if entry.Return != nil {
+			returned = true
+		} else if entry.If != nil {
+			checkReachability(entry.If.Value, scope)
+		} else if entry.ElseIf != nil {
+			checkReachability(entry.ElseIf.Value, scope)
+		} else if entry.Else != nil {
+			checkReachability(entry.Else.Value, scope)
+		} else if entry.Loop != nil {
+			checkReachability(entry.Loop.Value, scope)
+		}
+	}
+}
+
+func (u usages) entry(entry *tokens.Entry) {
+	switch {
+	case entry.Return != nil:
+		u.literal(entry.Return)
+	case entry.Assignment != nil:
+		// Assigning to a variable doesn't read it, but assigning to one of its fields does
+		if strings.Contains(entry.Assignment.Name, ".") {
+			u.add(entry.Assignment.Name)
+		}
+		u.literal(entry.Assignment.Value)
+	case entry.If != nil:
+		u.expression(entry.If.Expression)
+		u.entries(entry.If.Value)
+	case entry.ElseIf != nil:
+		u.expression(entry.ElseIf.Expression)
+		u.entries(entry.ElseIf.Value)
+	case entry.Else != nil:
+		u.entries(entry.Else.Value)
+	case entry.FuncCall != nil:
+		u.call(entry.FuncCall)
+	case entry.Method != nil:
+		u.method(entry.Method)
+	case entry.Class != nil:
+		u.add(entry.Class.Name)
+		for _, parent := range entry.Class.Extends {
+			u.add(parent)
+		}
+		for _, field := range entry.Class.Fields {
+			if field.Method != nil {
+				u.method(field.Method)
+			} else if field.Field != nil {
+				u.field(field.Field)
+			}
+		}
+	case entry.Field != nil:
+		u.field(entry.Field)
+	case entry.Loop != nil:
+		if entry.Loop.Iterator != nil {
+			u.typeRef(entry.Loop.Iterator.Variable.Type)
+			u.literal(entry.Loop.Iterator.SourceArray)
+		}
+		u.expression(entry.Loop.ForExpression)
+		u.entries(entry.Loop.Value)
+	}
+}
+
+func (u usages) entries(entries []*tokens.Entry) {
+	for _, entry := range entries {
+		u.entry(entry)
+	}
+}
+
+func (u usages) method(method *tokens.Method) {
+	for _, arg := range method.Arguments {
+		u.typeRef(arg.Type)
+		u.literal(arg.Default)
+	}
+	u.typeRef(method.Type)
+	u.entries(method.Value)
+}
+
+func (u usages) field(field *tokens.Field) {
+	u.typeRef(field.Type)
+	u.literal(field.Value)
+}
+
+func (u usages) typeRef(t *tokens.TypeRef) {
+	if t == nil {
+		return
+	} else if t.Array != nil {
+		u.typeRef(t.Array)
+	} else if t.Map != nil {
+		u.typeRef(t.Map.Key)
+		u.typeRef(t.Map.Value)
+	} else {
+		u.add(t.Type)
+	}
+}
+
+func (u usages) call(call *tokens.FuncCall) {
+	u.add(call.Function)
+	for _, arg := range call.Arguments {
+		u.literal(arg.Value)
+	}
+}
+
+func (u usages) str(literal string) {
+	_, expressions := splitInterpolation(literal)
+	for _, source := range expressions {
+		expr := &tokens.Expression{}
+		if expressionParser.ParseString(source, expr) == nil {
+			u.expression(expr)
+		}
+	}
+}
+
+func (u usages) literal(lit *tokens.Literal) {
+	if lit == nil {
+		return
+	} else if lit.FuncCall != nil {
+		u.call(lit.FuncCall)
+	} else if lit.Expression != nil {
+		u.expression(lit.Expression)
+	} else if len(lit.String) > 0 {
+		u.str(lit.String)
+	} else {
+		u.add(lit.Symbol)
+	}
+
+	for _, item := range lit.Array {
+		u.literal(item)
+	}
+	for _, entry := range lit.Object {
+		u.literal(entry.Value)
+	}
+	u.literal(lit.ArrayIndex)
+}
+
+func (u usages) expression(expr *tokens.Expression) {
+	if expr == nil {
+		return
+	}
+
+	for eq := expr.Equality; eq != nil; eq = eq.Next {
+		for cmp := eq.Comparison; cmp != nil; cmp = cmp.Next {
+			for add := cmp.Addition; add != nil; add = add.Next {
+				for mult := add.Multiplication; mult != nil; mult = mult.Next {
+					u.unary(mult.Unary)
+				}
+			}
+		}
+	}
+}
+
+func (u usages) unary(un *tokens.Unary) {
+	if un == nil {
+		return
+	} else if un.Primary == nil {
+		u.unary(un.Unary)
+		return
+	}
+
+	p := un.Primary
+	if p.FuncCall != nil {
+		u.call(p.FuncCall)
+	} else if p.SubExpression != nil {
+		u.expression(p.SubExpression)
+	} else if len(p.String) > 0 {
+		u.str(p.String)
+	} else {
+		u.add(p.Symbol)
+	}
+}
@@ -0,0 +1,96 @@
+package compiler_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/compiler"
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+	"github.com/neutrino2211/Gecko/config"
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+func TestUnused(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		codes  []string
+	}{
+		{"everything used", `func _twice(n: int): int {
+  return n * 2
+}
+
+func Main(): int {
+  n := 2
+  return _twice(n: n)
+}
+`, []string{}},
+		{"variable", `func Main() {
+  n := 2
+}
+`, []string{errors.UnusedVariable}},
+		{"private function", `func _helper(): int {
+  return 1
+}
+
+func Main() {
+}
+`, []string{errors.UnusedFunction}},
+		{"public function", `func helper(): int {
+  return 1
+}
+
+func Main() {
+}
+`, []string{}},
+		{"unreachable code", `func Main(): int {
+  return 1
+  n := 2
+}
+`, []string{errors.UnreachableCode, errors.UnusedVariable}},
+	}
+
+	for _, test := range tests {
+		compilertest.ExpectCodes(t, test.name, test.source, test.codes, nil)
+	}
+}
+
+func TestUnusedImports(t *testing.T) {
+	dir := filepath.Dir(compilertest.Write(t, ""))
+	if err := ioutil.WriteFile(filepath.Join(dir, "lib.g"), []byte("package lib\n\nfunc one(): int {\n  return 1\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Imports are looked for in the working directory
+	t.Chdir(dir)
+
+	tests := []struct {
+		name   string
+		source string
+		codes  []string
+	}{
+		{"used import", "import lib\n\nfunc Main(): int {\n  return lib.one()\n}\n", []string{}},
+		{"unused import", "import lib\n\nfunc Main(): int {\n  return 0\n}\n", []string{errors.UnusedImport}},
+	}
+
+	for _, test := range tests {
+		compilertest.ExpectCodes(t, test.name, test.source, test.codes, nil)
+	}
+}
+
+func TestWarningsAsErrors(t *testing.T) {
+	source := "package Main\n\nfunc Main() {\n  n := 2\n}\n"
+
+	for _, werror := range []string{"false", "true"} {
+		options := map[string]string{"werror": werror}
+		session := compiler.NewSession(&config.BuildConfig{}, options)
+		_, diagnostics := compilertest.Check(t, source, options)
+
+		if failed := session.Failed(diagnostics); failed != (werror == "true") {
+			t.Errorf("werror %s: Failed = %v", werror, failed)
+		}
+		if failed := session.FailedByWarnings(diagnostics); failed != (werror == "true") {
+			t.Errorf("werror %s: FailedByWarnings = %v", werror, failed)
+		}
+	}
+}
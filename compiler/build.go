@@ -1,78 +1,200 @@
 package compiler
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"os/exec"
 	"path"
 	"runtime"
+	"strconv"
 	"strings"
 
+	"github.com/alecthomas/participle/lexer"
 	"github.com/thoas/go-funk"
 
 	"github.com/neutrino2211/Gecko/config"
 
 	"github.com/fatih/color"
 	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/bytecode"
 	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/ir"
+	"github.com/neutrino2211/Gecko/libgecko"
+	"github.com/neutrino2211/Gecko/tokens"
+	"github.com/neutrino2211/Gecko/utils"
 )
 
-func streamPipe(std io.ReadCloser) {
-	buf := bufio.NewReader(std) // Notice that this is not in a loop
-	for {
+// streamCommand : Runs cmd with what it prints going to out, returns an error if it couldn't be run or failed
+func streamCommand(cmd *exec.Cmd, out io.Writer) *errors.Error {
+	compileLogger.LogString("executing command:", strings.Join(cmd.Args, " "))
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	if err := cmd.Run(); err != nil {
+		return errors.NewError(errors.ToolchainFailure, lexer.Position{}, "'"+strings.Join(cmd.Args, " ")+"' failed: "+err.Error(), nil)
+	}
+
+	return nil
+}
+
+// runCommand : Runs cmd, if it fails the failure is reported to the session and stops the build
+func (s *Session) runCommand(cmd *exec.Cmd) {
+	if err := streamCommand(cmd, s.toolchainOutput()); err != nil {
+		s.Diagnostics().Add(err)
+		abort()
+	}
+}
+
+// toolchainOutput : Returns where the output of the C toolchain goes, stderr when stdout is
+// kept for diagnostics in a machine readable format
+func (s *Session) toolchainOutput() io.Writer {
+	if funk.ContainsString(errors.Formats, s.Options["diagnostics-format"]) {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
 
-		line, _, err := buf.ReadLine()
+// workDirectory : Returns the directory the session keeps the generated C and the runtime in.
+// Every session has its own so sessions building at the same time don't overwrite each other's files
+func (s *Session) workDirectory() (string, *errors.Error) {
+	if s.workDir == "" {
+		dir, err := ioutil.TempDir("", "gecko")
 		if err != nil {
-			break
+			return "", errors.NewError(errors.ToolchainFailure, lexer.Position{}, "couldn't create a directory for the generated C: "+err.Error(), nil)
 		}
-		fmt.Println(string(line))
+		s.workDir = dir
 	}
+
+	return s.workDir, nil
 }
 
-func streamCommand(cmd *exec.Cmd) {
-	compileLogger.LogString("executing command:", strings.Join(cmd.Args, " "))
-	stdout, err := cmd.StdoutPipe()
-	stderr, err := cmd.StderrPipe()
+// removeWorkDirectory : Deletes the files the session generated on its way to its outputs
+func (s *Session) removeWorkDirectory() {
+	if s.workDir != "" {
+		os.RemoveAll(s.workDir)
+		s.workDir = ""
+		s.runtimeObjects = nil
+	}
+}
+
+// buildRuntime : Compiles the libgecko runtime once per compiler and returns its object files
+func (s *Session) buildRuntime(cfg *config.BuildConfig) ([]string, *errors.Error) {
+	compilerPath := cfg.Toolchain + cfg.Compiler
+	profile := compilerPath
+	if cfg.Debug {
+		profile += "-debug"
+	}
+
+	if s.runtimeObjects[profile] != nil {
+		return s.runtimeObjects[profile], nil
+	}
+
+	workDir, workErr := s.workDirectory()
+	if workErr != nil {
+		return nil, workErr
+	}
+
+	directory := path.Join(workDir, "runtime", strings.ReplaceAll(profile, string(os.PathSeparator), "_"))
+	err := os.MkdirAll(directory, 0755)
 	if err != nil {
-		log.Fatal(err)
+		return nil, errors.NewError(errors.ToolchainFailure, lexer.Position{}, "couldn't create the runtime directory: "+err.Error(), nil)
 	}
-	cmd.Start()
-	streamPipe(stdout)
-	streamPipe(stderr)
+
+	objects := []string{}
+	for _, source := range libgecko.Sources {
+		sourcePath := path.Join(directory, source.Name+".c")
+		objectPath := path.Join(directory, source.Name+".o")
+
+		err = ioutil.WriteFile(sourcePath, []byte(libgecko.Header()+"\n"+source.Code), 0755)
+		if err != nil {
+			return nil, errors.NewError(errors.ToolchainFailure, lexer.Position{}, "couldn't write the runtime source "+sourcePath+": "+err.Error(), nil)
+		}
+
+		compileLogger.DebugLogString("building runtime source", source.Name)
+		args := append([]string{"-c", sourcePath, "-o", objectPath}, debugFlags(cfg)...)
+		if err := streamCommand(exec.Command(compilerPath, args...), s.toolchainOutput()); err != nil {
+			return nil, err
+		}
+		objects = append(objects, objectPath)
+	}
+
+	if s.runtimeObjects == nil {
+		s.runtimeObjects = map[string][]string{}
+	}
+	s.runtimeObjects[profile] = objects
+	return objects, nil
 }
 
-func BuildImportedModules(baseCfg *config.BuildConfig) {
+// buildImportedModules : Builds the modules with their own build.json that were imported while parsing
+func (s *Session) buildImportedModules(baseCfg *config.BuildConfig) {
+	if len(s.modulesToBuild) == 0 {
+		return
+	}
+
 	cfg := *baseCfg
-	for _, config := range modulesToBuild[1:] {
+	for _, config := range s.modulesToBuild[1:] {
 
-		compileLogger.Log(builtModules)
-		if funk.ContainsString(builtModules, config) {
+		compileLogger.Log(s.builtModules)
+		if funk.ContainsString(s.builtModules, config) {
 			continue
 		}
 
-		if len(modulesToBuild) > 0 {
-			modulesToBuild = modulesToBuild[1:]
+		if len(s.modulesToBuild) > 0 {
+			s.modulesToBuild = s.modulesToBuild[1:]
 		}
 
 		compileLogger.LogString(color.HiYellowString("Building module: %s", config))
-		configDir := path.Dir(config)
-		os.Chdir(configDir)
-		ReadBuildJson(config, &cfg)
-		Build([]string{}, &cfg, make(map[string]string))
-		os.Chdir(invokeDir)
-		builtModules = append(builtModules, path.Join(path.Dir(config), cfg.Output))
+		if err := ReadBuildJson(config, &cfg); err != nil {
+			s.Diagnostics().Add(errors.NewError(errors.InvalidBuildConfig, lexer.Position{Filename: config}, err.Error(), nil))
+			abort()
+		}
+		s.build([]string{}, &cfg, make(map[string]string), path.Dir(config))
+		s.builtModules = append(s.builtModules, path.Join(path.Dir(config), cfg.Output))
 	}
 }
 
-func Build(sources []string, cfg *config.BuildConfig, cmdLineArgs map[string]string) []string {
-	outDir, _ := os.Getwd()
+// check : Compiles file and runs every analysis on the result. Problems are reported to the session
+func (s *Session) check(file *tokens.File, format string) (*ast.Ast, *ExecutionContext) {
+	c := newCompilation(s)
+	geckoAst := &ast.Ast{Compilation: c}
+	geckoAst.Initialize()
+
+	compileLogger.LogString("compiling gecko package", file.PackageName)
+	s.resolveImports(file)
+	Analyse(file, c)
+	a, ctx := CompilePass(file, geckoAst, true)
+
+	if format == "executable" && a.Methods["Main"] == nil {
+		errors.AddError(&errors.Error{
+			Code:   errors.MissingMain,
+			Pos:    file.Entries[len(file.Entries)-1].Pos,
+			Reason: "No 'Main' function in file. Did you mean to build an object file?",
+			Scope:  a,
+		})
+	}
+
+	TypeCheck(ctx)
+	CheckFlow(ctx)
+
+	return a, ctx
+}
+
+// build : Builds sources, or the sources of cfg if there are none, and returns the files it created.
+// The paths in cfg are relative to dir, the working directory if it is empty, and its build command
+// runs there. Errors are reported to the session and stop the build
+func (s *Session) build(sources []string, cfg *config.BuildConfig, cmdLineArgs map[string]string, dir string) []string {
+	outDir := dir
+	if dir == "" {
+		outDir, _ = os.Getwd()
+	}
+	includeDir := "-I" + map[bool]string{true: ".", false: dir}[dir == ""]
 	format := cfg.Type
 	generateHeader := cfg.Type == "library"
+	emit := cmdLineArgs["emit"]
 
 	var inputFiles []string
 
@@ -83,18 +205,19 @@ func Build(sources []string, cfg *config.BuildConfig, cmdLineArgs map[string]str
 	}
 
 	if cfg.Platform != runtime.GOOS {
-		compileLogger.Error("platform mismatch, current platform is", runtime.GOOS, "but source(s)", strings.Join(inputFiles, ", "), "require", cfg.Platform)
-		return []string{}
+		s.Diagnostics().Add(errors.NewError(errors.InvalidBuildConfig, lexer.Position{}, "platform mismatch, current platform is "+runtime.GOOS+" but source(s) "+strings.Join(inputFiles, ", ")+" require "+cfg.Platform, nil))
+		abort()
 	}
 
 	if cfg.Arch != runtime.GOARCH {
-		compileLogger.Error("arch mismatch, current arch is", runtime.GOARCH, "but source(s)", strings.Join(inputFiles, ", "), "require", cfg.Arch)
-		return []string{}
+		s.Diagnostics().Add(errors.NewError(errors.InvalidBuildConfig, lexer.Position{}, "arch mismatch, current arch is "+runtime.GOARCH+" but source(s) "+strings.Join(inputFiles, ", ")+" require "+cfg.Arch, nil))
+		abort()
 	}
 
 	outputs := []string{}
 
-	if len(cfg.Dependencies) > 0 {
+	// Emitting only needs the sources, what they link against doesn't have to be built
+	if len(cfg.Dependencies) > 0 && emit == "" {
 		for _, dependency := range cfg.Dependencies {
 			if dependency.Platform == "" {
 				dependency.Platform = cfg.Platform
@@ -107,29 +230,30 @@ func Build(sources []string, cfg *config.BuildConfig, cmdLineArgs map[string]str
 			if dependency.Compiler == "" {
 				dependency.Compiler = cfg.Compiler
 			}
-			dependencyOutputs := Build([]string{}, dependency, cmdLineArgs)
+
+			dependency.Debug = dependency.Debug || cfg.Debug
+			dependencyOutputs := s.build([]string{}, dependency, cmdLineArgs, dir)
 			outputs = append(outputs, dependencyOutputs...)
 		}
 	}
 
-	if cfg.Config != "" {
+	if cfg.Config != "" && emit == "" {
 		depCfg := &config.BuildConfig{}
 		depCfg.Platform = cfg.Platform
 		depCfg.Arch = cfg.Arch
 		depCfg.Compiler = cfg.Compiler
+		depCfg.Debug = cfg.Debug
 
-		ReadBuildJson(cfg.Config, depCfg)
-
-		configDir := path.Dir(cfg.Config)
-
-		os.Chdir(configDir)
-
-		Build([]string{}, depCfg, cmdLineArgs)
+		configPath := path.Join(dir, cfg.Config)
+		if err := ReadBuildJson(configPath, depCfg); err != nil {
+			s.Diagnostics().Add(errors.NewError(errors.InvalidBuildConfig, lexer.Position{Filename: configPath}, err.Error(), nil))
+			abort()
+		}
 
-		os.Chdir(invokeDir)
+		s.build([]string{}, depCfg, cmdLineArgs, path.Dir(configPath))
 	}
 
-	if cfg.Build != "" {
+	if cfg.Build != "" && emit == "" {
 		rootDir, _ := path.Split(cmdLineArgs["build"])
 		cfg.Build = strings.ReplaceAll(cfg.Build, "@{root}", rootDir)
 		if len(cfg.Sources) > 0 {
@@ -138,12 +262,13 @@ func Build(sources []string, cfg *config.BuildConfig, cmdLineArgs map[string]str
 				"This might potentially cause issues during build",
 				"["+cfg.Build+"]"))
 		}
+		cmd := exec.Command("sh", "-c", cfg.Build)
 		if runtime.GOOS == "windows" {
-			streamCommand(exec.Command("cmd", cfg.Build))
-		} else {
-			streamCommand(exec.Command("sh", "-c", cfg.Build))
+			cmd = exec.Command("cmd", cfg.Build)
 		}
-		outputs = append(outputs, cfg.Output)
+		cmd.Dir = dir
+		s.runCommand(cmd)
+		outputs = append(outputs, path.Join(dir, cfg.Output))
 	}
 
 	for _, inputFile := range inputFiles {
@@ -153,11 +278,10 @@ func Build(sources []string, cfg *config.BuildConfig, cmdLineArgs map[string]str
 		var outputPath string
 
 		if len(sources) == 0 {
-			possibleOutDir, outputFile := path.Split(cmdLineArgs["build"])
-			outputPath = outputFile
-			outDir = possibleOutDir
-			compileLogger.DebugLogString(possibleOutDir)
-			inputFile = path.Join(possibleOutDir, inputFile)
+			possibleOutDir, _ := path.Split(cmdLineArgs["build"])
+			outDir = path.Join(dir, possibleOutDir)
+			compileLogger.DebugLogString(outDir)
+			inputFile = path.Join(outDir, inputFile)
 		}
 
 		if outDir == "" {
@@ -170,18 +294,22 @@ func Build(sources []string, cfg *config.BuildConfig, cmdLineArgs map[string]str
 			outputPath = outDir + string(os.PathSeparator) + strings.ReplaceAll(cfg.Output, "$", outFile)
 		}
 
-		if cfg.C {
+		if cfg.C && emit != "" {
+			continue
+		} else if cfg.C {
 
 			compileLogger.LogString("compiling C file", inputFile)
 
 			if format == "executable" {
 				args := []string{cfg.Toolchain + cfg.Compiler, inputFile, "-o", outputPath}
+				args = append(args, debugFlags(cfg)...)
 				cmd := exec.Command(args[0], args[1:len(args)]...)
-				streamCommand(cmd)
+				s.runCommand(cmd)
 			} else if format == "library" {
-				args := []string{cfg.Toolchain + cfg.Compiler, "-c", inputFile, "-I.", "-o", outputPath}
+				args := []string{cfg.Toolchain + cfg.Compiler, "-c", inputFile, includeDir, "-o", outputPath}
+				args = append(args, debugFlags(cfg)...)
 				cmd := exec.Command(args[0], args[1:len(args)]...)
-				streamCommand(cmd)
+				s.runCommand(cmd)
 			}
 
 			outputs = append(outputs, outputPath)
@@ -189,11 +317,25 @@ func Build(sources []string, cfg *config.BuildConfig, cmdLineArgs map[string]str
 			continue
 		}
 
-		if inputFile[len(inputFile)-2:len(inputFile)] != ".g" {
+		if !strings.HasSuffix(inputFile, ".g") {
+			continue
+		}
+
+		if emit == "tokens" {
+			name, source := s.readSource(inputFile)
+			outputPath = emitPath(emit, inputFile, outDir, cmdLineArgs, cfg.Compiler)
+			s.writeArtefact(outputPath, s.tokensCode(name, source))
+			outputs = append(outputs, outputPath)
 			continue
 		}
 
-		_ast := ParseFile(inputFile)
+		_ast := s.parseFile(inputFile)
+		if emit == "ast" {
+			outputPath = emitPath(emit, inputFile, outDir, cmdLineArgs, cfg.Compiler)
+			s.writeArtefact(outputPath, astCode(_ast))
+			outputs = append(outputs, outputPath)
+			continue
+		}
 		compileLogger.DebugLogString(inputFile[len(inputFile)-2 : len(inputFile)-1])
 
 		// i := 0
@@ -211,129 +353,143 @@ func Build(sources []string, cfg *config.BuildConfig, cmdLineArgs map[string]str
 		// 	i++
 		// }
 
-		geckoAst := &ast.Ast{}
-		geckoAst.Initialize()
-
-		compileLogger.LogString("compiling gecko package", _ast.PackageName)
-		a, ctx := CompilePass(_ast, geckoAst, true)
+		a, ctx := s.check(_ast, format)
 
-		if firstBuild {
-			BuildImportedModules(cfg)
-			firstBuild = false
+		if !s.modulesBuilt && emit == "" {
+			s.buildImportedModules(cfg)
+			s.modulesBuilt = true
 		}
 
-		if format == "executable" && a.Methods["Main"] == nil {
-			errors.AddError(&errors.Error{
-				Pos:    _ast.Entries[len(_ast.Entries)-1].Pos,
-				Reason: "No 'Main' function in file. Did you mean to build an object file?",
-				Scope:  a,
-			})
+		if s.Diagnostics().HaveErrors() || len(s.Diagnostics().Warnings()) > 0 && cmdLineArgs["werror"] == "true" {
+			abort()
 		}
 
-		// repr.Println(ctx)
-
-		if errors.HaveErrors() {
-			for _, e := range errors.GetErrors() {
-				fmt.Println(e.String())
-			}
-
-			os.Exit(1)
+		module := lowerPackage(a, ctx)
+		if s.Diagnostics().HaveErrors() {
+			abort()
 		}
-		code := ctx.Code(a)
+		ir.Optimise(module, optimisation(cmdLineArgs))
 
-		code = GetPreludeCode() + "\n" + code
-
-		compileLogger.DebugLogString(color.HiYellowString("methods"), color.HiYellowString(GetPreludeCode()))
-
-		codeLines := strings.Split(code, "\n")
-		// fmt.Println(len(codeLines))
-		if format != "object" {
-			codeLines = codeLines[0 : len(codeLines)-1]
-		} else {
-			codeLines = codeLines[0:len(codeLines)]
+		if emit == "ir" {
+			outputPath = emitPath(emit, inputFile, outDir, cmdLineArgs, cfg.Compiler)
+			s.writeArtefact(outputPath, module.String())
+			outputs = append(outputs, outputPath)
+			continue
 		}
 
-		code = a.CPreliminary + strings.Join(codeLines, "\n")
-
-		if format == "executable" {
-			mainArgs := CreateMethArgs(a.Methods["Main"].Arguments, a)
-			passedArgs := ""
-
-			for _, arg := range a.Methods["Main"].Arguments {
-				passedArgs += arg.Name + ", "
+		if emit == "bytecode" {
+			program, err := bytecode.Compile(module)
+			if err != nil {
+				s.Diagnostics().Add(errors.NewError(errors.UnsupportedFeature, err.(*bytecode.Error).Pos, "couldn't compile "+inputFile+" to bytecode: "+err.(*bytecode.Error).Message, nil))
+				abort()
 			}
+			outputPath = emitPath(emit, inputFile, outDir, cmdLineArgs, cfg.Compiler)
+			s.writeArtefact(outputPath, string(program.Encode()))
+			outputs = append(outputs, outputPath)
+			continue
+		}
 
-			if len(passedArgs) > 2 {
-				passedArgs = passedArgs[0 : len(passedArgs)-2]
-			}
+		code := generateC(module, format)
+
+		compileLogger.DebugLogString(code)
 
-			if ctx.Ast.Methods["Main"].Type != nil && ctx.Ast.Methods["Main"].Type.Type == "int" {
-				code = code + "\nint main(" + mainArgs + "){return Main__Main(" + passedArgs + ");}\n"
+		if emit == "c" || emit == "header" {
+			outputPath = emitPath(emit, inputFile, outDir, cmdLineArgs, cfg.Compiler)
+			if emit == "c" {
+				s.writeArtefact(outputPath, code)
 			} else {
-				code = code + "\nint main(" + mainArgs + "){Main__Main(" + passedArgs + "); return 0;}\n"
+				s.writeArtefact(outputPath, headerCode(a, ctx))
 			}
+			outputs = append(outputs, outputPath)
+			continue
 		}
 
-		compileLogger.DebugLogString(code)
-
-		directory := os.TempDir() + string(os.PathSeparator)
+		directory, workErr := s.workDirectory()
+		if workErr != nil {
+			s.Diagnostics().Add(workErr)
+			abort()
+		}
 
 		if generateHeader {
-			headerFile := a.CPreliminary + "\n"
-			for _, m := range ctx.Methods {
-				mthd := a.Methods[m.Ast.Name]
-				headerFile += GetTypeAsString(m.ReturnType, a) + " " + mthd.GetFullPath() + "(" + CreateMethArgs(mthd.Arguments, a) + ");\n"
-			}
+			ioutil.WriteFile(inputFile+".h", []byte(headerCode(a, ctx)), 0755)
+		}
 
-			ioutil.WriteFile(inputFile+".h", []byte(headerFile), 0755)
+		// Sources are built one after the other, the C of one isn't needed once it was compiled
+		filePath := path.Join(directory, strings.TrimSuffix(path.Base(inputFile), ".g")+(map[bool]string{true: ".cc", false: ".c"}[cfg.Compiler == "g++"]))
+		if err := ioutil.WriteFile(filePath, []byte(code), 0644); err != nil {
+			s.Diagnostics().Add(errors.NewError(errors.ToolchainFailure, lexer.Position{}, "couldn't write the generated C to "+filePath+": "+err.Error(), nil))
+			abort()
 		}
 
-		filePath := directory + inputFile[0:len(inputFile)-1] + (map[bool]string{true: "cc", false: "c"}[cfg.Compiler == "g++"])
-		err := os.MkdirAll(path.Dir(filePath), 0755)
-		err = ioutil.WriteFile(filePath, []byte(code), 0755)
+		objects, runtimeErr := s.buildRuntime(cfg)
+		if runtimeErr != nil {
+			s.Diagnostics().Add(runtimeErr)
+			abort()
+		}
 
-		if err != nil {
-			panic(err)
+		runtimeLibs := []string{}
+		for _, object := range objects {
+			if !funk.ContainsString(outputs, object) && !funk.ContainsString(s.builtModules, object) {
+				runtimeLibs = append(runtimeLibs, object)
+			}
 		}
 
 		if format == "executable" {
 			args := []string{cfg.Toolchain + cfg.Compiler, "-o", outputPath, filePath}
 			args = append(args, outputs...)
-			args = append(args, builtModules...)
+			args = append(args, runtimeLibs...)
+			args = append(args, s.builtModules...)
 			args = append(args, cfg.Flags...)
+			args = append(args, debugFlags(cfg)...)
 			cmd := exec.Command(args[0], args[1:len(args)]...)
-			streamCommand(cmd)
+			s.runCommand(cmd)
 		} else if format == "library" {
-			args := []string{cfg.Toolchain + cfg.Compiler, "-I.", "-o", outputPath, "-c", filePath}
+			args := []string{cfg.Toolchain + cfg.Compiler, includeDir, "-o", outputPath, "-c", filePath}
 			args = append(args, outputs...)
-			args = append(args, builtModules...)
+			args = append(args, s.builtModules...)
 			args = append(args, cfg.Flags...)
+			args = append(args, debugFlags(cfg)...)
 			cmd := exec.Command(args[0], args[1:len(args)]...)
-			streamCommand(cmd)
+			s.runCommand(cmd)
+			outputs = append(outputs, runtimeLibs...)
 		}
 
 		outputPath = strings.Trim(outputPath, " ")
 
+		if cfg.Debug && format == "executable" && utils.FileExists(outputPath) {
+			s.writeArtefact(debugScriptPath(outputPath), compilationOf(a).debugScript())
+		}
+
 		outputs = append(outputs, outputPath)
 	}
 
 	return outputs
 }
 
-func ReadBuildJson(file string, cfg *config.BuildConfig) {
+// optimisation : Returns the optimisation level the O option asks for
+func optimisation(options map[string]string) int {
+	level, err := strconv.Atoi(options["O"])
+	if err != nil {
+		return ir.DefaultOptimisation
+	} else if level > ir.MaxOptimisation {
+		return ir.MaxOptimisation
+	}
+
+	return level
+}
+
+// ReadBuildJson : Reads the build configuration in file into cfg
+func ReadBuildJson(file string, cfg *config.BuildConfig) error {
 	configFile, err := os.Open(file)
 	if err != nil {
-		compileLogger.Fatal("opening config file", err.Error())
+		return fmt.Errorf("opening config file %s", err.Error())
 	}
+	defer configFile.Close()
 
 	jsonParser := json.NewDecoder(configFile)
 	if err = jsonParser.Decode(cfg); err != nil {
-		compileLogger.Fatal("parsing config file", err.Error()+".", file, "might not be a json file")
+		return fmt.Errorf("parsing config file %s. %s might not be a json file", err.Error(), file)
 	}
-}
 
-var (
-	invokeDir, _ = os.Getwd()
-	firstBuild   = true
-	builtModules = []string{}
-)
+	return nil
+}
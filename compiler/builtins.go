@@ -0,0 +1,144 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/ir"
+	"github.com/neutrino2211/Gecko/tokens"
+)
+
+/*
+	Built in methods
+
+	Values of built in types ([T] lists and map[K]V maps) expose methods that are
+	not declared in any gecko source. Calls to them are lowered to intrinsics,
+	which backends implement with the runtime declared in libgecko.
+*/
+
+var listMethods = map[string]ir.Op{
+	"push": ir.ListPush,
+	"pop":  ir.ListPop,
+}
+
+var mapMethods = map[string]ir.Op{
+	"get":    ir.MapGet,
+	"set":    ir.MapSet,
+	"has":    ir.MapHas,
+	"delete": ir.MapDelete,
+}
+
+func resolveBuiltinVariable(name string, geckoAst *ast.Ast) *ast.Variable {
+	updateMethodAst(geckoAst)
+	return geckoAst.Variables[name]
+}
+
+func isListType(t *tokens.TypeRef) bool {
+	return t != nil && t.Array != nil
+}
+
+func isMapType(t *tokens.TypeRef) bool {
+	return t != nil && t.Map != nil
+}
+
+// literalSymbol : Returns the symbol a literal refers to if it is nothing but a bare symbol
+func literalSymbol(lit *tokens.Literal) string {
+	if len(lit.Symbol) > 0 || lit.Expression == nil {
+		return lit.Symbol
+	}
+
+	eq := lit.Expression.Equality
+	if eq.Next != nil || eq.Comparison.Next != nil || eq.Comparison.Addition.Next != nil {
+		return ""
+	}
+
+	mult := eq.Comparison.Addition.Multiplication
+	if mult.Next != nil || mult.Unary.Primary == nil {
+		return ""
+	}
+
+	return mult.Unary.Primary.Symbol
+}
+
+// builtinArgument : Finds a built in method's argument by name, falling back to its position
+func builtinArgument(call *tokens.FuncCall, index int, name string, geckoAst *ast.Ast) *tokens.Literal {
+	for _, arg := range call.Arguments {
+		if arg.Name == name {
+			return arg.Value
+		}
+	}
+
+	if index < len(call.Arguments) && call.Arguments[index].Name == "" {
+		return call.Arguments[index].Value
+	}
+
+	errors.AddError(errors.NewError(errors.MissingArgument, call.Pos, call.Function+" requires the argument '"+name+"'", geckoAst))
+	return missingArgument()
+}
+
+func buildBuiltinMethodCallStep(call *tokens.FuncCall, geckoAst *ast.Ast) *MethodCall {
+	dot := strings.LastIndex(call.Function, ".")
+	if dot < 0 {
+		return nil
+	}
+
+	variable := resolveBuiltinVariable(call.Function[:dot], geckoAst)
+	method := call.Function[dot+1:]
+
+	if variable == nil {
+		return nil
+	} else if isListType(variable.Type) && listMethods[method] != "" {
+		return buildListMethodCallStep(call, variable, method, geckoAst)
+	} else if isMapType(variable.Type) && mapMethods[method] != "" {
+		return buildMapMethodCallStep(call, variable, method, geckoAst)
+	}
+
+	return nil
+}
+
+func buildListMethodCallStep(call *tokens.FuncCall, variable *ast.Variable, method string, geckoAst *ast.Ast) *MethodCall {
+	args := map[string]*tokens.Literal{
+		"list": &tokens.Literal{Symbol: variable.GetFullPath()},
+	}
+	argsOrder := []string{"list"}
+
+	if method == "push" {
+		item := builtinArgument(call, 0, "item", geckoAst)
+		flattenValue(item, geckoAst)
+		args["item"] = item
+		argsOrder = append(argsOrder, "item")
+	}
+
+	return &MethodCall{
+		MethodName:    call.Function,
+		Builtin:       listMethods[method],
+		Arguments:     &args,
+		ArgumentOrder: argsOrder,
+	}
+}
+
+func buildMapMethodCallStep(call *tokens.FuncCall, variable *ast.Variable, method string, geckoAst *ast.Ast) *MethodCall {
+	key := builtinArgument(call, 0, "key", geckoAst)
+	flattenValue(key, geckoAst)
+
+	args := map[string]*tokens.Literal{
+		"map": &tokens.Literal{Symbol: variable.GetFullPath()},
+		"key": key,
+	}
+	argsOrder := []string{"map", "key"}
+
+	if method == "set" {
+		value := builtinArgument(call, 1, "value", geckoAst)
+		flattenValue(value, geckoAst)
+		args["value"] = value
+		argsOrder = append(argsOrder, "value")
+	}
+
+	return &MethodCall{
+		MethodName:    call.Function,
+		Builtin:       mapMethods[method],
+		Arguments:     &args,
+		ArgumentOrder: argsOrder,
+	}
+}
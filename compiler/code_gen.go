@@ -1,67 +1,66 @@
 package compiler
 
 import (
-	"math/rand"
-	"time"
+	"math"
+	"strconv"
+	"strings"
 
-	"github.com/thoas/go-funk"
-
-	"github.com/fatih/color"
+	"github.com/alecthomas/participle/lexer"
 	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/ir"
+	"github.com/neutrino2211/Gecko/libgecko"
 	"github.com/neutrino2211/Gecko/tokens"
+	"github.com/neutrino2211/Gecko/utils"
 )
 
-/*
-  WARNING: ALL BYTECODE IS SUBJECT TO CHANGE!
-*/
-
-var typeMap = map[string]string{
-	"string":   "char *",
+// builtinTypes : The C names of gecko's built in types
+var builtinTypes = map[string]string{
+	"string":   "gecko_string",
+	"byte":     "unsigned char",
+	"i8":       "int8_t",
+	"i16":      "int16_t",
+	"i32":      "int32_t",
+	"i64":      "int64_t",
+	"u8":       "uint8_t",
+	"u16":      "uint16_t",
+	"u32":      "uint32_t",
+	"u64":      "uint64_t",
+	"usize":    "size_t",
+	"f32":      "float",
+	"f64":      "double",
 	"char *[]": "char **",
 }
 
-var (
-	types              = ""
-	methods            = ""
-	functionSignatures = ""
-)
+// lineDirective : Tells the C compiler that the code after it was generated from pos, so its
+// errors, warnings and debug information point at the gecko source
+func lineDirective(pos lexer.Position) string {
+	if pos.Line == 0 || pos.Filename == "" {
+		return ""
+	}
 
-func addCode(s string, a string) string {
-	return s + a + "\n"
+	return "#line " + strconv.Itoa(pos.Line) + " " + strconv.Quote(pos.Filename) + "\n"
 }
 
-func randomString(n int) string {
-	const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	const (
-		letterIdxBits = 6                    // 6 bits to represent a letter index
-		letterIdxMask = 1<<letterIdxBits - 1 // All 1-bits, as many as letterIdxBits
-		letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
-	)
-	b := make([]byte, n)
-	rand.Seed(time.Now().UnixNano())
-	// A rand.Int63() generates 63 random bits, enough for letterIdxMax letters!
-	for i, cache, remain := n-1, rand.Int63(), letterIdxMax; i >= 0; {
-		if remain == 0 {
-			cache, remain = rand.Int63(), letterIdxMax
-		}
-		if idx := int(cache & letterIdxMask); idx < len(letterBytes) {
-			b[i] = letterBytes[idx]
-			i--
-		}
-		cache >>= letterIdxBits
-		remain--
+// positioned : Puts a line directive for pos before every line of code, so the C compiler reports
+// all of them at pos however many lines the code takes
+func positioned(pos lexer.Position, code string) string {
+	directive := lineDirective(pos)
+	if directive == "" {
+		return code
 	}
 
-	return string(b)
-}
+	r := ""
+	for _, line := range strings.SplitAfter(code, "\n") {
+		if line != "" {
+			r += directive + line
+		}
+	}
 
-func GetPreludeCode() string {
-	return types + "\n" + functionSignatures + "\n" + methods
+	return r
 }
 
 func GetTypeAsString(v *tokens.TypeRef, geckoAst *ast.Ast) string {
 	r := ""
-	tmp := ""
 	tyr := v
 
 	if geckoAst.Types[tyr.Type] != nil {
@@ -83,25 +82,20 @@ func GetTypeAsString(v *tokens.TypeRef, geckoAst *ast.Ast) string {
 		r += tyr.Type
 	}
 
+	typeMap := builtinTypes
+	if c := compilationOf(geckoAst); c != nil {
+		typeMap = c.typeMap
+	}
+
 	if len(typeMap[r]) > 0 {
 		r = typeMap[r]
 	}
 
+	// [T] values are runtime lists, the item type only matters when indexing
 	if tyr.Array != nil {
-		for tyr.Array != nil {
-			r += "*"
-			tyr = tyr.Array
-		}
-
-		if len(tyr.Type) > 0 {
-			tmp = tyr.Type
-		}
-
-		if len(typeMap[tmp]) > 0 {
-			tmp = typeMap[tmp]
-		}
-
-		r = tmp + r
+		r = "gecko_list"
+	} else if tyr.Map != nil {
+		r = "gecko_map"
 	}
 
 	if len(typeMap[r]) > 0 {
@@ -124,227 +118,434 @@ func CreateMethArgs(args []*tokens.Value, geckoAst *ast.Ast) string {
 	}
 }
 
-// func generateExpression(e *tokens.Expression) string {
+/*
+	C generation
 
-// }
+	The C backend only reads the IR. Locals are declared at the top of their
+	function and blocks become labels, conditions and gotos, so the C mirrors
+	the IR one instruction at a time and every line of it has a #line for the
+	gecko code it came from.
+*/
 
-func codeify(v *tokens.Literal, ast *ast.Ast) string {
-	if v.Array != nil {
-		// refID := randomString(32)
-		// s := "allocate % " + refID + "\n"
-		arr := "{"
-		for _, v := range v.Array {
-			arr += codeify(v, ast) + ","
-		}
-		arr = arr[0 : len(arr)-1]
-		arr += "}"
-
-		// ar := funk.ReverseString(funk.ReverseString(strings.Join(strings.Split(s, "\n"), ","))[1:])
-
-		return arr
-	} else if v.Object != nil {
-		compileLogger.Log(v.Object)
-		obj := "{"
-		for _, o := range v.Object {
-			flattenValue(o.Value, ast)
-			obj += "." + o.Key + " = " + codeify(o.Value, ast) + ","
-		}
-		obj += "}"
-		return obj
-	} else if v.ArrayIndex != nil {
-		return ""
-	} else if len(v.Bool) > 0 {
-		return v.Bool
-	} else if len(v.Number) > 0 {
-		return v.Number
-	} else if len(v.String) > 0 {
-		return v.String
-	} else if v.FuncCall != nil {
-		methCall := buildMethodCallStep(v.FuncCall, ast).Code(ast)
-		return methCall[0 : len(methCall)-2]
-	} else {
-		return v.Symbol
+// generateC : Generates the C source of a lowered package
+func generateC(m *ir.Module, format string) string {
+	code := libgecko.Header() + m.Preamble + "\n"
+
+	for _, s := range m.Structs {
+		code += structCode(s)
 	}
-}
 
-func (f *LoopStep) Code(scope *ast.Ast) string {
-	// scope.MergeWithParents()
-	// flattenValue(f.SourceArray, scope)
-	// compileLogger.Log(f.SourceArray)
-	counterName := f.TargetVariable.GetFullPath() + randomString(8) + "counter"
-	loopArrayName := scope.GetFullPath() + randomString(8) + "array"
+	code += "\n"
+	for _, f := range m.Functions {
+		code += functionSignature(f) + ";\n"
+	}
 
-	code := "int " + counterName + " = 0;"
+	code += "\n" + globalsCode(m)
 
-	// f.Execution.Ast.Variables[f.TargetVariable.Name] = nil
-	// scope.Variables[f.TargetVariable.Name] = nil
+	for _, f := range m.Functions {
+		code += functionCode(f)
+	}
 
-	delete(scope.Variables, f.TargetVariable.Name)
-	delete(f.Execution.Ast.Variables, f.TargetVariable.Name)
+	if format == "executable" && m.Main != nil {
+		code += "\n" + positioned(m.Main.Pos, mainWrapperCode(m.Main))
+	}
 
-	// scope.MergeWithParents()
+	return code
+}
 
-	//Remove the
-	for _, step := range f.Execution.Steps {
-		if step.Expression != nil && step.Expression.Name == f.TargetVariable.GetFullPath() && !step.Expression.IsAssignement {
-			step.Expression = nil
-			break
-		}
+func structCode(s *ir.Struct) string {
+	r := "typedef struct {\n"
+	for _, field := range s.Fields {
+		r += field.Type.Name + " " + field.Name + ";\n"
 	}
 
-	code = addCode(code, "int "+loopArrayName+"[] = "+codeify(f.SourceArray, scope)+";")
-	code = addCode(code, GetTypeAsString(f.TargetVariable.Type, scope)+" "+f.TargetVariable.GetFullPath()+";")
+	return r + "} " + s.Name + ";\n"
+}
 
-	code = addCode(code, "while("+counterName+"< sizeof("+loopArrayName+")/sizeof("+GetTypeAsString(f.TargetVariable.Type, scope)+")){")
-	code = addCode(code, f.TargetVariable.GetFullPath()+" = "+loopArrayName+"["+counterName+"];")
-	code = addCode(code, f.Execution.Code(scope))
-	code = addCode(code, counterName+"++;\n}")
+func functionSignature(f *ir.Function) string {
+	params := []string{}
+	for _, p := range f.Params {
+		params = append(params, p.Type.Name+" "+p.Name)
+	}
 
-	return code
+	return f.Result.Name + " " + f.Name + " (" + strings.Join(params, ", ") + ")"
 }
 
-func (m *MethodCall) Code(scope *ast.Ast) string {
-	s := ""
-	a := ""
-	// repr.Println(m.Arguments)
-	tmpArgs := *m.Arguments
-	for _, argName := range m.ArgumentOrder {
-		k := tmpArgs[argName]
-		if k == nil && tmpArgs[""] != nil {
-			k = tmpArgs[""]
-		} else if k == nil {
-			compileLogger.Fatal(color.HiRedString("transpile error: %s [%s] requires at least one unnamed argument. None passed", m.MethodFullName, m.MethodName))
+// globalsCode : Declares the globals of m. Lists and maps can't be built by an initialiser so
+// they are built by a function that runs before main
+func globalsCode(m *ir.Module) string {
+	r := ""
+	init := ""
+
+	for _, g := range m.Globals {
+		switch g.Value.(type) {
+		case nil:
+			r += positioned(g.Pos, g.Type.Name+" "+g.Name+";\n")
+		case *ir.ListLit, *ir.MapLit:
+			r += positioned(g.Pos, g.Type.Name+" "+g.Name+";\n")
+			init += positioned(g.Pos, instrCode(&ir.Assign{Dest: &ir.Ref{Var: g}, Value: g.Value}))
+		default:
+			r += positioned(g.Pos, localCode(g))
 		}
-		instruction := codeify(k, scope)
-		// identification := funk.ReverseString(strings.Split(funk.ReverseString(strings.Split(instruction, "\n")[0]), " ")[0])
-		// s = addCode(s, instruction)
-		// s = addCode(s, "db "+m.MethodFullName+"__"+a+" @"+identification)
-		a += instruction + ","
-	}
-	// s = addCode(s, "call "+m.MethodFullName)
-	if len(a) != 0 {
-		a = a[0 : len(a)-1]
-	}
-	s = addCode(s, m.MethodFullName+"("+a+");")
-	return s
+	}
+
+	if init != "" {
+		r += "__attribute__((constructor)) static void " + m.Name + ast.Separator + "0globals(void){\n" + init + "}\n"
+	}
+
+	return r
 }
 
-func (c *Conditional) Code(ast *ast.Ast) string {
-	s := ""
+// localCode : Declares a variable, constants are initialised where they are declared and
+// string constants with an initialiser C accepts outside of a function
+func localCode(v *ir.Variable) string {
+	if c, ok := v.Value.(*ir.Const); ok && v.Const && c.T.Kind == ir.String {
+		return "static const " + v.Type.Name + " " + v.Name + " = GECKO_STR_CONST(" + cQuote(c.Str) + ");\n"
+	} else if v.Const {
+		return "static const " + v.Type.Name + " " + v.Name + " = " + valueCode(v.Value) + ";\n"
+	} else if v.Value != nil {
+		return v.Type.Name + " " + v.Name + " = " + valueCode(v.Value) + ";\n"
+	}
 
-	sectionName := randomString(32)
+	return v.Type.Name + " " + v.Name + ";\n"
+}
 
-	s = addCode(s, "cmp 0 0")
-	// repr.Println(c.Expression)
+func labelName(b *ir.Block) string {
+	return "b" + strconv.Itoa(b.ID)
+}
 
-	s = addCode(s, "section "+sectionName)
+func functionCode(f *ir.Function) string {
+	r := positioned(f.Pos, functionSignature(f)+"{\n")
 
-	code := c.Block.Code(ast)
-	s = addCode(s, code[:len(code)-2])
+	// Code the compiler adds, like the copies of parameters, is put at the function
+	at := func(pos lexer.Position) lexer.Position {
+		if pos.Line == 0 {
+			return f.Pos
+		}
+		return pos
+	}
 
-	s = addCode(s, "ret")
+	for _, v := range f.Locals {
+		r += positioned(at(v.Pos), localCode(v))
+	}
 
-	s = addCode(s, "end "+sectionName)
+	// Only blocks a goto leads to need a label, see terminatorCode
+	labels := map[*ir.Block]bool{}
+	for i, b := range f.Blocks {
+		for _, s := range b.Successors() {
+			if i+1 == len(f.Blocks) || s != f.Blocks[i+1] {
+				labels[s] = true
+			}
+		}
+	}
+
+	for i, b := range f.Blocks {
+		var next *ir.Block
+		if i+1 < len(f.Blocks) {
+			next = f.Blocks[i+1]
+		}
 
-	s = addCode(s, "jz "+sectionName)
+		if labels[b] {
+			r += labelName(b) + ":;\n"
+		}
 
-	return s
+		for _, instr := range b.Instrs {
+			r += positioned(at(instr.Position()), instrCode(instr))
+		}
+
+		if code := terminatorCode(b.Term, next); code != "" {
+			r += positioned(at(b.Term.Position()), code)
+		}
+	}
+
+	return r + "}\n"
 }
 
-func (e *Expression) Code(ast *ast.Ast) string {
+func instrCode(instr ir.Instr) string {
+	switch i := instr.(type) {
+	case *ir.Assign:
+		dest := valueCode(i.Dest)
 
-	r := ""
-	if e.Value != nil {
-		flattenValue(e.Value, ast)
+		switch v := i.Value.(type) {
+		case *ir.ListLit:
+			r := dest + " = gecko_list_new(sizeof(" + v.T.Elem.Name + "));\n"
+			for _, item := range v.Items {
+				r += "GECKO_LIST_PUSH(" + dest + ", " + v.T.Elem.Name + ", " + valueCode(item) + ");\n"
+			}
+			return r
+		case *ir.MapLit:
+			stringKeys := "0"
+			if v.T.Key.Kind == ir.String {
+				stringKeys = "1"
+			}
+
+			r := dest + " = gecko_map_new(sizeof(" + v.T.Elem.Name + "), " + stringKeys + ");\n"
+			for n := range v.Keys {
+				r += "GECKO_MAP_SET(" + dest + ", " + v.T.Elem.Name + ", " + mapKeyCode(v.T, v.Keys[n]) + ", " + valueCode(v.Values[n]) + ");\n"
+			}
+			return r
+		}
+
+		return dest + " = " + valueCode(i.Value) + ";\n"
+	case *ir.Eval:
+		return valueCode(i.Value) + ";\n"
 	}
-	if e.Value.FuncCall != nil {
-		r = buildMethodCallStep(e.Value.FuncCall, ast).Code(ast)
-	} else {
-		r = /*"auto " + strings.ReplaceAll(e.Name, "||", "::") + " = " +*/ codeify(e.Value, ast)
+
+	return ""
+}
+
+// terminatorCode : Ends a block, next is the block that follows it in the C, falling through to it needs no goto
+func terminatorCode(t ir.Terminator, next *ir.Block) string {
+	switch t := t.(type) {
+	case nil:
+		return ""
+	case *ir.Jump:
+		if t.Target == next {
+			return ""
+		}
+		return "goto " + labelName(t.Target) + ";\n"
+	case *ir.Branch:
+		cond := valueCode(t.Cond)
+		if t.Then == next {
+			return "if (!(" + cond + ")) goto " + labelName(t.Else) + ";\n"
+		} else if t.Else == next {
+			return "if (" + cond + ") goto " + labelName(t.Then) + ";\n"
+		}
+		return "if (" + cond + ") goto " + labelName(t.Then) + ";\ngoto " + labelName(t.Else) + ";\n"
+	case *ir.Return:
+		if t.Value == nil {
+			return "return;\n"
+		}
+		return "return " + valueCode(t.Value) + ";\n"
 	}
-	// repr.Println(e.Value)
-	// repr.Println(e)
-	// return "# TODO EVALUATE EXPRESSIONS -> " + e.Name
-	if r == "" {
-		compileLogger.Fatal(color.RedString("Failed to evaluate expression:"))
+
+	return ""
+}
+
+// cEscape : Escapes s for a C string literal. Bytes that aren't printable ASCII are written as octal
+// escapes, which never take more than three digits so they can't swallow the characters after them
+func cEscape(s string) string {
+	r := ""
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			r += "\\" + string(c)
+		case c == '\n':
+			r += "\\n"
+		case c == '\t':
+			r += "\\t"
+		case c == '\r':
+			r += "\\r"
+		case c < ' ' || c > '~':
+			r += "\\" + strconv.FormatInt(int64(c)+01000, 8)[1:]
+		default:
+			r += string(c)
+		}
 	}
+
 	return r
 }
 
-func (obj *ObjectDefinition) Code(scope *ast.Ast) string {
-	r := "typedef struct {\n"
+func cQuote(s string) string {
+	return "\"" + cEscape(s) + "\""
+}
 
-	for name, variable := range obj.Variables {
+// cStringCode : Converts a gecko string value into a C string for external functions
+func cStringCode(v ir.Value) string {
+	if c, ok := v.(*ir.Const); ok && c.T.Kind == ir.String {
+		return cQuote(c.Str)
+	}
 
-		if name == "__ctype__" {
-			continue
-		}
+	return "(" + valueCode(v) + ").data"
+}
 
-		// [Removed] Reason: Caused implicit truncation
-		// value := ""
-		// if variable.Value != nil {
-		// 	flattenValue(variable.Value, obj.Scope)
-		// 	value = ":" + codeify(variable.Value, scope)
-		// }
-		r = addCode(r, GetTypeAsString(variable.Type, obj.Scope)+" "+name+";")
+// formatArg : Returns the printf conversion and argument used to format v
+func formatArg(v ir.Value) (string, string) {
+	t := v.Type()
+
+	switch {
+	case t.Kind == ir.String:
+		return "%s", "(" + valueCode(v) + ").data"
+	case t.Kind == ir.Float:
+		return "%f", valueCode(v)
+	case t.GeckoName == "char" || t.GeckoName == "byte" || t.GeckoName == "u8":
+		return "%c", valueCode(v)
 	}
 
-	r += "} " + obj.Name + ";"
-	return r
+	return "%d", valueCode(v)
+}
+
+func mapKeyCode(t *ir.Type, key ir.Value) string {
+	if t.Key.Kind == ir.String {
+		return "gecko_map_skey(" + cStringCode(key) + ")"
+	}
+
+	return "gecko_map_ikey(" + valueCode(key) + ")"
 }
 
-func (ctx *ExecutionContext) Code(scope *ast.Ast) string {
-	s := ""
+func valuesCode(values []ir.Value) string {
+	r := []string{}
+	for _, v := range values {
+		r = append(r, valueCode(v))
+	}
+
+	return strings.Join(r, ", ")
+}
 
-	for _, class := range ctx.Classes {
-		types = addCode(types, class.Code(scope))
+func constCode(c *ir.Const) string {
+	switch {
+	case c.T.Kind == ir.Bool:
+		return map[bool]string{true: "1", false: "0"}[c.Int != 0]
+	case c.T.Kind == ir.String:
+		return "gecko_str_lit(" + cQuote(c.Str) + ")"
+	case c.T.Kind == ir.Float:
+		f := strconv.FormatFloat(c.Float, 'g', -1, 64)
+		if !strings.ContainsAny(f, ".e") {
+			f += ".0"
+		}
+		return f
+	case c.T.GeckoName == "char":
+		return utils.FormatChar(byte(c.Int))
+	case !c.T.Signed && c.T.Bits >= 64:
+		// Without the suffix C gives numbers above the largest int64 a signed type
+		return strconv.FormatUint(uint64(c.Int), 10) + "ULL"
+	case !c.T.Signed:
+		return strconv.FormatUint(uint64(c.Int), 10)
+	case c.Int == math.MinInt64:
+		// C reads -9223372036854775808 as the negation of a number too large for an int64
+		return "(-9223372036854775807LL - 1)"
 	}
 
-	for _, mthd := range ctx.Methods {
-		// mthd.As
-		if funk.ContainsString(methodsGenerated, mthd.Ast.GetFullPath()) {
-			continue
+	return strconv.FormatInt(c.Int, 10)
+}
+
+func isComparison(op string) bool {
+	return op == "<" || op == ">" || op == "<=" || op == ">="
+}
+
+func valueCode(v ir.Value) string {
+	switch v := v.(type) {
+	case *ir.Const:
+		return constCode(v)
+	case *ir.Null:
+		return "NULL"
+	case *ir.Ref:
+		return v.Var.Name
+	case *ir.Extern:
+		return v.Name
+	case *ir.Member:
+		// Lists and maps are handles to the runtime's structs
+		if k := v.X.Type().Kind; k == ir.List || k == ir.Map {
+			return valueCode(v.X) + "->" + v.Name
 		}
-		methodCode := mthd.Code(scope)
-		compileLogger.DebugLogString("building method", mthd.Ast.Name)
-		functionSignature := GetTypeAsString(mthd.ReturnType, mthd.Ast) + " " + mthd.Ast.GetFullPath() + " (" + CreateMethArgs(mthd.Ast.Parent.Methods[mthd.Ast.Name].Arguments, mthd.Ast) + ")"
-
-		functionSignatures += functionSignature + ";\n"
-		methods = addCode(methods, functionSignature+"{")
-		methods = addCode(methods, methodCode)
-		methods = addCode(methods, "}")
-		methodsGenerated = append(methodsGenerated, mthd.Ast.GetFullPath())
-	}
-
-	for _, step := range ctx.Steps {
-		var code string
-
-		if step.Conditional != nil {
-			s = addCode(s, step.Conditional.Code(ctx.Ast))
-		} else if step.MethodCall != nil {
-			s = addCode(s, step.MethodCall.Code(scope))
-		} else if step.Expression != nil {
-			if step.Expression.Value != nil && !step.Expression.IsAssignement {
-				s = addCode(s, GetTypeAsString(step.Expression.Type, ctx.Ast)+" "+step.Expression.Name+" = "+step.Expression.Code(ctx.Ast)+";")
-			} else if step.Expression.IsAssignement {
-				s = addCode(s, step.Expression.Name+" = "+step.Expression.Code(ctx.Ast)+";")
-			} else {
-				s = addCode(s, GetTypeAsString(step.Expression.Type, ctx.Ast)+" "+step.Expression.Name+";")
+		return valueCode(v.X) + "." + v.Name
+	case *ir.Unary:
+		return v.Op + "(" + valueCode(v.X) + ")"
+	case *ir.Binary:
+		x, y := valueCode(v.X), valueCode(v.Y)
+		xString, yString := v.X.Type().Kind == ir.String, v.Y.Type().Kind == ir.String
+
+		switch {
+		case (v.Op == "==" || v.Op == "!=") && (xString || yString):
+			r := "gecko_str_eq(" + x + ", " + y + ")"
+			if v.Op == "!=" {
+				r = "!" + r
 			}
-		} else if step.ReturnStep != nil {
-			s = addCode(s, "return "+codeify(step.ReturnStep, scope)+";")
-		} else if step.Loop != nil {
-			s = addCode(s, step.Loop.Code(scope))
+			return r
+		case isComparison(v.Op) && xString && yString:
+			return "(gecko_str_cmp(" + x + ", " + y + ") " + v.Op + " 0)"
+		case v.Op == "+" && v.T.Kind == ir.String:
+			return "gecko_str_concat(" + x + ", " + y + ")"
+		}
+
+		return "(" + x + " " + v.Op + " " + y + ")"
+	case *ir.Cast:
+		return "((" + v.T.Name + ")" + valueCode(v.X) + ")"
+	case *ir.Format:
+		format := ""
+		args := []string{}
+		for i, arg := range v.Args {
+			specifier, code := formatArg(arg)
+			format += cEscape(strings.ReplaceAll(v.Parts[i], "%", "%%")) + specifier
+			args = append(args, code)
+		}
+		format += cEscape(strings.ReplaceAll(v.Parts[len(v.Parts)-1], "%", "%%"))
+
+		if len(args) == 0 {
+			return "gecko_str_format(\"" + format + "\")"
+		}
+		return "gecko_str_format(\"" + format + "\", " + strings.Join(args, ", ") + ")"
+	case *ir.CString:
+		return cStringCode(v.X)
+	case *ir.Call:
+		return v.Func + "(" + valuesCode(v.Args) + ")"
+	case *ir.Intrinsic:
+		return intrinsicCode(v)
+	case *ir.StructLit:
+		r := "(" + v.T.Name + "){"
+		for i, field := range v.Fields {
+			r += "." + field + " = " + valueCode(v.Values[i]) + ","
 		}
+		return r + "}"
+	}
+
+	// List and map literals are only ever assigned, see instrCode
+	return ""
+}
 
-		if len(code) != 0 {
-			s = addCode(s, code)
+func intrinsicCode(v *ir.Intrinsic) string {
+	args := []string{}
+	for _, arg := range v.Args {
+		args = append(args, valueCode(arg))
+	}
+	t := v.Args[0].Type()
+
+	switch v.Op {
+	case ir.ListPush:
+		return "GECKO_LIST_PUSH(" + args[0] + ", " + t.Elem.Name + ", " + args[1] + ")"
+	case ir.ListPop:
+		return "GECKO_LIST_POP(" + args[0] + ", " + t.Elem.Name + ")"
+	case ir.ListAt:
+		return "GECKO_LIST_AT(" + args[0] + ", " + t.Elem.Name + ", " + args[1] + ")"
+	case ir.ListFree:
+		return "gecko_list_free(" + args[0] + ")"
+	case ir.MapGet:
+		return "GECKO_MAP_GET(" + args[0] + ", " + t.Elem.Name + ", " + mapKeyCode(t, v.Args[1]) + ")"
+	case ir.MapSet:
+		return "GECKO_MAP_SET(" + args[0] + ", " + t.Elem.Name + ", " + mapKeyCode(t, v.Args[1]) + ", " + args[2] + ")"
+	case ir.MapHas:
+		return "gecko_map_has(" + args[0] + ", " + mapKeyCode(t, v.Args[1]) + ")"
+	case ir.MapDelete:
+		return "gecko_map_delete(" + args[0] + ", " + mapKeyCode(t, v.Args[1]) + ")"
+	case ir.MapSlotUsed:
+		return "gecko_map_slot_used(" + args[0] + ", " + args[1] + ")"
+	case ir.MapSlotKey:
+		if t.Key.Kind == ir.String {
+			return "gecko_str_lit(" + args[0] + "->slots[" + args[1] + "].key.str)"
 		}
+		return "(" + v.T.Name + ")" + args[0] + "->slots[" + args[1] + "].key.num"
 	}
 
-	return s
+	return ""
 }
 
-var (
-	methodsGenerated = []string{}
-)
+// mainWrapperCode : Generates the C entry point that forwards the process arguments to Main
+func mainWrapperCode(main *ir.Function) string {
+	passedArgs := []string{}
+
+	for _, p := range main.Params {
+		if p.Type.Kind == ir.List {
+			passedArgs = append(passedArgs, "gecko_str_args(argc, argv)")
+		} else {
+			passedArgs = append(passedArgs, "argc")
+		}
+	}
+
+	call := main.Name + "(" + strings.Join(passedArgs, ", ") + ")"
+
+	if main.Result.GeckoName == "int" {
+		return "int main(int argc, char **argv){return " + call + ";}\n"
+	}
+
+	return "int main(int argc, char **argv){" + call + "; return 0;}\n"
+}
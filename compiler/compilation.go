@@ -0,0 +1,68 @@
+package compiler
+
+import (
+	"strconv"
+
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+/*
+	Compilation state
+
+	Everything the compiler keeps track of while it turns a package into an
+	ir.Module lives in a Compilation, which every scope of the package reaches
+	through ast.Ast.Compilation. State that spans a whole build, like the modules
+	that still have to be built and the errors reported so far, lives in the
+	Session the compilation belongs to. Nothing is shared between sessions so a process
+	can run as many of them as it wants, one after the other or at the same time.
+*/
+
+// Compilation : The state of compiling one package into C
+type Compilation struct {
+	session *Session
+	// typeMap : The C names of gecko types, classes are added as they are built
+	typeMap      map[string]string
+	builtMethods []string
+	builtClasses []string
+	// classes : The gecko names of the C structs generated for classes
+	classes map[string]string
+	// temporaries : How many temporaries each function has declared
+	temporaries map[string]int
+}
+
+func newCompilation(session *Session) *Compilation {
+	c := &Compilation{
+		session:     session,
+		typeMap:     map[string]string{},
+		classes:     map[string]string{},
+		temporaries: map[string]int{},
+	}
+
+	for name, cName := range builtinTypes {
+		c.typeMap[name] = cName
+	}
+
+	return c
+}
+
+// Diagnostics : Returns where the problems found while compiling are reported
+func (c *Compilation) Diagnostics() *errors.Diagnostics {
+	return c.session.Diagnostics()
+}
+
+// compilationOf : Returns the compilation scope is part of
+func compilationOf(scope *ast.Ast) *Compilation {
+	c, _ := scope.FindCompilation().(*Compilation)
+	return c
+}
+
+// temporary : Returns a prefix for the names of C temporaries declared in scope. Prefixes are
+// numbered per function so the same code always gets the same names
+func (c *Compilation) temporary(scope *ast.Ast) string {
+	function := scope.GetFullPath()
+	id := c.temporaries[function]
+	c.temporaries[function]++
+
+	return function + ast.Separator + strconv.Itoa(id)
+}
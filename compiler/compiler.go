@@ -1,10 +1,12 @@
 package compiler
 
 import (
+	"io/ioutil"
 	"os"
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/thoas/go-funk"
 
@@ -25,17 +27,20 @@ import (
 
 var (
 	compileLogger = &logger.Logger{}
+	initOnce      sync.Once
 	graphQLLexer  = lexer.Must(ebnf.New(`
 Comment = "//"  { "\u0000"…"\uffff"-"\n" } .
 CCode = "#"  { "\u0000"…"\uffff"-"\n" } .
 Ident = (alpha | "_" | ".") { "_" | "." | alpha | digit } .
 String = "\"" [ { "\u0000"…"\uffff"-"\""-"\\" | "\\" any } ] "\"" .
+Char = "'" ( "\u0000"…"\uffff"-"'"-"\\" | "\\" any { hex } ) "'" .
 Number = ( digit | "0x" | "." | "_" ) { digit | "." | "_" } .
 Whitespace = " " | "\t" | "\n" | "\r" .
 Digit = digit .
 Punct = "!"…"/" | ":"…"@" | "["…` + "\"`\"" + ` | "{"…"~" .
 alpha = "a"…"z" | "A"…"Z" .
 digit = "0"…"9" .
+hex = "0"…"9" | "a"…"f" | "A"…"F" .
 EOL = ( "\n" | "\r" ) { "\n" | "\r" } .
 any = "\u0000"…"\uffff" .
 `))
@@ -45,8 +50,6 @@ any = "\u0000"…"\uffff" .
 		participle.Elide("Comment", "Whitespace"),
 	)
 
-	modulesToBuild = []string{}
-
 	cli struct {
 		Files []string `arg:"" type:"existingfile" required:"" help:"GraphQL schema files to parse."`
 	}
@@ -93,23 +96,35 @@ func flattenArray(arr []*tokens.Literal, geckoAst *ast.Ast) {
 func flattenValue(value *tokens.Literal, geckoAst *ast.Ast) {
 	if value.Expression != nil {
 		v, _ := evaluate.Evaluate(value.Expression, geckoAst)
-		value.Expression = nil
+		// Expressions that can't be folded are kept and generated as C instead
 		switch v.(type) {
 		case int:
+			value.Expression = nil
 			value.Number = strconv.Itoa(v.(int))
 		case string:
+			value.Expression = nil
 			if v.(string)[0] == '"' {
 				value.String = v.(string)
 			} else {
 				value.Symbol = v.(string)
 			}
+		case uint64:
+			value.Expression = nil
+			value.Number = strconv.FormatUint(v.(uint64), 10)
+		case byte:
+			value.Expression = nil
+			value.Char = utils.FormatChar(v.(byte))
 		case bool:
+			value.Expression = nil
 			b := v.(bool)
 			if b {
 				value.Bool = "true"
 			} else {
 				value.Bool = "false"
 			}
+		case *tokens.FuncCall:
+			value.Expression = nil
+			value.FuncCall = v.(*tokens.FuncCall)
 		}
 	} else if value.Array != nil {
 		flattenArray(value.Array, geckoAst)
@@ -122,12 +137,19 @@ func updateMethodAst(a *ast.Ast) {
 	}
 }
 
-func ParseFile(filename string) *tokens.File {
-	file := &tokens.File{}
+// readSource : Finds filename and returns where it was found and what is in it. Problems are
+// reported to the session and stop the compilation
+func (s *Session) readSource(filename string) (string, string) {
+	scope := &ast.Ast{Name: filename, Compilation: s}
 	baseDirectory, _ := path.Split(filename)
 	filePath := string(os.PathSeparator) + filename
 	wd, err := os.Getwd()
 	searchDirectories := []string{wd, baseDirectory, config.GeckoConfig.StdLibPath, config.GeckoConfig.ModulesPath}
+	if path.IsAbs(filename) {
+		// Absolute paths are only looked for where they point
+		filePath = filename
+		searchDirectories = []string{""}
+	}
 
 	var r *os.File
 
@@ -145,15 +167,15 @@ func ParseFile(filename string) *tokens.File {
 	}
 
 	if err != nil {
-		compileLogger.Error("Couldn't resolve import", filename[:len(filename)-2])
-		os.Exit(1)
+		errors.AddError(errors.NewError(errors.UnresolvedImport, lexer.Position{Filename: filename}, "couldn't resolve import '"+filename[:len(filename)-2]+"'", scope))
+		abort()
 	} else {
 		finalFileName := r.Name()
 		potentialBuildPath := path.Join(path.Dir(finalFileName), "build.json")
 		compileLogger.LogString(finalFileName)
 
-		if utils.FileExists(potentialBuildPath) && !funk.ContainsString(modulesToBuild, potentialBuildPath) {
-			modulesToBuild = append(modulesToBuild, potentialBuildPath)
+		if utils.FileExists(potentialBuildPath) && !funk.ContainsString(s.modulesToBuild, potentialBuildPath) {
+			s.modulesToBuild = append(s.modulesToBuild, potentialBuildPath)
 		}
 	}
 
@@ -174,16 +196,25 @@ func ParseFile(filename string) *tokens.File {
 	// 		}
 	// 	}
 	// }
-	err = parser.Parse(r, file)
+	source, err := ioutil.ReadAll(r)
+	r.Close()
 	if err != nil {
-		tokErr := err.(participle.UnexpectedTokenError)
-		geckoErr := errors.NewError(tokErr.Unexpected.Pos, "unexpected token "+tokErr.Unexpected.Value+" expected "+tokErr.Expected, &ast.Ast{
-			Name: filename,
-		})
-		compileLogger.Error(geckoErr.String())
-		os.Exit(1)
+		errors.AddError(errors.NewError(errors.UnresolvedImport, lexer.Position{Filename: r.Name()}, "couldn't read '"+r.Name()+"': "+err.Error(), scope))
+		abort()
+	}
+	errors.CacheSource(r.Name(), string(source))
+
+	return r.Name(), string(source)
+}
+
+// parseFile : Finds and parses filename. Problems are reported to the session and stop the compilation
+func (s *Session) parseFile(filename string) *tokens.File {
+	name, source := s.readSource(filename)
+
+	file, syntaxErrors := parseSource(name, source, &ast.Ast{Name: filename, Compilation: s})
+	if syntaxErrors > 0 {
+		abort()
 	}
-	r.Close()
 	file.Name = filename
 
 	return file
@@ -206,9 +237,24 @@ func CompileClassEntries(class *tokens.Class) []*tokens.Entry {
 	return entries
 }
 
+// inferFieldType : Gives `name := value` declarations the type of their value
+func inferFieldType(field *tokens.Field, geckoAst *ast.Ast) {
+	inferred := evaluate.InferType(field.Value, geckoAst)
+	if inferred == nil {
+		errors.AddError(errors.NewError(errors.CannotInferType, field.Pos, "cannot infer the type of '"+field.Name+"', declare it as '"+field.Name+": <type> = ...' instead", geckoAst))
+		return
+	}
+
+	t := *inferred
+	field.Type = &t
+}
+
 func CompileEntries(entries []*tokens.Entry, geckoAst *ast.Ast) *ast.Ast {
 	for _, entry := range entries {
 		if entry.Field != nil {
+			if entry.Field.Type == nil {
+				inferFieldType(entry.Field, geckoAst)
+			}
 			variable := &ast.Variable{}
 			variable.FromToken(entry.Field)
 			variable.Scope = geckoAst
@@ -216,7 +262,9 @@ func CompileEntries(entries []*tokens.Entry, geckoAst *ast.Ast) *ast.Ast {
 				if entry.Field.Value.FuncCall != nil {
 					entry.Field.Value.Symbol = entry.Field.Name
 				} else {
+					valueType := evaluate.InferType(entry.Field.Value, geckoAst)
 					flattenValue(entry.Field.Value, geckoAst)
+					evaluate.CheckConversion(entry.Field.Value, valueType, entry.Field.Type, geckoAst)
 				}
 			}
 
@@ -227,13 +275,6 @@ func CompileEntries(entries []*tokens.Entry, geckoAst *ast.Ast) *ast.Ast {
 				assignSymbolVisibility(variable)
 			}
 			geckoAst.Variables[entry.Field.Name] = variable
-		} else if entry.Loop != nil {
-			variable := &ast.Variable{}
-			if entry.Loop.ForOf != nil {
-				variable.FromToken(entry.Loop.ForOf.Variable)
-				geckoAst.Variables[variable.Name] = variable
-				variable.Scope = geckoAst
-			}
 		} else if entry.Method != nil {
 			method := &ast.Method{}
 			method.FromToken(entry.Method)
@@ -266,6 +307,7 @@ func CompileEntries(entries []*tokens.Entry, geckoAst *ast.Ast) *ast.Ast {
 			class.Initialize()
 			classAst.Initialize()
 			classAst.Name = entry.Class.Name
+			classAst.Compilation = geckoAst.FindCompilation()
 			classEntries := CompileClassEntries(entry.Class)
 			classAst = CompileEntries(classEntries, classAst)
 			class.Merge(classAst)
@@ -304,14 +346,32 @@ func CompileEntries(entries []*tokens.Entry, geckoAst *ast.Ast) *ast.Ast {
 	return geckoAst
 }
 
+// Init : Sets up the compiler's logger, sessions call it so it only runs once
 func Init() {
-	compileLogger.Init("compiler engine", 2)
+	initOnce.Do(func() {
+		compileLogger.Init("compiler engine", 2)
+	})
+}
+
+// resolveImports : Parses every package imported by entryFile into entryFile.Imports, in the order they are imported
+func (s *Session) resolveImports(entryFile *tokens.File) {
+	if len(entryFile.Imports) > 0 {
+		return
+	}
+
+	for _, entry := range entryFile.Entries {
+		if len(entry.Import) > 0 {
+			importedFilePath := strings.ReplaceAll(entry.Import, ".", string(os.PathSeparator)) + ".g"
+			entryFile.Imports = append(entryFile.Imports, s.parseFile(importedFilePath))
+		}
+	}
 }
 
 func CompilePass(entryFile *tokens.File, geckoAst *ast.Ast, buildAll bool) (*ast.Ast, *ExecutionContext) {
 	compiledAst := &ast.Ast{}
 	compiledAst.Initialize()
 	compiledAst.Name = entryFile.PackageName
+	compiledAst.Compilation = geckoAst.FindCompilation()
 
 	compileLogger.DebugLogString("Transpiling", color.HiYellowString("'%s'", entryFile.Name))
 
@@ -322,18 +382,14 @@ func CompilePass(entryFile *tokens.File, geckoAst *ast.Ast, buildAll bool) (*ast
 	// 	// }}, entryFile.Entries...)
 	// }
 
-	for _, entry := range entryFile.Entries {
-		if len(entry.Import) > 0 {
-			importedFilePath := strings.ReplaceAll(entry.Import, ".", string(os.PathSeparator)) + ".g"
-			entryFile.Imports = append(entryFile.Imports, ParseFile(importedFilePath))
-		}
-	}
+	compilationOf(geckoAst).session.resolveImports(entryFile)
 
 	ctx := &ExecutionContext{}
 	importedContexts := []*ExecutionContext{}
 	for _, _import := range entryFile.Imports {
 		importAst := &ast.Ast{}
 		importAst.Name = _import.PackageName
+		importAst.Compilation = compiledAst.Compilation
 		importAst.Initialize()
 		compileLogger.DebugLogString("Branching into imported package", color.HiYellowString("'%s'", _import.PackageName))
 		_ast, compileCtx := CompilePass(_import, importAst, buildAll)
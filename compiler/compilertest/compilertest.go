@@ -0,0 +1,300 @@
+package compilertest
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/compiler"
+	"github.com/neutrino2211/Gecko/config"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/ir"
+	"github.com/neutrino2211/Gecko/logger"
+)
+
+/*
+	Test helpers
+
+	The packages that run gecko programs, the C backend, the optimiser, the
+	interpreter and the VM, are tested with the same programs so they can be
+	checked against each other and against what the programs should print.
+	The helpers compile sources the way `gecko compile` does, through a
+	Session, and work with the diagnostics it returns.
+*/
+
+// Program : A gecko program and what running it prints and exits with
+type Program struct {
+	Name   string
+	Source string
+	Output string
+	Status int
+}
+
+// Programs : Programs that use most of the language, every backend has to run them the same way
+var Programs = []Program{
+	{"arithmetic", `package Main
+
+func Main(): int {
+    a: int = 6
+    b: i64 = 7
+    f: f64 = 1.5
+    return a * 2 + 1
+}
+`, "", 13},
+	{"constants", `package Main
+
+##include<stdio.h>
+
+external func printf(format: string = "%d\n", val: int)
+external func puts(val: string)
+
+const limit: int = 10
+const greeting := "hello"
+
+func Main() {
+    const double := limit * 2
+    let count: int = double + 1
+    printf(val: double)
+    printf(val: count)
+    puts(greeting)
+    if (limit > 5) {
+        puts("big limit")
+    }
+}
+`, "20\n21\nhello\nbig limit\n", 0},
+	{"blocks and loops", `package Main
+
+##include<stdio.h>
+
+external func printf(format: string = "%d\n", val: int)
+
+func Main() {
+    a := 3
+    b := 4
+    if (true) {
+        a := 100
+        printf(val: a + b)
+    }
+    printf(val: a)
+    total := 0
+    for v: int of [1, 2, 3] {
+        sq := v * v
+        total = total + sq
+        if (sq > 3) {
+            printf(format: "big %d\n", val: sq + a)
+        }
+    }
+    printf(val: total)
+}
+`, "104\n3\nbig 7\nbig 12\n14\n", 0},
+	{"calls", `package Main
+
+##include<stdio.h>
+
+external func printf(format: string = "%d\n", val: int)
+
+counter: int = 0
+
+func sign(n: int): int {
+  if (n > 0) {
+    return 1
+  } elif (n < 0) {
+    return 0 - 1
+  } else {
+    return 0
+  }
+}
+
+func side(v: int): int {
+    printf(format: "side %d\n", val: v)
+    counter = counter + 1
+    return v
+}
+
+func sub(a: int, b: int): int {
+    return a - b
+}
+
+func Main(): int {
+    printf(val: sign(n: 0 - 5))
+    printf(val: sub(b: side(v: 1), a: side(v: 2)))
+    printf(val: sub(b: counter, a: side(v: 5)))
+    return counter
+}
+`, "-1\nside 1\nside 2\n1\nside 5\n3\n", 3},
+	{"lists and maps", `package Main
+
+##include<stdio.h>
+
+external func printf(format: string = "%d\n", val: int)
+
+func Main(): int {
+    l: [int] = [1, 2, 3]
+    l.push(4)
+    empty: [int] = []
+    empty.push(9)
+    m: map[string]int = {a: 1, b: 2}
+    m.set("c", 30)
+    printf(val: l.pop())
+    printf(val: m.get("c"))
+    printf(val: l.len + m.len + empty.len)
+    return 0
+}
+`, "4\n30\n7\n", 0},
+	{"strings and classes", `package Main
+
+##include<stdio.h>
+
+external func puts(val: string)
+
+class Counter {
+    count: int = 1
+
+    func constructor(self: Counter, start: int): Counter {
+        self.count = self.count + start
+        return self
+    }
+
+    func bump(self: Counter): int {
+        self.count = self.count + 1
+        return self.count
+    }
+}
+
+func Main(): int {
+    c := Counter(start: 3)
+    n := c.bump()
+    name: string = "gecko"
+    puts("${name} counted to ${n}")
+    return 0
+}
+`, "gecko counted to 5\n", 0},
+}
+
+// BuildConfig : The build configuration gecko compile uses when it isn't given one
+func BuildConfig() *config.BuildConfig {
+	// Tests don't read the gecko configuration, they build with the compiler it names by default
+	cc := config.GeckoConfig.DefaultCompiler
+	if cc == "" {
+		cc = "g++"
+	}
+
+	return &config.BuildConfig{
+		Platform: runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Compiler: cc,
+		Type:     "executable",
+		Output:   "gecko.out",
+		Root:     true,
+	}
+}
+
+// Write : Writes source to a.g in a directory that is removed once the test ends, and returns its path
+func Write(t *testing.T, source string) string {
+	t.Helper()
+	logger.SetDefaultDebugMode(0)
+
+	dir, err := ioutil.TempDir("", "gecko")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	file := filepath.Join(dir, "a.g")
+	if err := ioutil.WriteFile(file, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+// Check : Parses and checks source, options are the ones of the compile command. The package is nil
+// when source couldn't be parsed
+func Check(t *testing.T, source string, options map[string]string) (*compiler.Package, []*errors.Error) {
+	t.Helper()
+
+	session := compiler.NewSession(BuildConfig(), options)
+	file, diagnostics := session.Parse(Write(t, source))
+	if session.Failed(diagnostics) {
+		return nil, diagnostics
+	}
+
+	pkg, checked := session.Check(file)
+	return pkg, append(diagnostics, checked...)
+}
+
+// Module : Checks source and returns it lowered to the IR, failing the test if it has errors
+func Module(t *testing.T, source string, options map[string]string) *ir.Module {
+	t.Helper()
+
+	pkg, diagnostics := Check(t, source, options)
+	if pkg == nil || pkg.Module == nil {
+		t.Fatalf("checking failed: %v", diagnostics)
+	}
+
+	return pkg.Module
+}
+
+// Build : Builds source into an executable and returns its path, failing the test if it has errors.
+// It skips the test when there is no C compiler
+func Build(t *testing.T, source string, options map[string]string) string {
+	t.Helper()
+
+	cfg := BuildConfig()
+	if _, err := exec.LookPath(cfg.Compiler); err != nil {
+		t.Skip("no C compiler: " + err.Error())
+	}
+
+	file := Write(t, source)
+	output := filepath.Join(filepath.Dir(file), "a")
+	buildOptions := map[string]string{"output": output}
+	for option, value := range options {
+		buildOptions[option] = value
+	}
+
+	session := compiler.NewSession(cfg, buildOptions)
+	if _, diagnostics := session.Build([]string{file}); session.Failed(diagnostics) {
+		t.Fatalf("building failed: %v", diagnostics)
+	}
+
+	return output
+}
+
+// Run : Runs executable and returns what it prints and the status it exits with
+func Run(t *testing.T, executable string) (string, int) {
+	t.Helper()
+
+	out, err := exec.Command(executable).Output()
+	if exit, ok := err.(*exec.ExitError); ok {
+		return string(out), exit.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(out), 0
+}
+
+// ExpectCodes : Checks source with options as the body of package Main and fails the test unless it
+// reports codes, warnings come first
+func ExpectCodes(t *testing.T, name string, source string, codes []string, options map[string]string) {
+	t.Helper()
+
+	_, diagnostics := Check(t, "package Main\n\n"+source, options)
+	if got := Codes(diagnostics); !reflect.DeepEqual(got, codes) {
+		t.Errorf("%s: got %v, want %v\n%v", name, got, codes, diagnostics)
+	}
+}
+
+// Codes : Returns the codes of diagnostics in the order they were reported
+func Codes(diagnostics []*errors.Error) []string {
+	codes := []string{}
+	for _, d := range diagnostics {
+		codes = append(codes, d.Code)
+	}
+
+	return codes
+}
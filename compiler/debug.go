@@ -0,0 +1,218 @@
+package compiler
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neutrino2211/Gecko/config"
+)
+
+/*
+	Debug builds
+
+	A build with debug set, from build.json or `gecko compile --debug-info`, is
+	compiled with -g -O0 so gdb and lldb can step through it line by line,
+	which the #line directives in the generated C map back to the gecko
+	sources. Next to every program it writes <output>-gdb.py. gdb loads the
+	script on its own when the directory is in its auto-load safe-path, it can
+	also be loaded with `source`. The script prints strings and classes the way
+	gecko shows them, names frames after gecko functions and adds
+	`info gecko-locals`, which lists the variables of a frame by their gecko
+	names. Mangled symbols are turned back into gecko names the same way
+	`gecko demangle` does it.
+*/
+
+// debugFlags : Returns the C compiler flags for cfg's build profile
+func debugFlags(cfg *config.BuildConfig) []string {
+	if !cfg.Debug {
+		return nil
+	}
+
+	return []string{"-g", "-O0"}
+}
+
+// debugScriptPath : Returns where the gdb script of the program at output is written
+func debugScriptPath(output string) string {
+	return output + "-gdb.py"
+}
+
+// debugScript : Returns the gdb script for the program compiled in c
+func (c *Compilation) debugScript() string {
+	structs := []string{}
+	for name := range c.classes {
+		structs = append(structs, name)
+	}
+	sort.Strings(structs)
+
+	table := ""
+	for _, name := range structs {
+		table += "\t" + strconv.Quote(name) + ": " + strconv.Quote(c.classes[name]) + ",\n"
+	}
+
+	return strings.Replace(gdbScript, "\t# classes\n", table, 1)
+}
+
+var gdbScript = `# Generated by gecko, it is rewritten by every debug build.
+#
+# Prints gecko strings and classes, names frames after gecko functions and adds
+# "info gecko-locals". gdb loads it on its own when its directory is in the
+# auto-load safe-path, otherwise run "source" with the path of this file.
+
+import gdb
+import gdb.printing
+from gdb.FrameDecorator import FrameDecorator
+
+# The gecko names of the C structs of classes
+GECKO_CLASSES = {
+	# classes
+}
+
+SYMBOL_CHARS = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+
+def gecko_demangle(symbol):
+	"""Returns the gecko name of a C symbol or None if gecko didn't generate it."""
+	segments = []
+	current = ""
+	i = 0
+	while i < len(symbol):
+		c = symbol[i]
+		if c != "_":
+			if c not in SYMBOL_CHARS:
+				return None
+			current += c
+		elif i + 1 == len(symbol):
+			return None
+		elif symbol[i + 1] == "_":
+			if not current:
+				return None
+			segments.append(current)
+			current = ""
+			i += 1
+		elif symbol[i + 1] == "1":
+			current += "_"
+			i += 1
+		else:
+			current += "_"
+		i += 1
+
+	if not current or not segments:
+		return None
+	segments.append(current)
+
+	for n, segment in enumerate(segments):
+		digits = len(segment) - len(segment.lstrip("0123456789"))
+		if digits > 0:
+			segments[n] = "<%s %s>" % (segment[digits:], segment[:digits])
+
+	return ".".join(segments)
+
+
+class GeckoStringPrinter:
+	def __init__(self, val):
+		self.val = val
+
+	def to_string(self):
+		if int(self.val["data"]) == 0:
+			return ""
+		return self.val["data"].string(length=int(self.val["len"]))
+
+	def display_hint(self):
+		return "string"
+
+
+class GeckoClassPrinter:
+	def __init__(self, name, val):
+		self.name = name
+		self.val = val
+
+	def to_string(self):
+		return self.name
+
+	def children(self):
+		for field in self.val.type.strip_typedefs().fields():
+			yield field.name, self.val[field.name]
+
+
+def gecko_lookup(val):
+	name = val.type.unqualified().name
+	if name == "gecko_string":
+		return GeckoStringPrinter(val)
+	if name in GECKO_CLASSES:
+		return GeckoClassPrinter(GECKO_CLASSES[name], val)
+	return None
+
+
+class GeckoVariable:
+	def __init__(self, variable, frame):
+		self.variable = variable
+		self.frame = frame
+
+	def symbol(self):
+		symbol = self.variable.symbol()
+		if not isinstance(symbol, str):
+			symbol = symbol.print_name
+		return gecko_demangle(symbol) or symbol
+
+	def value(self):
+		value = self.variable.value()
+		if value is None:
+			symbol = self.variable.symbol()
+			if isinstance(symbol, gdb.Symbol):
+				value = symbol.value(self.frame)
+		return value
+
+
+class GeckoFrame(FrameDecorator):
+	def function(self):
+		function = super().function()
+		if isinstance(function, str):
+			return gecko_demangle(function) or function
+		return function
+
+	def frame_args(self):
+		return self.gecko_variables(super().frame_args())
+
+	def frame_locals(self):
+		return self.gecko_variables(super().frame_locals())
+
+	def gecko_variables(self, variables):
+		if variables is None:
+			return None
+		return [GeckoVariable(variable, self.inferior_frame()) for variable in variables]
+
+
+class GeckoFrameFilter:
+	def __init__(self):
+		self.name = "gecko"
+		self.priority = 100
+		self.enabled = True
+
+	def filter(self, frames):
+		return map(GeckoFrame, frames)
+
+
+class GeckoLocals(gdb.Command):
+	"""Lists the variables of the selected frame by their gecko names."""
+
+	def __init__(self):
+		super().__init__("info gecko-locals", gdb.COMMAND_STACK)
+
+	def invoke(self, arg, from_tty):
+		frame = gdb.selected_frame()
+		block = frame.block()
+		while block is not None:
+			for symbol in block:
+				if symbol.is_variable or symbol.is_argument:
+					name = gecko_demangle(symbol.name) or symbol.name
+					gdb.write("%s = %s\n" % (name, symbol.value(frame)))
+			if block.function is not None:
+				break
+			block = block.superblock
+
+
+gdb.printing.register_pretty_printer(gdb.current_objfile(), gecko_lookup, replace=True)
+gdb.current_progspace().frame_filters["gecko"] = GeckoFrameFilter()
+GeckoLocals()
+`
@@ -0,0 +1,101 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/alecthomas/repr"
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/libgecko"
+	"github.com/neutrino2211/Gecko/tokens"
+)
+
+/*
+	Intermediate artefacts
+
+	`gecko compile --emit=<kind>` writes one of the artefacts the compiler makes
+	on its way to a binary and stops, so generated code can be inspected, diffed
+	or checked in. The artefact goes to --output or next to the source, named
+	after it, and nothing is linked or built. Bytecode is the exception, it is
+	a program of its own that `gecko run` can run without a C compiler.
+*/
+
+// EmitKinds : The artefacts --emit can write
+var EmitKinds = []string{"c", "header", "ir", "ast", "tokens", "bytecode"}
+
+// emitPath : Returns where the artefact of kind for inputFile is written
+func emitPath(kind string, inputFile string, outDir string, cmdLineArgs map[string]string, cCompiler string) string {
+	if cmdLineArgs["output"] != "" {
+		return cmdLineArgs["output"]
+	}
+
+	extension := map[string]string{"c": ".c", "header": ".h", "ir": ".ir", "ast": ".ast", "tokens": ".tokens", "bytecode": ".gbc"}[kind]
+	if kind == "c" && cCompiler == "g++" {
+		extension = ".cc"
+	}
+
+	_, name := path.Split(inputFile)
+	return path.Join(outDir, strings.TrimSuffix(name, ".g")+extension)
+}
+
+// writeArtefact : Writes an emitted artefact, failing to do so stops the build
+func (s *Session) writeArtefact(file string, content string) {
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		s.Diagnostics().Add(errors.NewError(errors.ToolchainFailure, lexer.Position{Filename: file}, "couldn't write "+file+": "+err.Error(), nil))
+		abort()
+	}
+}
+
+// headerCode : Declares the methods of a package so C code can call them
+func headerCode(a *ast.Ast, ctx *ExecutionContext) string {
+	header := libgecko.Header() + a.CPreliminary + "\n"
+	for _, m := range ctx.Methods {
+		mthd := a.Methods[m.Ast.Name]
+		header += GetTypeAsString(m.ReturnType, a) + " " + mthd.GetFullPath() + "(" + CreateMethArgs(mthd.Arguments, a) + ");\n"
+	}
+
+	return header
+}
+
+// astCode : Dumps the syntax tree of file
+func astCode(file *tokens.File) string {
+	return repr.String(file, repr.Indent("  "), repr.OmitEmpty(true)) + "\n"
+}
+
+// tokensCode : Lists the tokens the parser sees in source one per line, whitespace and comments are left out
+func (s *Session) tokensCode(name string, source string) string {
+	symbols := map[rune]string{}
+	for symbol, r := range graphQLLexer.Symbols() {
+		symbols[r] = symbol
+	}
+
+	scope := &ast.Ast{Name: name, Compilation: s}
+	lex, err := graphQLLexer.Lex(namedReader{strings.NewReader(source), name})
+	if err != nil {
+		pos, reason := describeSyntaxError(err, name)
+		errors.AddError(errors.NewError(errors.SyntaxError, pos, reason, scope))
+		abort()
+	}
+
+	r := ""
+	for {
+		token, err := lex.Next()
+		if err != nil {
+			pos, reason := describeSyntaxError(err, name)
+			errors.AddError(errors.NewError(errors.SyntaxError, pos, reason, scope))
+			abort()
+		}
+
+		if token.EOF() {
+			return r
+		}
+
+		if symbol := symbols[token.Type]; symbol != "Whitespace" && symbol != "Comment" {
+			r += token.Pos.String() + "\t" + symbol + "\t" + strconv.Quote(token.Value) + "\n"
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package compiler_test
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/compiler"
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+)
+
+const emitted = `package Main
+
+##include<stdio.h>
+
+external func puts(val: string)
+
+func Main(): int {
+    puts("hello")
+    return 0
+}
+`
+
+func TestEmit(t *testing.T) {
+	tests := []struct {
+		kind     string
+		contains string
+	}{
+		{"c", `Main__Main`},
+		{"header", `Main__Main(`},
+		{"ir", "func Main__Main() int {"},
+		{"ast", "PackageName: \"Main\""},
+		{"tokens", "Ident\t\"puts\""},
+		{"bytecode", "GBC"},
+	}
+
+	for _, test := range tests {
+		output := compilertest.Build(t, emitted, map[string]string{"emit": test.kind})
+		byts, err := ioutil.ReadFile(output)
+		if err != nil {
+			t.Errorf("--emit=%s: %v", test.kind, err)
+		} else if !strings.Contains(string(byts), test.contains) {
+			t.Errorf("--emit=%s wrote\n%s\nwhich doesn't contain %q", test.kind, byts, test.contains)
+		}
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	file := compilertest.Write(t, emitted)
+	output := filepath.Join(filepath.Dir(file), "a.c")
+	if _, diagnostics := compiler.NewSession(compilertest.BuildConfig(), map[string]string{"emit": "c", "output": output}).Build([]string{file}); len(diagnostics) > 0 {
+		t.Fatal(diagnostics)
+	}
+
+	byts, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(string(byts), "\n")
+	for i, line := range lines {
+		if strings.Contains(line, `puts(`) && !strings.Contains(line, "extern") {
+			if want := "#line 8 " + strconv.Quote(file); i == 0 || lines[i-1] != want {
+				t.Errorf("the call to puts isn't mapped to its line, want %q before\n%s", want, line)
+			}
+			return
+		}
+	}
+
+	t.Errorf("no call to puts in\n%s", byts)
+}
+
+func TestDebugBuild(t *testing.T) {
+	cfg := compilertest.BuildConfig()
+	cfg.Debug = true
+	if _, err := exec.LookPath(cfg.Compiler); err != nil {
+		t.Skip("no C compiler: " + err.Error())
+	}
+
+	file := compilertest.Write(t, emitted)
+	output := filepath.Join(filepath.Dir(file), "a")
+	if _, diagnostics := compiler.NewSession(cfg, map[string]string{"output": output}).Build([]string{file}); len(diagnostics) > 0 {
+		t.Fatal(diagnostics)
+	}
+
+	if out, _ := compilertest.Run(t, output); out != "hello\n" {
+		t.Errorf("prints %q, want \"hello\\n\"", out)
+	}
+	if _, err := ioutil.ReadFile(output + "-gdb.py"); err != nil {
+		t.Errorf("no gdb script: %v", err)
+	}
+}
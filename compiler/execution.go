@@ -1,12 +1,16 @@
 package compiler
 
 import (
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/alecthomas/participle/lexer"
 	"github.com/fatih/color"
 	"github.com/neutrino2211/Gecko/ast"
 	"github.com/neutrino2211/Gecko/errors"
 	"github.com/neutrino2211/Gecko/evaluate"
+	"github.com/neutrino2211/Gecko/ir"
 	"github.com/neutrino2211/Gecko/tokens"
 	"github.com/neutrino2211/Gecko/utils"
 
@@ -24,6 +28,8 @@ type ExecutionStep struct {
 	Loop         *LoopStep
 	ReturnStep   *tokens.Literal
 	CPreliminary string
+	// Pos : Where the step is in the gecko source, the C it generates is mapped back to it
+	Pos lexer.Position
 }
 
 type ObjectDefinition struct {
@@ -36,6 +42,7 @@ type ObjectDefinition struct {
 type LoopStep struct {
 	_step
 	SourceArray    *tokens.Literal
+	SourceMap      *tokens.MapType
 	TargetVariable *ast.Variable
 	Expression     *tokens.Expression
 	Execution      ExecutionContext
@@ -46,14 +53,20 @@ type MethodCall struct {
 	MethodName     string
 	Arguments      *map[string]*tokens.Literal
 	ArgumentOrder  []string
+	ArgumentTypes  map[string]*tokens.TypeRef
 	MethodFullName string
 	External       bool
+	// ReturnType : What the method returns, nil when it returns nothing
+	ReturnType *tokens.TypeRef
+	// Builtin : The intrinsic a built in method of a list or map is lowered to
+	Builtin ir.Op
 }
 
 type Conditional struct {
 	_step
 	Block      *ExecutionContext
 	Expression *tokens.Expression
+	Kind       string
 }
 
 type Expression struct {
@@ -62,6 +75,7 @@ type Expression struct {
 	Name          string
 	Type          *tokens.TypeRef
 	IsAssignement bool
+	IsConstant    bool
 }
 
 type ExecutionContext struct {
@@ -70,6 +84,8 @@ type ExecutionContext struct {
 	Classes    []*ObjectDefinition
 	Ast        *ast.Ast
 	ReturnType *tokens.TypeRef
+	// Pos : Where the method the context is the body of is declared
+	Pos lexer.Position
 }
 
 func (e *ExecutionContext) Init() {
@@ -79,7 +95,7 @@ func (e *ExecutionContext) Init() {
 func (e *ExecutionContext) Merge(m *ExecutionContext) {
 	compileLogger.LogString("Merging contexts: ", e.Ast.GetFullPath(), ",", m.Ast.GetFullPath())
 	if m.Steps != nil {
-		for _, step := range e.Steps {
+		for _, step := range m.Steps {
 			if !funk.Contains(e.Steps, step) {
 				e.Steps = append(e.Steps, step)
 			}
@@ -107,9 +123,6 @@ func (e *ExecutionContext) Merge(m *ExecutionContext) {
 	// }
 }
 
-var builtMethods = []string{}
-var builtClasses = []string{}
-
 func methodWasBuilt(ctx *ExecutionContext, mthd *ast.Method) bool {
 	for _, m := range ctx.Methods {
 		if m.Ast.Name == mthd.Name {
@@ -155,74 +168,197 @@ func resolveTypeFunction(function string, scope *ast.Ast) *ast.Method {
 			// for n, _ := range finalScope.Classes {
 			// 	println(color.MagentaString(n), finalScope.GetFullPath())
 			// }
-			finalScope = &finalScope.Classes[finalScope.Variables[level].Type.Type].Ast
+			// Variables whose type could not be inferred have no methods, that was reported already
+			t := finalScope.Variables[level].Type
+			if t == nil || finalScope.Classes[t.Type] == nil {
+				return nil
+			}
+			finalScope = &finalScope.Classes[t.Type].Ast
 		}
 	}
 
 	return finalScope.Methods[finalLevel]
 }
 
-func buildConditional(ctx *ExecutionContext, ifBlock interface{}, geckoAst *ast.Ast) *Conditional {
-	conditional := &Conditional{}
-	var b = false
-	switch ifBlock.(type) {
-	case *tokens.If:
-		ifBlock := ifBlock.(*tokens.If)
-		_bool, err := evaluate.Evaluate(ifBlock.Expression, geckoAst)
-		if err != nil {
-			panic(err)
-		} else if utils.IsBool(_bool) {
-			b = _bool.(bool)
-			if b != false {
-				conditional.Block = buildExecutionContext(ifBlock.Value, geckoAst, false)
-				conditional.Expression = ifBlock.Expression
-				ctx.Steps = append(ctx.Steps, &ExecutionStep{
-					Conditional: conditional,
-				})
-			}
-		} else if utils.IsFuncCall(_bool) {
-			conditional.Block = buildExecutionContext(ifBlock.Value, geckoAst, false)
-			conditional.Expression = ifBlock.Expression
-			ctx.Steps = append(ctx.Steps, &ExecutionStep{
-				Conditional: conditional,
-			})
+func findMethod(name string, geckoAst *ast.Ast) *ast.Method {
+	for s := geckoAst; s != nil; s = s.Parent {
+		if s.Methods[name] != nil {
+			return s.Methods[name]
 		}
+	}
 
-	case *tokens.ElseIf:
-		ifBlock := ifBlock.(*tokens.ElseIf)
-		_bool, err := evaluate.Evaluate(ifBlock.Expression, geckoAst)
-
-		if err != nil {
-			panic(err)
-		} else if utils.IsBool(_bool) {
-			b = _bool.(bool)
-			if b != false {
-				conditional.Block = buildExecutionContext(ifBlock.Value, geckoAst, false)
-				conditional.Expression = ifBlock.Expression
-				ctx.Steps = append(ctx.Steps, &ExecutionStep{
-					Conditional: conditional,
-				})
+	return nil
+}
+
+func findVariable(name string, geckoAst *ast.Ast) *ast.Variable {
+	for s := geckoAst; s != nil; s = s.Parent {
+		if s.Variables[name] != nil {
+			return s.Variables[name]
+		}
+	}
+
+	return nil
+}
+
+// checkBinding : Checks that const and let declarations are initialised, constants with a compile time value
+func checkBinding(field *tokens.Field, geckoAst *ast.Ast) {
+	if field.Mutability == "" {
+		return
+	} else if field.Value == nil {
+		errors.AddError(errors.NewError(errors.UninitialisedBinding, field.Pos, field.Mutability+" '"+field.Name+"' must be given a value when it is declared", geckoAst))
+	} else if field.Mutability == "const" && !evaluate.IsConstant(field.Value) {
+		errors.AddError(errors.NewError(errors.NonConstantValue, field.Value.Pos, "the value of const '"+field.Name+"' must be known at compile time", geckoAst).WithNote("declare it with let if its value is only known when the program runs"))
+	}
+}
+
+// newBlockScope : Creates the scope for the body of an if or a loop. Variables declared in
+// a block are only visible inside it and shadow those of the enclosing scopes.
+func newBlockScope(kind string, pos lexer.Position, entries []*tokens.Entry, geckoAst *ast.Ast) *ast.Ast {
+	block := &ast.Ast{}
+	block.Initialize()
+	block.Name = kind + strconv.Itoa(pos.Line)
+	block.Parent = geckoAst
+	block.Block = true
+
+	for _, entry := range entries {
+		if entry.Field != nil {
+			warnShadowing(entry.Field.Name, entry.Field.Pos, block)
+		}
+	}
+
+	block.MergeWithParents()
+	return block
+}
+
+func warnShadowing(name string, pos lexer.Position, block *ast.Ast) {
+	if outer := findVariable(name, block.Parent); outer != nil {
+		errors.AddWarning(errors.NewError(errors.ShadowedVariable, pos, "'"+name+"' shadows a variable declared in an outer scope", block).WithLabel(outer.Pos, "the outer '"+name+"' is declared here"))
+	}
+}
+
+// shadows : Variables of a block that aren't visible yet because their declarations haven't been reached
+type shadows map[string]*ast.Variable
+
+// hideShadows : Makes the names the steps of a block declare refer to the variables of the enclosing
+// scopes until their declarations are reached, so "x: int = x + 1" reads the outer x
+func hideShadows(steps []*ExecutionStep, block *ast.Ast) shadows {
+	hidden := shadows{}
+	if !block.Block {
+		return hidden
+	}
+
+	for _, step := range steps {
+		name := declaredName(step, block)
+		if v := block.Variables[name]; v != nil && v.Scope == block {
+			if outer := findVariable(name, block.Parent); outer != nil {
+				hidden[name] = v
+				block.Variables[name] = outer
 			}
-		} else if utils.IsFuncCall(_bool) {
-			conditional.Block = buildExecutionContext(ifBlock.Value, geckoAst, false)
-			conditional.Expression = ifBlock.Expression
-			ctx.Steps = append(ctx.Steps, &ExecutionStep{
-				Conditional: conditional,
-			})
 		}
+	}
+
+	return hidden
+}
+
+// reveal : Makes the variable step declares visible from now on
+func (h shadows) reveal(step *ExecutionStep, block *ast.Ast) {
+	name := declaredName(step, block)
+	if v := h[name]; v != nil {
+		block.Variables[name] = v
+		delete(h, name)
+	}
+}
+
+// assignedScope : Returns the scope that declares the variable an assignment in geckoAst to name
+// sets, which can be a block, the method or the package around geckoAst
+func assignedScope(name string, geckoAst *ast.Ast) *ast.Ast {
+	if dot := strings.Index(name, "."); dot >= 0 {
+		name = name[:dot]
+	}
+
+	v := geckoAst.Variables[name]
+	for scope := geckoAst; v != nil && scope != nil; scope = scope.Parent {
+		if v.Scope == scope {
+			return scope
+		}
+	}
+
+	return geckoAst
+}
+
+func declaredName(step *ExecutionStep, block *ast.Ast) string {
+	if step.Expression == nil || step.Expression.IsAssignement {
+		return ""
+	}
+
+	for name := range block.Variables {
+		if block.GetFullPath()+ast.Separator+ast.MangleName(name) == step.Expression.Name {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// appendConditional : Adds a conditional to ctx, chaining it onto the previous step when possible
+func appendConditional(ctx *ExecutionContext, conditional *Conditional, kind string, pos lexer.Position, expression *tokens.Expression, value []*tokens.Entry, geckoAst *ast.Ast) {
+	// An elif or else without an if before it has nothing to chain onto
+	if kind != "if" && (len(ctx.Steps) == 0 || ctx.Steps[len(ctx.Steps)-1].Conditional == nil) {
+		kind = map[string]string{"elif": "if", "else": "block"}[kind]
+	}
+
+	conditional.Kind = kind
+	conditional.Block = buildExecutionContext(value, newBlockScope(kind, pos, value, geckoAst), false)
+	conditional.Expression = expression
+	ctx.Steps = append(ctx.Steps, &ExecutionStep{
+		Conditional: conditional,
+		Pos:         pos,
+	})
+}
 
+// buildConditional : Adds an if, elif or else to ctx. Conditions that are always false are kept,
+// the optimiser drops their branches once the package was lowered
+func buildConditional(ctx *ExecutionContext, ifBlock interface{}, geckoAst *ast.Ast) *Conditional {
+	conditional := &Conditional{}
+	switch ifBlock := ifBlock.(type) {
+	case *tokens.If:
+		appendConditional(ctx, conditional, "if", ifBlock.Pos, ifBlock.Expression, ifBlock.Value, geckoAst)
+	case *tokens.ElseIf:
+		appendConditional(ctx, conditional, "elif", ifBlock.Pos, ifBlock.Expression, ifBlock.Value, geckoAst)
 	case *tokens.Else:
-		ifBlock := ifBlock.(*tokens.Else)
-		conditional.Block = buildExecutionContext(ifBlock.Value, geckoAst, false)
-		//TODO: Set conditional expression to true
-		ctx.Steps = append(ctx.Steps, &ExecutionStep{
-			Conditional: conditional,
-		})
+		appendConditional(ctx, conditional, "else", ifBlock.Pos, nil, ifBlock.Value, geckoAst)
 	}
 
 	return conditional
 }
 
+// newObject : Builds the object literal a constructor gets as self, its fields hold the defaults of the class
+func newObject(class *ast.Class, pos lexer.Position) *tokens.Literal {
+	names := []string{}
+	for name, variable := range class.Variables {
+		if name != "__ctype__" && variable.Value != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	object := &tokens.Literal{Braces: true}
+	object.Pos = pos
+	for _, name := range names {
+		// Lowering flattens the value, each object gets its own copy
+		value := *class.Variables[name].Value
+		object.Object = append(object.Object, &tokens.ObjectKeyValue{Key: name, Value: &value})
+	}
+
+	return object
+}
+
+// missingArgument : Stands in for an argument that wasn't passed, the call was reported already
+func missingArgument() *tokens.Literal {
+	missing := true
+	return &tokens.Literal{Nil: &missing}
+}
+
 func buildMethodCallStep(call *tokens.FuncCall, geckoAst *ast.Ast) *MethodCall {
 	mthdStep := &MethodCall{}
 	args := make(map[string]*tokens.Literal)
@@ -236,6 +372,12 @@ func buildMethodCallStep(call *tokens.FuncCall, geckoAst *ast.Ast) *MethodCall {
 	mthd := geckoAst.Methods[call.Function]
 	compileLogger.DebugLogString("building call step for", call.Function)
 
+	if mthd == nil {
+		if builtinStep := buildBuiltinMethodCallStep(call, geckoAst); builtinStep != nil {
+			return builtinStep
+		}
+	}
+
 	if mthd == nil {
 		mthd = resolveTypeFunction(call.Function, geckoAst)
 		if mthd != nil { // This is a type function, add the self variable
@@ -252,18 +394,16 @@ func buildMethodCallStep(call *tokens.FuncCall, geckoAst *ast.Ast) *MethodCall {
 		}
 	}
 
-	if mthd == nil && geckoAst.Classes[call.Function] != nil {
-		mthd = geckoAst.Classes[call.Function].Methods["constructor"]
-		if mthd != nil { // This is a type function, add the self variable
-			for v := range geckoAst.Variables {
-				compileLogger.Log(v)
+	if class := geckoAst.Classes[call.Function]; mthd == nil && class != nil {
+		mthd = class.Methods["constructor"]
+		if mthd != nil { // Calling the class calls its constructor with a new object as self
+			if mthd.Type == nil || mthd.Type.Type != call.Function {
+				errors.AddError(errors.NewError(errors.TypeMismatch, mthd.Pos, "the constructor of '"+call.Function+"' has to return "+call.Function+" to be called as '"+call.Function+"(...)'", geckoAst))
 			}
-			varsList := strings.Split(call.Function, ".")
+
 			self := &tokens.Argument{
-				Name: "self",
-				Value: &tokens.Literal{
-					Symbol: geckoAst.Variables[strings.Join(varsList[0:len(varsList)-1], ".")].GetFullPath(),
-				},
+				Name:  "self",
+				Value: newObject(class, call.Pos),
 			}
 
 			call.Arguments = append([]*tokens.Argument{self}, call.Arguments...)
@@ -271,11 +411,17 @@ func buildMethodCallStep(call *tokens.FuncCall, geckoAst *ast.Ast) *MethodCall {
 	}
 
 	if mthd == nil {
-		compileLogger.Fatal(color.HiRedString("Could not find method %s", call.Function))
+		errors.AddError(errors.NewError(errors.UnknownMethod, call.Pos, "method '"+call.Function+"' not found", geckoAst).WithSuggestion(utils.SimilarSymbol(geckoAst, call.Function)))
+		mthdStep.MethodName = call.Function
+		mthdStep.Arguments = &args
+		return mthdStep
 	}
 
+	argTypes := make(map[string]*tokens.TypeRef)
+
 	for _, arg := range mthd.Arguments {
 		argsOrder = append(argsOrder, arg.Name)
+		argTypes[arg.Name] = arg.Type
 		if arg.Default != nil {
 			flattenValue(arg.Default, geckoAst)
 			args[arg.Name] = arg.Default
@@ -283,45 +429,28 @@ func buildMethodCallStep(call *tokens.FuncCall, geckoAst *ast.Ast) *MethodCall {
 		}
 	}
 
+	// Arguments are resolved where the call is, geckoAst was merged with the scopes around it above
 	for _, arg := range call.Arguments {
 		if arg.Value != nil {
-			mthdAst := mthd.ToAst()
-			// mthdAst.MergeWithParents()
-			mthdAst.Name = geckoAst.Name
-			// Hack for adding variables to function calls when their scope has not been finalized
-			if geckoAst.Parent != nil && geckoAst.Parent.Methods[geckoAst.Name] != nil {
-				mthdAst.Merge(CompileEntries(geckoAst.Parent.Methods[geckoAst.Name].Value, geckoAst))
-			}
-
 			compileLogger.DebugLogString("adding variable", arg.Name, "to", mthd.Name, "call")
-
-			// if arg.Value.Expression != nil {
-			// 	// repr.Println(evaluate.Evaluate(arg.Value.Expression, mthdAst))
-			// 	i := 0
-			// 	for n, mthdArg := range mthd.Arguments {
-			// 		if mthdArg.Name == arg.Name {
-			// 			i = n
-			// 		}
-			// 	}
-
-			// 	repr.Println(arg.Value, mthd.Arguments[i])
-			// }
-			// repr.Println(arg.Value)
-			valTmp := *arg.Value
-			errors.IgnoreNextError()
-			flattenValue(&valTmp, mthdAst)
-			if errors.ErrorWasIgnored() {
-				flattenValue(arg.Value, geckoAst)
-			} else {
-				arg.Value = &valTmp
-			}
+			flattenValue(arg.Value, geckoAst)
 			args[arg.Name] = arg.Value
 		}
 	}
+
+	// An unnamed value fills every argument that wasn't passed by name
+	for _, name := range argsOrder {
+		if args[name] == nil && args[""] == nil {
+			errors.AddError(errors.NewError(errors.MissingArgument, call.Pos, call.Function+" requires the argument '"+name+"'", geckoAst))
+			args[name] = missingArgument()
+		}
+	}
 	mthdStep.MethodName = call.Function
 	mthdStep.Arguments = &args
 	mthdStep.External = mthd.Visibility == "external"
 	mthdStep.ArgumentOrder = argsOrder
+	mthdStep.ArgumentTypes = argTypes
+	mthdStep.ReturnType = mthd.Type
 	if mthdStep.External {
 		// compileLogger.DebugLogString("method", mthd.Name, "is external", call.Function)
 		mthdStep.MethodFullName = mthd.Name
@@ -332,8 +461,33 @@ func buildMethodCallStep(call *tokens.FuncCall, geckoAst *ast.Ast) *MethodCall {
 	return mthdStep
 }
 
+// sortedVariables : Orders variables the way they are declared in the source, arguments first, so
+// the same package always compiles to the same code
+func sortedVariables(variables map[string]*ast.Variable) []*ast.Variable {
+	sorted := []*ast.Variable{}
+	for _, variable := range variables {
+		sorted = append(sorted, variable)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i].Pos, sorted[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		} else if a.Line != b.Line {
+			return a.Line < b.Line
+		} else if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
+
 func buildExecutionContext(entries []*tokens.Entry, geckoAst *ast.Ast, buildAll bool) *ExecutionContext {
 	ctx := &ExecutionContext{}
+	c := compilationOf(geckoAst)
 
 	ctx.Methods = []*ExecutionContext{}
 	ctx.Steps = []*ExecutionStep{}
@@ -353,7 +507,7 @@ func buildExecutionContext(entries []*tokens.Entry, geckoAst *ast.Ast, buildAll
 			name = class.Class.Name
 		}
 
-		if funk.ContainsString(builtClasses, name) {
+		if funk.ContainsString(c.builtClasses, name) {
 			continue
 		}
 
@@ -363,9 +517,10 @@ func buildExecutionContext(entries []*tokens.Entry, geckoAst *ast.Ast, buildAll
 			Scope:     geckoAst,
 		})
 
-		typeMap[class.Class.Name] = name
+		c.typeMap[class.Class.Name] = name
+		c.classes[name] = class.Class.Name
 
-		builtClasses = append(builtClasses, name)
+		c.builtClasses = append(c.builtClasses, name)
 
 		for _, mthd := range class.Methods {
 			compileLogger.DebugLogString("building execution context for method", color.HiYellowString("'%s'", mthd.Name), "in class", color.HiYellowString("'%s'", class.Class.Name))
@@ -373,16 +528,31 @@ func buildExecutionContext(entries []*tokens.Entry, geckoAst *ast.Ast, buildAll
 		}
 	}
 
-	for _, variable := range geckoAst.Variables {
+	for _, variable := range sortedVariables(geckoAst.Variables) {
+		// Blocks declare their own variables as they reach them and share everything else with their parents
+		if geckoAst.Block {
+			break
+		} else if variable.Scope != geckoAst {
+			continue
+		}
 		ctx.Steps = append(ctx.Steps, &ExecutionStep{
 			Expression: &Expression{
-				Name:  variable.GetFullPath(),
-				Value: variable.Value,
-				Type:  variable.Type,
+				Name:       variable.GetFullPath(),
+				Value:      variable.Value,
+				Type:       variable.Type,
+				IsConstant: variable.IsConst(),
 			},
+			Pos: variable.Pos,
 		})
 	}
 
+	// Resolve the scope's own variables so their values are checked before any code is generated
+	if geckoAst.Block {
+		CompileEntries(entries, geckoAst)
+	} else {
+		updateMethodAst(geckoAst)
+	}
+
 	for _, mthd := range classMethods {
 		mthdAst := mthd.ToAst()
 		methodContext := buildExecutionContext(mthd.Method.Value, mthdAst, buildAll)
@@ -394,8 +564,9 @@ func buildExecutionContext(entries []*tokens.Entry, geckoAst *ast.Ast, buildAll
 				NonNullable: false,
 			}
 		}
+		methodContext.Pos = mthd.Pos
 		ctx.Methods = append(ctx.Methods, methodContext)
-		builtMethods = append(builtMethods, mthd.GetFullPath())
+		c.builtMethods = append(c.builtMethods, mthd.GetFullPath())
 	}
 
 	for _, entry := range entries {
@@ -411,35 +582,41 @@ func buildExecutionContext(entries []*tokens.Entry, geckoAst *ast.Ast, buildAll
 						NonNullable: false,
 					}
 				}
+				methodContext.Pos = mthd.Pos
 				ctx.Methods = append(ctx.Methods, methodContext)
-				builtMethods = append(builtMethods, mthd.GetFullPath())
+				c.builtMethods = append(c.builtMethods, mthd.GetFullPath())
 			}
 
 			ctx.Steps = append(ctx.Steps, &ExecutionStep{
 				MethodCall: buildMethodCallStep(entry.FuncCall, geckoAst),
+				Pos:        entry.Pos,
 			})
 		} else if entry.If != nil {
 			isBool := evaluate.CouldBeBool(entry.If.Expression, geckoAst)
 			if isBool {
 				buildConditional(ctx, entry.If, geckoAst)
 			} else {
-				errors.AddError(errors.NewError(entry.If.Pos, "Expression does not evaluate to a bool", geckoAst))
+				errors.AddError(errors.NewError(errors.NonBoolCondition, entry.If.Pos, "Expression does not evaluate to a bool", geckoAst))
 			}
 		} else if entry.ElseIf != nil {
 			isBool := evaluate.CouldBeBool(entry.ElseIf.Expression, geckoAst)
 			if isBool {
 				buildConditional(ctx, entry.ElseIf, geckoAst)
 			} else {
-				errors.AddError(errors.NewError(entry.ElseIf.Pos, "Expression does not evaluate to a bool", geckoAst))
+				errors.AddError(errors.NewError(errors.NonBoolCondition, entry.ElseIf.Pos, "Expression does not evaluate to a bool", geckoAst))
 			}
 		} else if entry.Else != nil {
 			buildConditional(ctx, entry.Else, geckoAst)
 		} else if entry.Field != nil {
+			if entry.Field.Type == nil {
+				inferFieldType(entry.Field, geckoAst)
+			}
+			checkBinding(entry.Field, geckoAst)
 			name := ""
 			if entry.Field.Visibility == "external" {
 				name = entry.Field.Name
 			} else {
-				name = geckoAst.GetFullPath() + "__" + entry.Field.Name
+				name = geckoAst.GetFullPath() + ast.Separator + ast.MangleName(entry.Field.Name)
 			}
 
 			// class := geckoAst.Classes[entry.Field.Type.Type]
@@ -460,14 +637,24 @@ func buildExecutionContext(entries []*tokens.Entry, geckoAst *ast.Ast, buildAll
 					Value:         entry.Field.Value,
 					Type:          entry.Field.Type,
 					IsAssignement: false,
+					IsConstant:    entry.Field.Mutability == "const",
 				},
+				Pos: entry.Pos,
 			})
 		} else if entry.Assignment != nil {
 			name := entry.Assignment.Name
+			if variable := findVariable(name, geckoAst); variable != nil && variable.IsImmutable() {
+				errors.AddError(errors.NewError(errors.AssignToImmutable, entry.Assignment.Pos, "cannot assign to '"+name+"', it was declared with "+variable.Mutability, geckoAst).WithLabel(variable.Pos, "'"+name+"' is declared here"))
+			}
 			if geckoAst.Variables[name] != nil && geckoAst.Variables[name].Visibility == "external" {
 				name = geckoAst.Variables[name].Name
 			} else {
-				name = geckoAst.GetFullPath() + "__" + name
+				// Only the variable is part of the symbol, the class fields after it are members
+				fields := ""
+				if dot := strings.Index(name, "."); dot >= 0 {
+					name, fields = name[:dot], name[dot:]
+				}
+				name = assignedScope(name, geckoAst).GetFullPath() + ast.Separator + ast.MangleName(name) + fields
 			}
 			ctx.Steps = append(ctx.Steps, &ExecutionStep{
 				Expression: &Expression{
@@ -475,32 +662,61 @@ func buildExecutionContext(entries []*tokens.Entry, geckoAst *ast.Ast, buildAll
 					Value:         entry.Assignment.Value,
 					IsAssignement: true,
 				},
+				Pos: entry.Pos,
 			})
 		} else if entry.Loop != nil {
 			variable := &ast.Variable{}
-			if entry.Loop.ForOf != nil {
-				variable.FromToken(entry.Loop.ForOf.Variable)
-				geckoAst.Parent.Variables[variable.Name] = variable
-				variable.Scope = geckoAst
-				loopContext := buildExecutionContext(entry.Loop.Value, geckoAst, true)
-				// if entry.Loop.ForOf.SourceArray.Expression != nil {
-				flattenValue(entry.Loop.ForOf.SourceArray, geckoAst)
+			block := newBlockScope("for", entry.Loop.Pos, entry.Loop.Value, geckoAst)
+			if entry.Loop.Iterator != nil {
+				warnShadowing(entry.Loop.Iterator.Variable.Name, entry.Loop.Iterator.Variable.Pos, block)
+			}
+
+			if entry.Loop.Iterator != nil && entry.Loop.Iterator.Kind == "of" {
+				variable.FromToken(entry.Loop.Iterator.Variable)
+				block.Variables[variable.Name] = variable
+				variable.Scope = block
+				loopContext := buildExecutionContext(entry.Loop.Value, block, true)
+				// if entry.Loop.Iterator.SourceArray.Expression != nil {
+				flattenValue(entry.Loop.Iterator.SourceArray, geckoAst)
 				// }
-				// compileLogger.Log(entry.Loop.ForOf.SourceArray)
+				// compileLogger.Log(entry.Loop.Iterator.SourceArray)
+
+				ctx.Steps = append(ctx.Steps, &ExecutionStep{
+					Loop: &LoopStep{
+						Execution:      *loopContext,
+						TargetVariable: variable,
+						SourceArray:    entry.Loop.Iterator.SourceArray,
+					},
+					Pos: entry.Pos,
+				})
+			} else if entry.Loop.Iterator != nil {
+				source := resolveBuiltinVariable(literalSymbol(entry.Loop.Iterator.SourceArray), geckoAst)
+				if source == nil || !isMapType(source.Type) {
+					errors.AddError(errors.NewError(errors.InvalidForIn, entry.Loop.Pos, "for-in loops can only iterate over maps", geckoAst))
+					continue
+				}
+
+				variable.FromToken(entry.Loop.Iterator.Variable)
+				block.Variables[variable.Name] = variable
+				variable.Scope = block
+				loopContext := buildExecutionContext(entry.Loop.Value, block, true)
+				flattenValue(entry.Loop.Iterator.SourceArray, geckoAst)
 
 				ctx.Steps = append(ctx.Steps, &ExecutionStep{
 					Loop: &LoopStep{
 						Execution:      *loopContext,
 						TargetVariable: variable,
-						SourceArray:    entry.Loop.ForOf.SourceArray,
+						SourceArray:    entry.Loop.Iterator.SourceArray,
+						SourceMap:      source.Type.Map,
 					},
+					Pos: entry.Pos,
 				})
 			}
 
 		} else if entry.Method != nil && entry.Method.Visibility != "external" && buildAll {
 			mthd := geckoAst.Methods[entry.Method.Name]
 			compileLogger.DebugLogString("building execution context for method", color.HiYellowString("'%s'", entry.Method.Name))
-			if mthd != nil && !funk.Contains(builtMethods, mthd.GetFullPath()) {
+			if mthd != nil && !funk.Contains(c.builtMethods, mthd.GetFullPath()) {
 				mthdAst := mthd.ToAst()
 				mthdAst.MergeWithParents()
 				methodContext := buildExecutionContext(mthd.Method.Value, mthdAst, buildAll)
@@ -512,13 +728,15 @@ func buildExecutionContext(entries []*tokens.Entry, geckoAst *ast.Ast, buildAll
 						NonNullable: false,
 					}
 				}
+				methodContext.Pos = mthd.Pos
 				ctx.Methods = append(ctx.Methods, methodContext)
-				builtMethods = append(builtMethods, mthd.GetFullPath())
+				c.builtMethods = append(c.builtMethods, mthd.GetFullPath())
 			}
 		} else if entry.Return != nil {
 			flattenValue(entry.Return, geckoAst)
 			ctx.Steps = append(ctx.Steps, &ExecutionStep{
 				ReturnStep: entry.Return,
+				Pos:        entry.Pos,
 			})
 		}
 	}
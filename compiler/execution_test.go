@@ -0,0 +1,103 @@
+package compiler_test
+
+import (
+	"testing"
+
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+func TestBlockScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		codes  []string
+	}{
+		{"outer variables in blocks", `external func printf(format: string = "%d\n", val: int)
+
+func Main() {
+  x := 2
+  y := 3
+  if (x < y) {
+    printf(val: x + y)
+  }
+  for i: int of [1, 2] {
+    printf(val: x * y + i)
+  }
+}
+`, []string{}},
+		{"block variable after the block", `func Main(): int {
+  if (true) {
+    inner := 1
+  }
+  return inner
+}
+`, []string{errors.UnknownSymbol}},
+		{"loop variable after the loop", `func Main(): int {
+  for i: int of [1, 2] {
+  }
+  return i
+}
+`, []string{errors.UnknownSymbol}},
+		{"shadowed variable", `func Main(): int {
+  n := 2
+  if (true) {
+    n := 3
+    return n
+  }
+  return n
+}
+`, []string{errors.ShadowedVariable}},
+	}
+
+	for _, test := range tests {
+		compilertest.ExpectCodes(t, test.name, test.source, test.codes, nil)
+	}
+}
+
+func TestBlockArguments(t *testing.T) {
+	executable := compilertest.Build(t, `package Main
+
+##include<stdio.h>
+
+external func printf(format: string = "%d\n", val: int)
+
+func Main() {
+  x := 2
+  y := 3
+  if (x < y) {
+    z := 10
+    printf(val: x + y * z)
+  }
+  for i: int of [1, 2] {
+    printf(val: x * y + i)
+  }
+}
+`, nil)
+
+	if out, _ := compilertest.Run(t, executable); out != "32\n7\n8\n" {
+		t.Errorf("prints\n%s\nwant\n32\n7\n8\n", out)
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	tests := []struct {
+		source     string
+		suggestion string
+	}{
+		{"counter := 1\n  return countr", "counter"},
+		{"total := 1\n  return totl", "total"},
+		{"count := 1\n  return cuont", ""},
+		{"z := 1\n  return q", ""},
+		{"total := 1\n  return width", ""},
+	}
+
+	for _, test := range tests {
+		_, diagnostics := compilertest.Check(t, "package Main\n\nfunc Main(): int {\n  "+test.source+"\n}\n", nil)
+		for _, d := range diagnostics {
+			if d.Code == errors.UnknownSymbol && d.Suggestion != test.suggestion {
+				t.Errorf("%q suggests %q, want %q", test.source, d.Suggestion, test.suggestion)
+			}
+		}
+	}
+}
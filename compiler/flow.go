@@ -0,0 +1,206 @@
+package compiler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/evaluate"
+	"github.com/neutrino2211/Gecko/tokens"
+)
+
+/*
+	Control flow analysis
+
+	Follows every path through the steps of a method to find methods that can
+	reach their end without returning a value and variables that can be read
+	before anything was assigned to them. Both would otherwise compile to C that
+	silently works with garbage.
+*/
+
+// assignments : The variables declared without a value on the current path, mapped to
+// whether they are known to have been assigned since
+type assignments map[string]bool
+
+func (a assignments) copy() assignments {
+	r := assignments{}
+	for name, assigned := range a {
+		r[name] = assigned
+	}
+
+	return r
+}
+
+// join : Merges the paths that lead to the same step, a variable is only assigned if it is on all of them
+func join(paths []assignments) assignments {
+	r := paths[0].copy()
+	for _, path := range paths[1:] {
+		for name := range r {
+			r[name] = r[name] && path[name]
+		}
+	}
+
+	return r
+}
+
+// CheckFlow : Reports the methods in ctx that are missing a return and reads of unassigned variables
+func CheckFlow(ctx *ExecutionContext) {
+	for _, mthd := range ctx.Methods {
+		CheckFlow(mthd)
+	}
+
+	if ctx.Ast == nil || ctx.Ast.Parent == nil || ctx.Ast.Parent.Methods[ctx.Ast.Name] == nil {
+		return
+	}
+
+	mthd := ctx.Ast.Parent.Methods[ctx.Ast.Name]
+	returns := checkFlowSteps(ctx.Steps, assignments{}, ctx.Ast)
+	if !returns && !isVoid(ctx.ReturnType) {
+		errors.AddError(errors.NewError(errors.MissingReturn, mthd.Pos, "method '"+mthd.Name+"' must return a value of type "+evaluate.TypeName(ctx.ReturnType)+" but can reach its end without returning", ctx.Ast.Parent).WithNote("every if without an else and every loop can be skipped, add a return after them"))
+	}
+}
+
+func isVoid(t *tokens.TypeRef) bool {
+	return t == nil || t.Type == "void" && t.Array == nil && t.Map == nil
+}
+
+// isScalarType : Checks if t is a number, string or bool. Lists and maps start out empty and
+// class instances are initialised by calling their methods so only these can hold garbage
+func isScalarType(t *tokens.TypeRef) bool {
+	return evaluate.IsNumericType(t) || t != nil && !t.Pointer && t.Array == nil && t.Map == nil && (t.Type == "string" || t.Type == "bool")
+}
+
+// checkFlowSteps : Follows steps from the state in assigned, which is updated along the way.
+// Returns true if every path through steps ends in a return
+func checkFlowSteps(steps []*ExecutionStep, assigned assignments, scope *ast.Ast) bool {
+	for i := 0; i < len(steps); i++ {
+		step := steps[i]
+
+		if step.ReturnStep != nil {
+			checkReads(step.ReturnStep, assigned, scope)
+			return true
+		} else if step.MethodCall != nil {
+			for _, name := range step.MethodCall.ArgumentOrder {
+				checkReads((*step.MethodCall.Arguments)[name], assigned, scope)
+			}
+			checkReads((*step.MethodCall.Arguments)[""], assigned, scope)
+		} else if step.Expression != nil {
+			e := step.Expression
+			checkReads(e.Value, assigned, scope)
+
+			if e.IsAssignement {
+				if _, ok := assigned[e.Name]; ok {
+					assigned[e.Name] = true
+				}
+			} else if e.Value == nil && isScalarType(e.Type) {
+				assigned[e.Name] = false
+			} else {
+				// A declaration with a value replaces any earlier variable of the same name
+				delete(assigned, e.Name)
+			}
+		} else if step.Loop != nil {
+			checkReads(step.Loop.SourceArray, assigned, scope)
+			checkReadsInExpression(step.Loop.Expression, assigned, scope)
+			// The body may not run at all so nothing it assigns counts afterwards
+			checkFlowSteps(step.Loop.Execution.Steps, assigned.copy(), step.Loop.Execution.Ast)
+		} else if step.Conditional != nil {
+			end := conditionalChainEnd(steps, i)
+			if checkConditionalChain(steps[i:end], assigned, scope) {
+				return true
+			}
+			i = end - 1
+		}
+	}
+
+	return false
+}
+
+// conditionalChainEnd : Returns the index after the last elif or else that belongs to the if at start
+func conditionalChainEnd(steps []*ExecutionStep, start int) int {
+	end := start + 1
+	for end < len(steps) && steps[end].Conditional != nil && (steps[end].Conditional.Kind == "elif" || steps[end].Conditional.Kind == "else") {
+		end++
+		if steps[end-1].Conditional.Kind == "else" {
+			break
+		}
+	}
+
+	return end
+}
+
+// checkConditionalChain : Follows every branch of an if, elif and else chain. Returns true if they all return
+func checkConditionalChain(chain []*ExecutionStep, assigned assignments, scope *ast.Ast) bool {
+	paths := []assignments{}
+	exhaustive := false
+
+	for _, step := range chain {
+		conditional := step.Conditional
+		checkReadsInExpression(conditional.Expression, assigned, scope)
+
+		branch := assigned.copy()
+		if !checkFlowSteps(conditional.Block.Steps, branch, conditional.Block.Ast) {
+			paths = append(paths, branch)
+		}
+
+		// A block is an else without an if before it, so it always runs
+		exhaustive = conditional.Kind == "else" || conditional.Kind == "block"
+	}
+
+	if !exhaustive {
+		paths = append(paths, assigned.copy())
+	} else if len(paths) == 0 {
+		return true
+	}
+
+	for name, value := range join(paths) {
+		assigned[name] = value
+	}
+
+	return false
+}
+
+// variableName : Returns the C name of the variable symbol refers to
+func variableName(symbol string, scope *ast.Ast) string {
+	if variable := findVariable(strings.Split(symbol, ".")[0], scope); variable != nil && variable.Scope != nil {
+		return variable.GetFullPath()
+	}
+
+	return strings.Split(symbol, ".")[0]
+}
+
+func reportUnassigned(used usages, assigned assignments, pos lexer.Position, scope *ast.Ast) {
+	symbols := []string{}
+	for symbol := range used {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		name := variableName(symbol, scope)
+		if isAssigned, ok := assigned[name]; ok && !isAssigned {
+			errors.AddError(errors.NewError(errors.UseBeforeAssignment, pos, "'"+sourceName(name)+"' is used before it is assigned a value", scope))
+		}
+	}
+}
+
+func checkReads(value *tokens.Literal, assigned assignments, scope *ast.Ast) {
+	if value == nil {
+		return
+	}
+
+	used := usages{}
+	used.literal(value)
+	reportUnassigned(used, assigned, value.Pos, scope)
+}
+
+func checkReadsInExpression(expr *tokens.Expression, assigned assignments, scope *ast.Ast) {
+	if expr == nil {
+		return
+	}
+
+	used := usages{}
+	used.expression(expr)
+	reportUnassigned(used, assigned, expr.Pos, scope)
+}
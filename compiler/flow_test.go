@@ -0,0 +1,76 @@
+package compiler_test
+
+import (
+	"testing"
+
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+func TestFlow(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		codes  []string
+	}{
+		{"every branch returns", `func sign(n: int): int {
+  if (n > 0) {
+    return 1
+  } elif (n < 0) {
+    return 0 - 1
+  } else {
+    return 0
+  }
+}
+
+func Main(): int {
+  return sign(n: 2)
+}
+`, []string{}},
+		{"missing return", `func sign(n: int): int {
+  if (n > 0) {
+    return 1
+  }
+}
+
+func Main(): int {
+  return sign(n: 2)
+}
+`, []string{errors.MissingReturn}},
+		{"assigned in every branch", `func Main(): int {
+  n: int
+  if (true) {
+    n = 2
+  } else {
+    n = 3
+  }
+  return n
+}
+`, []string{}},
+		{"never assigned", `func Main(): int {
+  n: int
+  return n
+}
+`, []string{errors.UseBeforeAssignment}},
+		{"assigned in one branch", `func Main(): int {
+  n: int
+  if (true) {
+    n = 2
+  }
+  return n
+}
+`, []string{errors.UseBeforeAssignment}},
+		{"assigned in a loop", `func Main(): int {
+  n: int
+  for i: int of [1, 2] {
+    n = i
+  }
+  return n
+}
+`, []string{errors.UseBeforeAssignment}},
+	}
+
+	for _, test := range tests {
+		compilertest.ExpectCodes(t, test.name, test.source, test.codes, nil)
+	}
+}
@@ -0,0 +1,893 @@
+package compiler
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/evaluate"
+	"github.com/neutrino2211/Gecko/ir"
+	"github.com/neutrino2211/Gecko/tokens"
+	"github.com/neutrino2211/Gecko/utils"
+)
+
+/*
+	Lowering
+
+	Once a package was checked its ExecutionContext is lowered to an ir.Module,
+	the only thing backends see. Lowering is where the tokens left in the steps
+	are flattened, calls are resolved and every value gets a type. Ifs and loops
+	become branches between basic blocks and the variables of a function,
+	temporaries included, are collected in its Locals. A variable that shadows
+	another one of the same function is renamed so every local has a name of its
+	own.
+*/
+
+// sizeType : The type of list and map lengths and of loop counters
+var sizeType = &ir.Type{Kind: ir.Int, Name: "size_t", GeckoName: "usize", Bits: 64}
+
+// lowering : The state of lowering one package
+type lowering struct {
+	c      *Compilation
+	module *ir.Module
+	fn     *ir.Function
+	block  *ir.Block
+	// scopes : The variables of the blocks being lowered by their C name, innermost last
+	scopes []map[string]*ir.Variable
+	// names : How many locals of the current function were declared with each name
+	names   map[string]int
+	params  map[string]*ir.Variable
+	globals map[string]*ir.Variable
+	structs map[string]*ir.Struct
+	lowered map[string]bool
+}
+
+// lowerPackage : Lowers a package that was checked without errors. Problems found on the way are
+// reported like those of any other stage
+func lowerPackage(a *ast.Ast, ctx *ExecutionContext) *ir.Module {
+	l := &lowering{
+		c: compilationOf(a),
+		module: &ir.Module{
+			Name:     a.Name,
+			Preamble: a.CPreliminary,
+		},
+		globals: map[string]*ir.Variable{},
+		structs: map[string]*ir.Struct{},
+		lowered: map[string]bool{},
+	}
+
+	definitions := []*ObjectDefinition{}
+	collectClasses(ctx, &definitions)
+	l.lowerStructs(definitions)
+	l.lowerGlobals(ctx)
+	l.lowerMethods(ctx)
+
+	if main := a.Methods["Main"]; main != nil {
+		l.module.Main = l.module.Function(main.GetFullPath())
+	}
+
+	return l.module
+}
+
+// collectClasses : Finds the classes built anywhere in ctx
+func collectClasses(ctx *ExecutionContext, definitions *[]*ObjectDefinition) {
+	*definitions = append(*definitions, ctx.Classes...)
+
+	for _, mthd := range ctx.Methods {
+		collectClasses(mthd, definitions)
+	}
+
+	for _, step := range ctx.Steps {
+		if step.Conditional != nil {
+			collectClasses(step.Conditional.Block, definitions)
+		} else if step.Loop != nil {
+			collectClasses(&step.Loop.Execution, definitions)
+		}
+	}
+}
+
+func (l *lowering) lowerStructs(definitions []*ObjectDefinition) {
+	// Every struct exists before any field is typed so fields can hold other classes
+	for _, definition := range definitions {
+		if l.structs[definition.Name] != nil {
+			continue
+		}
+
+		s := &ir.Struct{Name: definition.Name, GeckoName: l.c.classes[definition.Name]}
+		l.structs[s.Name] = s
+		l.module.Structs = append(l.module.Structs, s)
+	}
+
+	for _, definition := range definitions {
+		s := l.structs[definition.Name]
+		if len(s.Fields) > 0 {
+			continue
+		}
+
+		names := []string{}
+		for name := range definition.Variables {
+			if name != "__ctype__" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			s.Fields = append(s.Fields, &ir.Field{
+				Name: name,
+				Type: l.typeOf(definition.Variables[name].Type, definition.Scope),
+			})
+		}
+	}
+}
+
+// lowerGlobals : Lowers the variables declared at package level. Their values can't run any
+// statements so anything lowering one adds to a function body is dropped
+func (l *lowering) lowerGlobals(ctx *ExecutionContext) {
+	l.startFunction(&ir.Function{Result: ir.VoidType})
+
+	for _, step := range ctx.Steps {
+		e := step.Expression
+		if e == nil || e.IsAssignement {
+			continue
+		}
+
+		t := l.typeOf(e.Type, ctx.Ast)
+		global := &ir.Variable{
+			Name:  e.Name,
+			Type:  t,
+			Const: e.IsConstant && e.Value != nil && t.Kind != ir.List && t.Kind != ir.Map,
+			Value: l.declarationValue(e, t, ctx.Ast),
+			Pos:   step.Pos,
+		}
+
+		l.globals[global.Name] = global
+		l.module.Globals = append(l.module.Globals, global)
+	}
+}
+
+// lowerMethods : Lowers the methods built anywhere in ctx, the methods a method calls come before it
+func (l *lowering) lowerMethods(ctx *ExecutionContext) {
+	for _, mthd := range ctx.Methods {
+		name := mthd.Ast.GetFullPath()
+		if l.lowered[name] {
+			continue
+		}
+		l.lowered[name] = true
+
+		l.lowerMethods(mthd)
+		l.module.Functions = append(l.module.Functions, l.lowerFunction(mthd))
+	}
+
+	for _, step := range ctx.Steps {
+		if step.Conditional != nil {
+			l.lowerMethods(step.Conditional.Block)
+		} else if step.Loop != nil {
+			l.lowerMethods(&step.Loop.Execution)
+		}
+	}
+}
+
+func (l *lowering) startFunction(fn *ir.Function) {
+	l.fn = fn
+	l.block = fn.NewBlock()
+	l.scopes = []map[string]*ir.Variable{{}}
+	l.names = map[string]int{}
+	l.params = map[string]*ir.Variable{}
+}
+
+func (l *lowering) lowerFunction(ctx *ExecutionContext) *ir.Function {
+	fn := &ir.Function{
+		Name:   ctx.Ast.GetFullPath(),
+		Result: l.typeOf(ctx.ReturnType, ctx.Ast),
+		Pos:    ctx.Pos,
+	}
+	l.startFunction(fn)
+
+	for _, arg := range ctx.Ast.Parent.Methods[ctx.Ast.Name].Arguments {
+		param := &ir.Variable{Name: arg.Name, Type: l.typeOf(arg.Type, ctx.Ast), Pos: arg.Pos}
+		fn.Params = append(fn.Params, param)
+		l.params[arg.Name] = param
+	}
+
+	l.lowerSteps(ctx)
+
+	if fn.Result.Kind == ir.Void {
+		l.terminate(&ir.Return{})
+	} else {
+		// Control flow analysis made sure every path returns
+		l.terminate(&ir.Unreachable{})
+	}
+
+	fn.RemoveUnreachable()
+	return fn
+}
+
+func (l *lowering) lowerSteps(ctx *ExecutionContext) {
+	steps := ctx.Steps
+	scope := ctx.Ast
+
+	hidden := hideShadows(steps, scope)
+	for i := 0; i < len(steps); i++ {
+		step := steps[i]
+
+		if step.Conditional != nil && step.Conditional.Kind == "block" {
+			l.lowerBlock(step.Conditional.Block)
+		} else if step.Conditional != nil {
+			end := conditionalChainEnd(steps, i)
+			l.lowerConditionalChain(steps[i:end], scope)
+			i = end - 1
+		} else if step.MethodCall != nil {
+			l.emit(&ir.Eval{Pos: step.Pos, Value: l.lowerCall(step.MethodCall, scope)})
+		} else if step.Expression != nil {
+			l.lowerExpressionStep(step, scope)
+		} else if step.ReturnStep != nil {
+			l.terminate(&ir.Return{Pos: step.Pos, Value: l.lowerLiteral(step.ReturnStep, scope, l.fn.Result)})
+			// Anything after a return is unreachable, it is lowered into a block nothing jumps to
+			l.block = l.fn.NewBlock()
+		} else if step.Loop != nil && step.Loop.SourceMap != nil {
+			l.lowerMapLoop(step, scope)
+		} else if step.Loop != nil {
+			l.lowerListLoop(step, scope)
+		}
+		hidden.reveal(step, scope)
+	}
+}
+
+// lowerBlock : Lowers the body of an if or a loop, its variables are only visible inside it
+func (l *lowering) lowerBlock(ctx *ExecutionContext) {
+	l.scopes = append(l.scopes, map[string]*ir.Variable{})
+	l.lowerSteps(ctx)
+	l.scopes = l.scopes[:len(l.scopes)-1]
+}
+
+func (l *lowering) emit(instr ir.Instr) {
+	l.block.Instrs = append(l.block.Instrs, instr)
+}
+
+// terminate : Ends the current block with t unless it already ended
+func (l *lowering) terminate(t ir.Terminator) {
+	if l.block.Term == nil {
+		l.block.Term = t
+	}
+}
+
+// jump : Ends the current block with a jump whose target is set later, nil if the block already ended
+func (l *lowering) jump(pos lexer.Position) *ir.Jump {
+	if l.block.Term != nil {
+		return nil
+	}
+
+	j := &ir.Jump{Pos: pos}
+	l.block.Term = j
+	return j
+}
+
+// continueIn : Jumps from the current block to b and continues lowering in b
+func (l *lowering) continueIn(b *ir.Block, pos lexer.Position) {
+	l.terminate(&ir.Jump{Pos: pos, Target: b})
+	l.block = b
+}
+
+// declare : Adds a local called name to the innermost scope, renaming it if the function already has one
+func (l *lowering) declare(name string, t *ir.Type, pos lexer.Position) *ir.Variable {
+	unique := name
+	if n := l.names[name]; n > 0 {
+		unique = name + ast.Separator + strconv.Itoa(n) + "shadow"
+	}
+	l.names[name]++
+
+	v := &ir.Variable{Name: unique, Type: t, Pos: pos}
+	l.fn.Locals = append(l.fn.Locals, v)
+	l.scopes[len(l.scopes)-1][name] = v
+	return v
+}
+
+// lookup : Finds the variable with the C name name
+func (l *lowering) lookup(name string) *ir.Variable {
+	for i := len(l.scopes) - 1; i >= 0; i-- {
+		if v := l.scopes[i][name]; v != nil {
+			return v
+		}
+	}
+
+	if v := l.params[name]; v != nil {
+		return v
+	}
+
+	return l.globals[name]
+}
+
+// resolve : Lowers a C name, class fields after a "." become members
+func (l *lowering) resolve(name string) ir.Value {
+	fields := strings.Split(name, ".")
+
+	var v ir.Value
+	if variable := l.lookup(fields[0]); variable != nil {
+		v = &ir.Ref{Var: variable}
+	} else {
+		v = &ir.Extern{Name: fields[0], T: ir.UnknownType}
+	}
+
+	for _, field := range fields[1:] {
+		v = l.member(v, field)
+	}
+
+	return v
+}
+
+func (l *lowering) member(x ir.Value, name string) ir.Value {
+	t := ir.UnknownType
+	xt := x.Type()
+
+	if xt.Kind == ir.Class && xt.Struct != nil {
+		for _, field := range xt.Struct.Fields {
+			if field.Name == name {
+				t = field.Type
+			}
+		}
+	} else if name == "len" || name == "cap" {
+		t = sizeType
+	}
+
+	return &ir.Member{X: x, Name: name, T: t}
+}
+
+// typeOf : Lowers a gecko type as it is written in scope
+func (l *lowering) typeOf(t *tokens.TypeRef, scope *ast.Ast) *ir.Type {
+	if t == nil {
+		return ir.UnknownType
+	} else if t.Array != nil {
+		return &ir.Type{Kind: ir.List, Name: "gecko_list", Elem: l.typeOf(t.Array, scope)}
+	} else if t.Map != nil {
+		return &ir.Type{Kind: ir.Map, Name: "gecko_map", Key: l.typeOf(t.Map.Key, scope), Elem: l.typeOf(t.Map.Value, scope)}
+	}
+
+	name := GetTypeAsString(t, scope)
+
+	switch {
+	case t.Type == "void" && !t.Pointer:
+		return ir.VoidType
+	case t.Type == "string" && !t.Pointer:
+		return ir.StringType
+	case t.Type == "bool" && !t.Pointer:
+		return &ir.Type{Kind: ir.Bool, Name: name, GeckoName: "bool"}
+	case evaluate.IsNumericType(t):
+		numeric := evaluate.GetNumericType(t)
+		kind := ir.Int
+		if numeric.Float {
+			kind = ir.Float
+		}
+		return &ir.Type{Kind: kind, Name: name, GeckoName: t.Type, Bits: numeric.Bits, Signed: numeric.Signed}
+	case l.structs[name] != nil && !t.Pointer:
+		return &ir.Type{Kind: ir.Class, Name: name, GeckoName: t.Type, Struct: l.structs[name]}
+	}
+
+	return &ir.Type{Kind: ir.Opaque, Name: name, GeckoName: t.Type, Pointer: t.Pointer}
+}
+
+// named : Lowers the built in type called name
+func (l *lowering) named(name string) *ir.Type {
+	return l.typeOf(&tokens.TypeRef{Type: name}, &ast.Ast{})
+}
+
+func (l *lowering) lowerExpressionStep(step *ExecutionStep, scope *ast.Ast) {
+	e := step.Expression
+
+	if e.IsAssignement {
+		dest := l.resolve(e.Name)
+		l.emit(&ir.Assign{Pos: step.Pos, Dest: dest, Value: l.lowerValue(e.Value, scope, dest.Type())})
+		return
+	}
+
+	t := l.typeOf(e.Type, scope)
+	// The value is lowered first, it still sees the variable the declaration may shadow
+	value := l.declarationValue(e, t, scope)
+	variable := l.declare(e.Name, t, step.Pos)
+
+	if e.IsConstant && e.Value != nil && t.Kind != ir.List && t.Kind != ir.Map {
+		variable.Const = true
+		variable.Value = value
+	} else if value != nil {
+		l.emit(&ir.Assign{Pos: step.Pos, Dest: &ir.Ref{Var: variable}, Value: value})
+	}
+}
+
+// declarationValue : Lowers what a variable is declared with, lists and maps declared without a value start out empty
+func (l *lowering) declarationValue(e *Expression, t *ir.Type, scope *ast.Ast) ir.Value {
+	if e.Value != nil {
+		return l.lowerValue(e.Value, scope, t)
+	} else if t.Kind == ir.List {
+		return &ir.ListLit{T: t}
+	} else if t.Kind == ir.Map {
+		return &ir.MapLit{T: t}
+	}
+
+	return nil
+}
+
+// lowerValue : Lowers the value of a declaration or an assignment, want is the type of the variable.
+// Unlike other values these can be list and map literals
+func (l *lowering) lowerValue(value *tokens.Literal, scope *ast.Ast, want *ir.Type) ir.Value {
+	flattenValue(value, scope)
+
+	if value.Brackets {
+		return l.listLiteral(value, scope, want)
+	} else if value.Braces && want.Kind == ir.Map {
+		return l.mapLiteral(value, scope, want)
+	}
+
+	return l.lowerLiteral(value, scope, want)
+}
+
+func (l *lowering) listLiteral(value *tokens.Literal, scope *ast.Ast, want *ir.Type) *ir.ListLit {
+	t := want
+	if t == nil || t.Kind != ir.List {
+		t = l.typeOf(evaluate.InferType(value, scope), scope)
+		if t.Kind != ir.List {
+			t = &ir.Type{Kind: ir.List, Name: "gecko_list", Elem: ir.UnknownType}
+		}
+	}
+
+	list := &ir.ListLit{T: t}
+	for _, item := range value.Array {
+		list.Items = append(list.Items, l.lowerLiteral(item, scope, t.Elem))
+	}
+
+	return list
+}
+
+func (l *lowering) mapLiteral(value *tokens.Literal, scope *ast.Ast, t *ir.Type) *ir.MapLit {
+	m := &ir.MapLit{T: t}
+
+	for _, entry := range value.Object {
+		// The type checker made sure string keys are identifiers and other keys numbers
+		if t.Key.Kind == ir.String {
+			m.Keys = append(m.Keys, ir.StringConst(entry.Key))
+		} else {
+			m.Keys = append(m.Keys, l.lowerLiteral(&tokens.Literal{Number: entry.Number}, scope, t.Key))
+		}
+
+		flattenValue(entry.Value, scope)
+		m.Values = append(m.Values, l.lowerLiteral(entry.Value, scope, t.Elem))
+	}
+
+	return m
+}
+
+// materialise : Stores a list or map literal in a temporary so it can be used like any other value
+func (l *lowering) materialise(value ir.Value, suffix string, scope *ast.Ast, pos lexer.Position) ir.Value {
+	tmp := l.declare(l.c.temporary(scope)+suffix, value.Type(), pos)
+	l.emit(&ir.Assign{Pos: pos, Dest: &ir.Ref{Var: tmp}, Value: value})
+	return &ir.Ref{Var: tmp}
+}
+
+// lowerLiteral : Lowers a literal, want is the type it is used as or nil if that isn't known
+func (l *lowering) lowerLiteral(v *tokens.Literal, scope *ast.Ast, want *ir.Type) ir.Value {
+	if v.Brackets {
+		return l.materialise(l.listLiteral(v, scope, want), "list", scope, v.Pos)
+	} else if v.Braces && want != nil && want.Kind == ir.Map {
+		return l.materialise(l.mapLiteral(v, scope, want), "map", scope, v.Pos)
+	} else if v.Braces {
+		t := want
+		if t == nil {
+			t = ir.UnknownType
+		}
+
+		s := &ir.StructLit{T: t}
+		for _, o := range v.Object {
+			flattenValue(o.Value, scope)
+			s.Fields = append(s.Fields, o.Key)
+			s.Values = append(s.Values, l.lowerLiteral(o.Value, scope, l.member(s, o.Key).Type()))
+		}
+		return s
+	} else if v.ArrayIndex != nil {
+		errors.AddError(errors.NewError(errors.UnsupportedFeature, v.Pos, "indexing is not supported yet, loop over the items with for-of instead", scope))
+		return &ir.Null{T: ir.UnknownType}
+	} else if v.Nil != nil {
+		if want == nil {
+			want = ir.UnknownType
+		}
+		return &ir.Null{T: want}
+	} else if len(v.Bool) > 0 {
+		return ir.BoolConst(v.Bool == "true")
+	} else if len(v.Number) > 0 {
+		return l.numberConst(v.Number)
+	} else if len(v.String) > 0 {
+		return l.stringValue(v.String, v.Pos, scope)
+	} else if len(v.Char) > 0 {
+		return l.charConst(v.Char)
+	} else if v.Expression != nil {
+		return l.lowerExpression(v.Expression, scope)
+	} else if v.FuncCall != nil {
+		return l.lowerCall(buildMethodCallStep(v.FuncCall, scope), scope)
+	}
+
+	// Flattened symbols are C names already
+	return l.resolve(v.Symbol)
+}
+
+func (l *lowering) numberConst(number string) *ir.Const {
+	number = strings.ReplaceAll(number, "_", "")
+
+	if strings.Contains(number, ".") && !strings.HasPrefix(number, "0x") {
+		f, _ := strconv.ParseFloat(number, 64)
+		return &ir.Const{T: l.named("float"), Float: f}
+	}
+
+	if n, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return ir.IntConst(ir.IntType, n)
+	}
+
+	// Too large for an int64, it can only be meant as a u64
+	n, _ := strconv.ParseUint(number, 0, 64)
+	return ir.IntConst(l.named("u64"), int64(n))
+}
+
+func (l *lowering) charConst(char string) *ir.Const {
+	// Invalid characters were reported when the expression was evaluated
+	c, _ := utils.ParseChar(char)
+	return ir.IntConst(l.named("char"), int64(c))
+}
+
+func (l *lowering) lowerExpression(e *tokens.Expression, scope *ast.Ast) ir.Value {
+	updateMethodAst(scope)
+	scope.MergeWithParents()
+	return l.lowerEquality(e.Equality, scope)
+}
+
+/*
+	The expression grammar is right recursive so "a - b - c" is parsed as
+	"a - (b - c)". The chains of operators are lowered from the left so the
+	operators keep the usual left to right associativity.
+*/
+
+func (l *lowering) lowerEquality(eq *tokens.Equality, scope *ast.Ast) ir.Value {
+	r := l.lowerComparison(eq.Comparison, scope)
+
+	for ; len(eq.Op) > 0; eq = eq.Next {
+		r = &ir.Binary{Op: eq.Op, X: r, Y: l.lowerComparison(eq.Next.Comparison, scope), T: ir.BoolType}
+	}
+
+	return r
+}
+
+func (l *lowering) lowerComparison(cmp *tokens.Comparison, scope *ast.Ast) ir.Value {
+	r := l.lowerAddition(cmp.Addition, scope)
+
+	for ; len(cmp.Op) > 0; cmp = cmp.Next {
+		r = &ir.Binary{Op: cmp.Op, X: r, Y: l.lowerAddition(cmp.Next.Addition, scope), T: ir.BoolType}
+	}
+
+	return r
+}
+
+// arithmeticType : The type of an arithmetic operator on x and y, floats win over integers
+func arithmeticType(x ir.Value, y ir.Value) *ir.Type {
+	if x.Type() == ir.UnknownType || y.Type().Kind == ir.Float {
+		return y.Type()
+	}
+
+	return x.Type()
+}
+
+// toString : Formats a value that is concatenated to a string
+func toString(v ir.Value) ir.Value {
+	if v.Type().Kind == ir.String {
+		return v
+	}
+
+	return &ir.Format{Parts: []string{"", ""}, Args: []ir.Value{v}}
+}
+
+func (l *lowering) lowerAddition(add *tokens.Addition, scope *ast.Ast) ir.Value {
+	r := l.lowerMultiplication(add.Multiplication, scope)
+
+	for ; len(add.Op) > 0; add = add.Next {
+		right := l.lowerMultiplication(add.Next.Multiplication, scope)
+
+		if add.Op == "+" && (r.Type().Kind == ir.String || right.Type().Kind == ir.String) {
+			r = &ir.Binary{Op: "+", X: toString(r), Y: toString(right), T: ir.StringType}
+		} else {
+			r = &ir.Binary{Op: add.Op, X: r, Y: right, T: arithmeticType(r, right)}
+		}
+	}
+
+	return r
+}
+
+func (l *lowering) lowerMultiplication(mult *tokens.Multiplication, scope *ast.Ast) ir.Value {
+	r := l.lowerUnary(mult.Unary, scope)
+
+	for ; len(mult.Op) > 0; mult = mult.Next {
+		right := l.lowerUnary(mult.Next.Unary, scope)
+		r = &ir.Binary{Op: mult.Op, X: r, Y: right, T: arithmeticType(r, right)}
+	}
+
+	return r
+}
+
+func (l *lowering) lowerUnary(un *tokens.Unary, scope *ast.Ast) ir.Value {
+	if number, ok := evaluate.NegatedNumber(un); ok {
+		if _, err := strconv.ParseInt(strings.ReplaceAll(number, "_", ""), 0, 64); err == nil {
+			return l.numberConst(number)
+		}
+	}
+
+	if un.Primary == nil {
+		x := l.lowerUnary(un.Unary, scope)
+		t := x.Type()
+		if un.Op == "!" {
+			t = ir.BoolType
+		}
+		return &ir.Unary{Op: un.Op, X: x, T: t}
+	}
+
+	if un.Cast != nil {
+		return &ir.Cast{X: l.lowerPrimary(un.Primary, scope), T: l.typeOf(un.Cast, scope)}
+	}
+
+	return l.lowerPrimary(un.Primary, scope)
+}
+
+func (l *lowering) lowerPrimary(p *tokens.Primary, scope *ast.Ast) ir.Value {
+	if p.FuncCall != nil {
+		return l.lowerCall(buildMethodCallStep(p.FuncCall, scope), scope)
+	} else if len(p.Bool) > 0 {
+		return ir.BoolConst(p.Bool == "true")
+	} else if p.Nil != nil {
+		return &ir.Null{T: ir.UnknownType}
+	} else if len(p.String) > 0 {
+		return l.stringValue(p.String, p.Pos, scope)
+	} else if len(p.Char) > 0 {
+		return l.charConst(p.Char)
+	} else if len(p.Number) > 0 {
+		return l.numberConst(p.Number)
+	} else if p.SubExpression != nil {
+		return l.lowerEquality(p.SubExpression.Equality, scope)
+	}
+
+	v := l.resolve(symbolName(p.Symbol, scope))
+	if extern, ok := v.(*ir.Extern); ok {
+		extern.T = l.typeOf(evaluate.InferType(p, scope), scope)
+	}
+
+	return v
+}
+
+// symbolName : Resolves a gecko symbol to the C name of its variable
+func symbolName(symbol string, scope *ast.Ast) string {
+	variable := utils.ResolveVariable(scope, symbol)
+
+	if variable == nil {
+		// Unknown symbols have already been reported while evaluating the expression
+		return symbol
+	} else if strings.Contains(symbol, ".") {
+		return strings.Replace(symbol, strings.Split(symbol, ".")[0], variable.GetFullPath(), 1)
+	}
+
+	return variable.GetFullPath()
+}
+
+func (l *lowering) lowerCall(m *MethodCall, scope *ast.Ast) ir.Value {
+	args := *m.Arguments
+
+	if m.Builtin != "" {
+		return l.lowerBuiltinCall(m, scope)
+	}
+
+	passed := []*tokens.Literal{}
+	for _, name := range m.ArgumentOrder {
+		arg := args[name]
+		if arg == nil {
+			arg = args[""]
+		}
+		passed = append(passed, arg)
+	}
+
+	// Arguments are computed in the order they were written, not in the order of the parameters
+	order := make([]int, len(passed))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return passed[order[i]].Pos.Offset < passed[order[j]].Pos.Offset
+	})
+
+	values := make([]ir.Value, len(passed))
+	effects := false
+	for _, i := range order {
+		name := m.ArgumentOrder[i]
+		values[i] = l.lowerLiteral(passed[i], scope, l.typeOf(m.ArgumentTypes[name], scope))
+		effects = effects || ir.HasEffects(values[i])
+	}
+
+	// C doesn't say in which order the arguments of a call are computed, so when one of them
+	// does more than compute a value they are all stored in temporaries first
+	if effects && len(values) > 1 {
+		for _, i := range order {
+			if _, ok := values[i].(*ir.Const); !ok {
+				values[i] = l.materialise(values[i], "arg", scope, passed[i].Pos)
+			}
+		}
+	}
+
+	for i, name := range m.ArgumentOrder {
+		// C functions expect plain C strings
+		if m.External && (len(passed[i].String) > 0 || evaluate.IsStringType(m.ArgumentTypes[name])) {
+			values[i] = &ir.CString{X: values[i]}
+		}
+	}
+
+	t := ir.VoidType
+	if m.ReturnType != nil {
+		t = l.typeOf(m.ReturnType, scope)
+	}
+
+	return &ir.Call{Func: m.MethodFullName, Args: values, T: t, External: m.External}
+}
+
+// lowerBuiltinCall : Lowers a call to a built in method of a list or map, the receiver is the first argument
+func (l *lowering) lowerBuiltinCall(m *MethodCall, scope *ast.Ast) ir.Value {
+	args := *m.Arguments
+	receiver := l.lowerLiteral(args[m.ArgumentOrder[0]], scope, nil)
+	rt := receiver.Type()
+
+	values := []ir.Value{receiver}
+	for i, name := range m.ArgumentOrder[1:] {
+		want := rt.Elem
+		if rt.Kind == ir.Map && i == 0 {
+			want = rt.Key
+		}
+		values = append(values, l.lowerLiteral(args[name], scope, want))
+	}
+
+	t := ir.VoidType
+	switch m.Builtin {
+	case ir.ListPop, ir.MapGet:
+		t = rt.Elem
+	case ir.MapHas, ir.MapDelete:
+		t = ir.BoolType
+	}
+
+	if t == nil {
+		t = ir.UnknownType
+	}
+
+	return &ir.Intrinsic{Op: m.Builtin, Args: values, T: t}
+}
+
+// lowerConditionalChain : Lowers an if and the elifs and else that follow it. Every condition
+// branches to its body or to the next condition and every body jumps to the end of the chain
+func (l *lowering) lowerConditionalChain(chain []*ExecutionStep, scope *ast.Ast) {
+	ends := []*ir.Jump{}
+
+	for _, step := range chain {
+		conditional := step.Conditional
+
+		if conditional.Kind == "else" {
+			l.lowerBlock(conditional.Block)
+			break
+		}
+
+		branch := &ir.Branch{Pos: step.Pos, Cond: l.lowerExpression(conditional.Expression, scope)}
+		l.terminate(branch)
+
+		branch.Then = l.fn.NewBlock()
+		l.block = branch.Then
+		l.lowerBlock(conditional.Block)
+		if j := l.jump(step.Pos); j != nil {
+			ends = append(ends, j)
+		}
+
+		branch.Else = l.fn.NewBlock()
+		l.block = branch.Else
+	}
+
+	if j := l.jump(chain[0].Pos); j != nil {
+		ends = append(ends, j)
+	}
+
+	end := l.fn.NewBlock()
+	for _, j := range ends {
+		j.Target = end
+	}
+	l.block = end
+}
+
+// lowerListLoop : Lowers a for-of loop, a counter walks the items of the list
+func (l *lowering) lowerListLoop(step *ExecutionStep, scope *ast.Ast) {
+	loop := step.Loop
+	pos := step.Pos
+	prefix := l.c.temporary(scope)
+	itemType := l.typeOf(loop.TargetVariable.Type, scope)
+	listType := &ir.Type{Kind: ir.List, Name: "gecko_list", Elem: itemType}
+
+	list := l.declare(prefix+"list", listType, pos)
+	counter := l.declare(prefix+"counter", sizeType, pos)
+
+	var source ir.Value
+	if loop.SourceArray.Brackets {
+		source = l.listLiteral(loop.SourceArray, scope, listType)
+	} else {
+		source = l.lowerLiteral(loop.SourceArray, scope, listType)
+	}
+	l.emit(&ir.Assign{Pos: pos, Dest: &ir.Ref{Var: list}, Value: source})
+	l.emit(&ir.Assign{Pos: pos, Dest: &ir.Ref{Var: counter}, Value: ir.IntConst(sizeType, 0)})
+
+	cond := l.fn.NewBlock()
+	l.continueIn(cond, pos)
+	branch := &ir.Branch{
+		Pos:  pos,
+		Cond: &ir.Binary{Op: "<", X: &ir.Ref{Var: counter}, Y: l.member(&ir.Ref{Var: list}, "len"), T: ir.BoolType},
+		Then: l.fn.NewBlock(),
+	}
+	l.terminate(branch)
+
+	l.block = branch.Then
+	l.scopes = append(l.scopes, map[string]*ir.Variable{})
+	item := l.declare(loop.TargetVariable.GetFullPath(), itemType, loop.TargetVariable.Pos)
+	l.emit(&ir.Assign{Pos: pos, Dest: &ir.Ref{Var: item}, Value: &ir.Intrinsic{Op: ir.ListAt, Args: []ir.Value{&ir.Ref{Var: list}, &ir.Ref{Var: counter}}, T: itemType}})
+	l.lowerSteps(&loop.Execution)
+	l.scopes = l.scopes[:len(l.scopes)-1]
+	l.emit(&ir.Assign{Pos: pos, Dest: &ir.Ref{Var: counter}, Value: &ir.Binary{Op: "+", X: &ir.Ref{Var: counter}, Y: ir.IntConst(sizeType, 1), T: sizeType}})
+	l.terminate(&ir.Jump{Pos: pos, Target: cond})
+
+	branch.Else = l.fn.NewBlock()
+	l.block = branch.Else
+
+	// Lists made for array literals belong to the loop
+	if loop.SourceArray.Brackets {
+		l.emit(&ir.Eval{Pos: pos, Value: &ir.Intrinsic{Op: ir.ListFree, Args: []ir.Value{&ir.Ref{Var: list}}, T: ir.VoidType}})
+	}
+}
+
+// lowerMapLoop : Lowers a for-in loop, a counter walks the slots of the map and the body runs for the used ones
+func (l *lowering) lowerMapLoop(step *ExecutionStep, scope *ast.Ast) {
+	loop := step.Loop
+	pos := step.Pos
+	prefix := l.c.temporary(scope)
+	keyType := l.typeOf(loop.TargetVariable.Type, scope)
+	mapType := l.typeOf(&tokens.TypeRef{Map: loop.SourceMap}, scope)
+
+	m := l.declare(prefix+"map", mapType, pos)
+	counter := l.declare(prefix+"counter", sizeType, pos)
+
+	l.emit(&ir.Assign{Pos: pos, Dest: &ir.Ref{Var: m}, Value: l.lowerLiteral(loop.SourceArray, scope, mapType)})
+	l.emit(&ir.Assign{Pos: pos, Dest: &ir.Ref{Var: counter}, Value: ir.IntConst(sizeType, 0)})
+
+	cond := l.fn.NewBlock()
+	l.continueIn(cond, pos)
+	branch := &ir.Branch{
+		Pos:  pos,
+		Cond: &ir.Binary{Op: "<", X: &ir.Ref{Var: counter}, Y: l.member(&ir.Ref{Var: m}, "cap"), T: ir.BoolType},
+		Then: l.fn.NewBlock(),
+	}
+	l.terminate(branch)
+
+	l.block = branch.Then
+	used := &ir.Branch{
+		Pos:  pos,
+		Cond: &ir.Intrinsic{Op: ir.MapSlotUsed, Args: []ir.Value{&ir.Ref{Var: m}, &ir.Ref{Var: counter}}, T: ir.BoolType},
+		Then: l.fn.NewBlock(),
+	}
+	l.terminate(used)
+
+	l.block = used.Then
+	l.scopes = append(l.scopes, map[string]*ir.Variable{})
+	key := l.declare(loop.TargetVariable.GetFullPath(), keyType, loop.TargetVariable.Pos)
+	l.emit(&ir.Assign{Pos: pos, Dest: &ir.Ref{Var: key}, Value: &ir.Intrinsic{Op: ir.MapSlotKey, Args: []ir.Value{&ir.Ref{Var: m}, &ir.Ref{Var: counter}}, T: keyType}})
+	l.lowerSteps(&loop.Execution)
+	l.scopes = l.scopes[:len(l.scopes)-1]
+
+	used.Else = l.fn.NewBlock()
+	l.continueIn(used.Else, pos)
+	l.emit(&ir.Assign{Pos: pos, Dest: &ir.Ref{Var: counter}, Value: &ir.Binary{Op: "+", X: &ir.Ref{Var: counter}, Y: ir.IntConst(sizeType, 1), T: sizeType}})
+	l.terminate(&ir.Jump{Pos: pos, Target: cond})
+
+	branch.Else = l.fn.NewBlock()
+	l.block = branch.Else
+}
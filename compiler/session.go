@@ -0,0 +1,176 @@
+package compiler
+
+import (
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/config"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/ir"
+	"github.com/neutrino2211/Gecko/logger"
+	"github.com/neutrino2211/Gecko/tokens"
+)
+
+/*
+	Compiler sessions
+
+	A Session runs the compiler for programs that embed it. Every stage returns
+	the diagnostics it produced instead of printing them or exiting, so editors
+	and build tools can decide what to do with them. The compile command is a
+	thin wrapper around Session.Build.
+
+	Inside the compiler a stage that can't continue calls abort, after its
+	problems were reported to the session, and the session recovers from it.
+	Sessions don't share any state so several can run at once. The generated C
+	and the runtime go to a temporary directory of the session's own, which is
+	deleted once Build returns.
+*/
+
+// aborted : Unwinds the compiler after the reason was reported to the session
+type aborted struct{}
+
+func abort() {
+	panic(aborted{})
+}
+
+// Session : Compiles gecko code with one build configuration
+type Session struct {
+	Config  *config.BuildConfig
+	Options map[string]string
+
+	diagnostics *errors.Diagnostics
+	// modulesToBuild : The build.json files found next to the sources that were parsed
+	modulesToBuild []string
+	builtModules   []string
+	modulesBuilt   bool
+	// workDir : Where the generated C and the runtime are built, see workDirectory
+	workDir string
+	// runtimeObjects : The object files of the runtime for every compiler the session built with
+	runtimeObjects map[string][]string
+}
+
+// Package : A parsed and checked gecko file, ready to be turned into C
+type Package struct {
+	File    *tokens.File
+	Ast     *ast.Ast
+	Context *ExecutionContext
+	// Module : The package lowered to the IR, nil if checking it found errors
+	Module *ir.Module
+}
+
+// NewSession : Creates a session that builds with cfg. options are the same as the compile command's
+func NewSession(cfg *config.BuildConfig, options map[string]string) *Session {
+	Init()
+
+	if options == nil {
+		options = map[string]string{}
+	}
+
+	return &Session{
+		Config:  cfg,
+		Options: options,
+	}
+}
+
+// finish : Ends a session stage, turning aborts and fatal compiler errors into diagnostics
+func (s *Session) finish(diagnostics *[]*errors.Error) {
+	if r := recover(); r != nil {
+		switch e := r.(type) {
+		case aborted:
+		case *logger.FatalError:
+			errors.AddError(errors.NewError(errors.CompilerFailure, lexer.Position{}, e.Error(), &ast.Ast{Name: "gecko", Compilation: s}))
+		default:
+			panic(r)
+		}
+	}
+
+	*diagnostics = s.Diagnostics().All()
+}
+
+// Diagnostics : Returns the errors and warnings reported since the current stage started
+func (s *Session) Diagnostics() *errors.Diagnostics {
+	if s.diagnostics == nil {
+		s.diagnostics = &errors.Diagnostics{}
+	}
+
+	return s.diagnostics
+}
+
+// Failed : Reports if diagnostics should stop a build, warnings do when the werror option is set
+func (s *Session) Failed(diagnostics []*errors.Error) bool {
+	for _, d := range diagnostics {
+		if !d.Warning || s.Options["werror"] == "true" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FailedByWarnings : Reports if diagnostics stop a build only because the werror option turns warnings into errors
+func (s *Session) FailedByWarnings(diagnostics []*errors.Error) bool {
+	for _, d := range diagnostics {
+		if !d.Warning {
+			return false
+		}
+	}
+
+	return len(diagnostics) > 0 && s.Failed(diagnostics)
+}
+
+// Parse : Parses filename and the packages it imports
+func (s *Session) Parse(filename string) (file *tokens.File, diagnostics []*errors.Error) {
+	s.diagnostics = &errors.Diagnostics{}
+	defer s.finish(&diagnostics)
+
+	file = s.parseFile(filename)
+	s.resolveImports(file)
+	return
+}
+
+// Check : Compiles file and runs every analysis on it
+func (s *Session) Check(file *tokens.File) (pkg *Package, diagnostics []*errors.Error) {
+	s.diagnostics = &errors.Diagnostics{}
+	defer s.finish(&diagnostics)
+
+	a, ctx := s.check(file, s.Config.Type)
+	pkg = &Package{
+		File:    file,
+		Ast:     a,
+		Context: ctx,
+	}
+
+	if !s.Diagnostics().HaveErrors() {
+		module := lowerPackage(a, ctx)
+		// Some problems, like calls missing arguments inside expressions, are only found while lowering
+		if !s.Diagnostics().HaveErrors() {
+			ir.Optimise(module, optimisation(s.Options))
+			pkg.Module = module
+		}
+	}
+	return
+}
+
+// GenerateC : Returns the C source for a package that was checked without errors
+func (s *Session) GenerateC(pkg *Package) (code string, diagnostics []*errors.Error) {
+	s.diagnostics = &errors.Diagnostics{}
+	defer s.finish(&diagnostics)
+
+	if pkg.Module == nil {
+		pkg.Module = lowerPackage(pkg.Ast, pkg.Context)
+		ir.Optimise(pkg.Module, optimisation(s.Options))
+	}
+
+	code = generateC(pkg.Module, s.Config.Type)
+	return
+}
+
+// Build : Compiles sources, or the sources in the session's build configuration if there are none,
+// and returns the files that were created
+func (s *Session) Build(sources []string) (outputs []string, diagnostics []*errors.Error) {
+	s.diagnostics = &errors.Diagnostics{}
+	defer s.removeWorkDirectory()
+	defer s.finish(&diagnostics)
+
+	outputs = s.build(sources, s.Config, s.Options, "")
+	return
+}
@@ -0,0 +1,75 @@
+package compiler_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/compiler"
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+	"github.com/neutrino2211/Gecko/config"
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+func TestSessionStages(t *testing.T) {
+	session := compiler.NewSession(compilertest.BuildConfig(), nil)
+	file, diagnostics := session.Parse(compilertest.Write(t, compilertest.Programs[0].Source))
+	if file == nil || len(diagnostics) > 0 {
+		t.Fatalf("Parse: %v", diagnostics)
+	}
+
+	pkg, diagnostics := session.Check(file)
+	if pkg == nil || pkg.Module == nil || session.Failed(diagnostics) {
+		t.Fatalf("Check: %v", diagnostics)
+	}
+
+	code, diagnostics := session.GenerateC(pkg)
+	if len(code) == 0 || len(diagnostics) > 0 {
+		t.Errorf("GenerateC returned %d bytes of C and %v", len(code), diagnostics)
+	}
+}
+
+func TestSessionFailures(t *testing.T) {
+	unresolved := compiler.NewSession(compilertest.BuildConfig(), nil)
+	if _, diagnostics := unresolved.Parse(compilertest.Write(t, "package Main\n\nimport nowhere\n\nfunc Main() {\n}\n")); !reflect.DeepEqual(compilertest.Codes(diagnostics), []string{errors.UnresolvedImport}) {
+		t.Errorf("unresolved import: %v", diagnostics)
+	}
+
+	program := compilertest.Write(t, "package Main\n\nfunc Main(): int {\n  return 0\n}\n")
+	library := compilertest.Write(t, "package Main\n\nfunc helper(): int {\n  return 1\n}\n")
+
+	tests := []struct {
+		name   string
+		source string
+		change func(cfg *config.BuildConfig)
+		codes  []string
+	}{
+		{"missing Main", library, func(cfg *config.BuildConfig) {}, []string{errors.MissingMain}},
+		{"missing build config", program, func(cfg *config.BuildConfig) { cfg.Config = "missing/build.json" }, []string{errors.InvalidBuildConfig}},
+		{"other platform", program, func(cfg *config.BuildConfig) { cfg.Platform = "plan9" }, []string{errors.InvalidBuildConfig}},
+		{"missing C compiler", program, func(cfg *config.BuildConfig) { cfg.Compiler = "gecko-missing-cc" }, []string{errors.ToolchainFailure}},
+	}
+
+	for _, test := range tests {
+		cfg := compilertest.BuildConfig()
+		test.change(cfg)
+
+		_, diagnostics := compiler.NewSession(cfg, nil).Build([]string{test.source})
+		if codes := compilertest.Codes(diagnostics); !reflect.DeepEqual(codes, test.codes) {
+			t.Errorf("%s: got %v, want %v\n%v", test.name, codes, test.codes, diagnostics)
+		}
+	}
+}
+
+func TestConcurrentSessions(t *testing.T) {
+	for _, program := range compilertest.Programs {
+		program := program
+		t.Run(program.Name, func(t *testing.T) {
+			t.Parallel()
+
+			executable := compilertest.Build(t, program.Source, nil)
+			if out, status := compilertest.Run(t, executable); out != program.Output || status != program.Status {
+				t.Errorf("prints\n%s\nand exits with %d, want\n%s\nand %d", out, status, program.Output, program.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package compiler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/ir"
+	"github.com/neutrino2211/Gecko/tokens"
+)
+
+var (
+	// Used to parse the expressions inside "${...}" of interpolated strings
+	expressionParser = participle.MustBuild(&tokens.Expression{},
+		participle.Lexer(graphQLLexer),
+		participle.Elide("Comment", "Whitespace"),
+	)
+)
+
+// splitInterpolation : Splits a string literal (quotes included) at every "${...}".
+// There is always one more part than there are expressions, the i-th expression
+// goes between parts[i] and parts[i+1]. "\$" escapes a dollar sign.
+func splitInterpolation(literal string) ([]string, []string) {
+	parts := []string{}
+	expressions := []string{}
+	s := literal[1 : len(literal)-1]
+	current := ""
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if s[i+1] == '$' {
+				current += "$"
+			} else {
+				current += s[i : i+2]
+			}
+			i++
+		} else if s[i] == '$' && i+1 < len(s) && s[i+1] == '{' {
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				current += s[i:]
+				break
+			}
+			parts = append(parts, current)
+			expressions = append(expressions, s[i+2:i+end])
+			current = ""
+			i += end
+		} else {
+			current += string(s[i])
+		}
+	}
+
+	return append(parts, current), expressions
+}
+
+var stringEscapes = map[byte]byte{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'a':  '\a',
+	'b':  '\b',
+	'f':  '\f',
+	'v':  '\v',
+	'\\': '\\',
+	'\'': '\'',
+	'"':  '"',
+	'?':  '?',
+}
+
+// unescape : Replaces the escape sequences of a string literal with the bytes they stand for.
+// Octal escapes have up to three digits and hex escapes up to two, like in C
+func unescape(s string) string {
+	r := []byte{}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			r = append(r, s[i])
+			continue
+		}
+
+		c := s[i+1]
+		if value, ok := stringEscapes[c]; ok {
+			r = append(r, value)
+			i++
+		} else if c == 'x' {
+			end := i + 2
+			for end < len(s) && end < i+4 && strings.IndexByte("0123456789abcdefABCDEF", s[end]) >= 0 {
+				end++
+			}
+			n, _ := strconv.ParseUint(s[i+2:end], 16, 8)
+			r = append(r, byte(n))
+			i = end - 1
+		} else if c >= '0' && c <= '7' {
+			end := i + 1
+			for end < len(s) && end < i+4 && s[end] >= '0' && s[end] <= '7' {
+				end++
+			}
+			n, _ := strconv.ParseUint(s[i+1:end], 8, 8)
+			r = append(r, byte(n))
+			i = end - 1
+		} else {
+			r = append(r, s[i])
+		}
+	}
+
+	return string(r)
+}
+
+// stringValue : Lowers a string literal at pos, interpolated values are formatted into it
+func (l *lowering) stringValue(literal string, pos lexer.Position, scope *ast.Ast) ir.Value {
+	parts, expressions := splitInterpolation(literal)
+
+	if len(expressions) == 0 {
+		return ir.StringConst(unescape(strings.Join(parts, "")))
+	}
+
+	format := &ir.Format{}
+
+	for i, source := range expressions {
+		expr := &tokens.Expression{}
+		err := expressionParser.ParseString(source, expr)
+		if err != nil {
+			errors.AddError(errors.NewError(errors.InvalidInterpolation, pos, "invalid expression in string interpolation: ${"+source+"}", scope))
+			continue
+		}
+
+		format.Parts = append(format.Parts, unescape(parts[i]))
+		format.Args = append(format.Args, l.lowerExpression(expr, scope))
+	}
+
+	format.Parts = append(format.Parts, unescape(parts[len(parts)-1]))
+
+	return format
+}
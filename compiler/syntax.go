@@ -0,0 +1,127 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/tokens"
+)
+
+/*
+	Syntax error recovery
+
+	When a file fails to parse the entry that contains the error is blanked out
+	and the file is parsed again, so every broken entry is reported in one run
+	instead of one per compile. Entries are found by their first line, which is
+	the only one that starts at the beginning of a line, and blanking keeps every
+	line and column in place so the positions of later errors stay correct.
+*/
+
+// namedReader : Lets the lexer know which file the source it reads came from
+type namedReader struct {
+	*strings.Reader
+	name string
+}
+
+func (n namedReader) Name() string {
+	return n.name
+}
+
+// parseSource : Parses source, reporting every syntax error in it. Returns the parsed
+// file and the number of syntax errors found
+func parseSource(name string, source string, scope *ast.Ast) (*tokens.File, int) {
+	lines := strings.SplitAfter(source, "\n")
+	found := 0
+	var last lexer.Position
+
+	for {
+		file := &tokens.File{}
+		err := parser.Parse(namedReader{strings.NewReader(strings.Join(lines, "")), name}, file)
+		if err == nil {
+			return file, found
+		}
+
+		pos, reason := describeSyntaxError(err, name)
+		if found > 0 && pos == last {
+			// Blanking the entry didn't get the parser past the error, usually a missing "}"
+			return file, found
+		}
+
+		errors.AddError(errors.NewError(errors.SyntaxError, pos, reason, scope))
+		found++
+		last = pos
+
+		start, end := entryBounds(lines, pos.Line-1)
+		if start < 0 {
+			return file, found
+		}
+
+		for i := start; i < end; i++ {
+			lines[i] = blankLine(lines[i])
+		}
+	}
+}
+
+// describeSyntaxError : Returns where err happened and what went wrong for any error the parser or lexer returns
+func describeSyntaxError(err error, name string) (lexer.Position, string) {
+	switch e := err.(type) {
+	case participle.UnexpectedTokenError:
+		found := "unexpected token " + e.Unexpected.Value
+		if e.Unexpected.EOF() {
+			found = "unexpected end of file"
+		}
+
+		if len(e.Expected) > 0 {
+			return e.Unexpected.Pos, found + " expected " + e.Expected
+		}
+
+		return e.Unexpected.Pos, found
+	case participle.Error:
+		return e.Token().Pos, e.Message()
+	}
+
+	return lexer.Position{Filename: name}, err.Error()
+}
+
+func isEntryStart(line string) bool {
+	if len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "package ") {
+		return false
+	}
+
+	return !strings.ContainsAny(line[:1], " \t}")
+}
+
+// entryBounds : Returns the range of lines taken by the entry around line, start is -1 if
+// line isn't part of an entry
+func entryBounds(lines []string, line int) (int, int) {
+	if line < 0 {
+		return -1, -1
+	} else if line >= len(lines) {
+		line = len(lines) - 1
+	}
+
+	start := line
+	for start >= 0 && !isEntryStart(lines[start]) {
+		start--
+	}
+
+	end := line + 1
+	for end < len(lines) && !isEntryStart(lines[end]) {
+		end++
+	}
+
+	return start, end
+}
+
+// blankLine : Replaces everything in line with spaces so it is skipped without moving what follows
+func blankLine(line string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' {
+			return r
+		}
+		return ' '
+	}, line)
+}
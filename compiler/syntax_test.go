@@ -0,0 +1,69 @@
+package compiler_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+func TestSyntaxRecovery(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		lines  []int
+	}{
+		{"one broken method", `func add(a: int, b: int): int {
+  return a +
+}
+
+func Main(): int {
+  return add(a: 1, b: 2)
+}
+`, []int{4}},
+		{"every broken method", `func add(a: int, b: int): int {
+  return a +
+}
+
+func sub(a: int, b: int): int {
+  return a -
+}
+
+func Main(): int {
+  return 0
+}
+`, []int{4, 8}},
+		{"broken statements and declarations", `func Main(): int {
+  n := 1 +
+  return n
+}
+
+class {
+}
+
+func other() {
+  if (true {
+  }
+}
+`, []int{5, 8, 12}},
+	}
+
+	for _, test := range tests {
+		pkg, diagnostics := compilertest.Check(t, "package Main\n\n"+test.source, nil)
+		if pkg != nil {
+			t.Errorf("%s: checked a file with syntax errors", test.name)
+		}
+
+		lines := []int{}
+		for _, d := range diagnostics {
+			if d.Code != errors.SyntaxError {
+				t.Errorf("%s: %v, want only syntax errors", test.name, d)
+			}
+			lines = append(lines, d.Pos.Line)
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("%s: syntax errors on lines %v, want %v", test.name, lines, test.lines)
+		}
+	}
+}
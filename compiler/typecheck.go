@@ -0,0 +1,266 @@
+package compiler
+
+import (
+	"sort"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/evaluate"
+	"github.com/neutrino2211/Gecko/tokens"
+	"github.com/neutrino2211/Gecko/utils"
+)
+
+/*
+	Type checking
+
+	Runs over a finished ExecutionContext before any C is generated so that type
+	mismatches are reported against the gecko source instead of surfacing as C
+	compiler errors on mangled names.
+*/
+
+// TypeCheck : Reports every call argument, return value and assignment in ctx whose type doesn't match
+func TypeCheck(ctx *ExecutionContext) {
+	for _, mthd := range ctx.Methods {
+		TypeCheck(mthd)
+	}
+
+	checkSteps(ctx.Steps, ctx.Ast, ctx.ReturnType)
+}
+
+func checkSteps(steps []*ExecutionStep, scope *ast.Ast, returnType *tokens.TypeRef) {
+	hidden := hideShadows(steps, scope)
+	for _, step := range steps {
+		if step.MethodCall != nil {
+			checkMethodCall(step.MethodCall, scope)
+		} else if step.Expression != nil {
+			checkExpression(step.Expression, scope)
+		} else if step.ReturnStep != nil {
+			checkReturn(step.ReturnStep, returnType, scope)
+		} else if step.Conditional != nil {
+			checkSteps(step.Conditional.Block.Steps, step.Conditional.Block.Ast, returnType)
+		} else if step.Loop != nil {
+			checkLoop(step.Loop, scope)
+			checkSteps(step.Loop.Execution.Steps, step.Loop.Execution.Ast, returnType)
+		}
+		hidden.reveal(step, scope)
+	}
+}
+
+func typeMismatch(pos lexer.Position, from *tokens.TypeRef, to *tokens.TypeRef, context string, scope *ast.Ast) {
+	errors.AddError(errors.NewError(errors.TypeMismatch, pos, "cannot use a value of type "+evaluate.TypeName(from)+" as "+evaluate.TypeName(to)+" in "+context, scope))
+}
+
+// sourceName : Strips the scope path from a mangled variable name
+func sourceName(name string) string {
+	if segments, ok := ast.Demangle(name); ok {
+		return segments[len(segments)-1]
+	}
+
+	return name
+}
+
+func checkMethodCall(call *MethodCall, scope *ast.Ast) {
+	// Built in methods are lowered straight to the runtime and have no declared argument types
+	if call.ArgumentTypes == nil {
+		return
+	}
+
+	args := *call.Arguments
+	names := []string{}
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name != "" && name != "self" && call.ArgumentTypes[name] == nil {
+			errors.AddError(errors.NewError(errors.UnknownArgument, args[name].Pos, call.MethodName+" has no argument named '"+name+"'", scope).WithSuggestion(utils.ClosestMatch(name, call.ArgumentOrder)))
+		}
+	}
+
+	for _, name := range call.ArgumentOrder {
+		value := args[name]
+		if value == nil {
+			value = args[""]
+		}
+		if value == nil {
+			continue
+		}
+
+		checkNestedCalls(value, scope)
+		from := evaluate.InferType(value, scope)
+		if !evaluate.IsAssignable(from, call.ArgumentTypes[name], scope) {
+			typeMismatch(value.Pos, from, call.ArgumentTypes[name], "argument '"+name+"' of "+call.MethodName, scope)
+		} else {
+			evaluate.CheckConversion(value, from, call.ArgumentTypes[name], scope)
+		}
+		checkMapKeys(value, call.ArgumentTypes[name], scope)
+	}
+}
+
+// checkCall : Checks a call that is part of a value. These are only turned into MethodCall
+// steps while code is generated so they are checked from their tokens instead
+func checkCall(call *tokens.FuncCall, scope *ast.Ast) {
+	mthd := findMethod(call.Function, scope)
+	if mthd == nil {
+		return
+	}
+
+	types := map[string]*tokens.TypeRef{}
+	names := []string{}
+	named := map[string]bool{}
+	for _, arg := range call.Arguments {
+		named[arg.Name] = true
+	}
+
+	for _, arg := range mthd.Arguments {
+		types[arg.Name] = arg.Type
+		names = append(names, arg.Name)
+	}
+
+	for _, arg := range call.Arguments {
+		checkNestedCalls(arg.Value, scope)
+		from := evaluate.InferType(arg.Value, scope)
+
+		if arg.Name == "" {
+			// Unnamed values fill every argument that wasn't passed by name
+			for _, param := range mthd.Arguments {
+				if !named[param.Name] && param.Default == nil && !evaluate.IsAssignable(from, param.Type, scope) {
+					typeMismatch(arg.Value.Pos, from, param.Type, "argument '"+param.Name+"' of "+call.Function, scope)
+				} else if !named[param.Name] && param.Default == nil {
+					evaluate.CheckConversion(arg.Value, from, param.Type, scope)
+					checkMapKeys(arg.Value, param.Type, scope)
+				}
+			}
+		} else if types[arg.Name] == nil {
+			errors.AddError(errors.NewError(errors.UnknownArgument, arg.Value.Pos, call.Function+" has no argument named '"+arg.Name+"'", scope).WithSuggestion(utils.ClosestMatch(arg.Name, names)))
+		} else if !evaluate.IsAssignable(from, types[arg.Name], scope) {
+			typeMismatch(arg.Value.Pos, from, types[arg.Name], "argument '"+arg.Name+"' of "+call.Function, scope)
+		} else {
+			evaluate.CheckConversion(arg.Value, from, types[arg.Name], scope)
+			checkMapKeys(arg.Value, types[arg.Name], scope)
+		}
+	}
+}
+
+// checkNestedCalls : Checks the calls made anywhere inside value
+func checkNestedCalls(value interface{}, scope *ast.Ast) {
+	switch n := value.(type) {
+	case *tokens.Literal:
+		if n == nil {
+			return
+		} else if n.FuncCall != nil {
+			checkCall(n.FuncCall, scope)
+		} else if n.Expression != nil {
+			checkNestedCalls(n.Expression.Equality, scope)
+		}
+		for _, item := range n.Array {
+			checkNestedCalls(item, scope)
+		}
+		for _, entry := range n.Object {
+			checkNestedCalls(entry.Value, scope)
+		}
+	case *tokens.Equality:
+		if n != nil {
+			checkNestedCalls(n.Comparison, scope)
+			checkNestedCalls(n.Next, scope)
+		}
+	case *tokens.Comparison:
+		if n != nil {
+			checkNestedCalls(n.Addition, scope)
+			checkNestedCalls(n.Next, scope)
+		}
+	case *tokens.Addition:
+		if n != nil {
+			checkNestedCalls(n.Multiplication, scope)
+			checkNestedCalls(n.Next, scope)
+		}
+	case *tokens.Multiplication:
+		if n != nil {
+			checkNestedCalls(n.Unary, scope)
+			checkNestedCalls(n.Next, scope)
+		}
+	case *tokens.Unary:
+		if n != nil {
+			checkNestedCalls(n.Unary, scope)
+			checkNestedCalls(n.Primary, scope)
+		}
+	case *tokens.Primary:
+		if n == nil {
+			return
+		} else if n.FuncCall != nil {
+			checkCall(n.FuncCall, scope)
+		} else if n.SubExpression != nil {
+			checkNestedCalls(n.SubExpression.Equality, scope)
+		}
+	}
+}
+
+func checkExpression(e *Expression, scope *ast.Ast) {
+	if e.Value == nil {
+		return
+	}
+
+	to := e.Type
+	if e.IsAssignement {
+		to = evaluate.SymbolType(e.Name, scope)
+	}
+
+	checkNestedCalls(e.Value, scope)
+	from := evaluate.InferType(e.Value, scope)
+	if !evaluate.IsAssignable(from, to, scope) {
+		typeMismatch(e.Value.Pos, from, to, "the assignment to "+sourceName(e.Name), scope)
+	} else if e.IsAssignement {
+		// Declarations were checked when their scope was compiled
+		evaluate.CheckConversion(e.Value, from, to, scope)
+	}
+	checkMapKeys(e.Value, to, scope)
+}
+
+// checkMapKeys : Checks the keys of value if it is a literal of a map of type to. Keys written as
+// identifiers are strings, maps with other keys take numbers
+func checkMapKeys(value *tokens.Literal, to *tokens.TypeRef, scope *ast.Ast) {
+	if !value.Braces || to == nil || to.Map == nil {
+		return
+	}
+
+	stringKeys := evaluate.IsStringType(to.Map.Key)
+	for _, entry := range value.Object {
+		if stringKeys && entry.Number != "" {
+			errors.AddError(errors.NewError(errors.TypeMismatch, entry.Pos, "cannot use the number "+entry.Number+" as a key of "+evaluate.TypeName(to)+", its keys are strings", scope))
+		} else if !stringKeys && entry.Number == "" {
+			errors.AddError(errors.NewError(errors.TypeMismatch, entry.Pos, "cannot use '"+entry.Key+"' as a key of "+evaluate.TypeName(to)+", its keys are numbers", scope))
+		}
+	}
+}
+
+func checkReturn(value *tokens.Literal, returnType *tokens.TypeRef, scope *ast.Ast) {
+	checkNestedCalls(value, scope)
+	from := evaluate.InferType(value, scope)
+	if returnType != nil && returnType.Type == "void" && returnType.Array == nil && returnType.Map == nil {
+		if from != nil && from.Type != "void" {
+			errors.AddError(errors.NewError(errors.VoidReturnValue, value.Pos, "cannot return a value of type "+evaluate.TypeName(from)+" from a void method", scope))
+		}
+	} else if !evaluate.IsAssignable(from, returnType, scope) {
+		typeMismatch(value.Pos, from, returnType, "a return statement", scope)
+	}
+	checkMapKeys(value, returnType, scope)
+}
+
+func checkLoop(loop *LoopStep, scope *ast.Ast) {
+	if loop.SourceArray == nil || loop.TargetVariable == nil {
+		return
+	}
+
+	var item *tokens.TypeRef
+	if loop.SourceMap != nil {
+		item = loop.SourceMap.Key
+	} else if source := evaluate.InferType(loop.SourceArray, scope); source != nil && source.Array != nil {
+		item = source.Array
+	}
+
+	if !evaluate.IsAssignable(item, loop.TargetVariable.Type, scope) {
+		typeMismatch(loop.SourceArray.Pos, item, loop.TargetVariable.Type, "the loop variable "+loop.TargetVariable.Name, scope)
+	}
+}
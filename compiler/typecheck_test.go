@@ -0,0 +1,273 @@
+package compiler_test
+
+import (
+	"testing"
+
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+func TestTypeCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		codes  []string
+	}{
+		{"matching types", `external func printf(format: string = "%d\n", val: int)
+
+func square(n: int): int {
+  return n * n
+}
+
+func Main() {
+  printf(val: square(n: 4))
+}
+`, []string{}},
+		{"argument", `func square(n: int): int {
+  return n * n
+}
+
+func Main() {
+  square(n: "4")
+}
+`, []string{errors.TypeMismatch}},
+		{"assignment", `func Main(): int {
+  s: string = "a"
+  n: int = 3
+  n = s
+  return n
+}
+`, []string{errors.TypeMismatch}},
+		{"return value", `func half(n: int): string {
+  return n / 2
+}
+
+func Main() {
+  half(n: 4)
+}
+`, []string{errors.TypeMismatch}},
+		{"unknown argument", `external func printf(format: string, val: int)
+
+func Main() {
+  printf(format: "%d", val: 1)
+  printf(val: 2, value: 1, format: "%d")
+}
+`, []string{errors.UnknownArgument}},
+		{"value from a void method", `func log() {
+  return 1
+}
+
+func Main() {
+  log()
+}
+`, []string{errors.VoidReturnValue}},
+		{"condition", `func Main() {
+  if ("yes") {
+  }
+}
+`, []string{errors.NonBoolCondition}},
+		{"unknown method", `func Main() {
+  prnitf(val: 1)
+}
+`, []string{errors.UnknownMethod}},
+		{"missing argument", `external func printf(format: string, val: int)
+
+func Main() {
+  printf(format: "%d")
+}
+`, []string{errors.MissingArgument}},
+		{"missing argument in an expression", `external func printf(format: string, val: int)
+
+func twice(n: int): int {
+  return n * 2
+}
+
+func Main() {
+  printf(format: "%d", val: twice())
+}
+`, []string{errors.MissingArgument}},
+	}
+
+	for _, test := range tests {
+		compilertest.ExpectCodes(t, test.name, test.source, test.codes, nil)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		codes  []string
+	}{
+		{"limits", `func Main(): int {
+  smallest: i64 = -9223372036854775808
+  largest: u64 = 18446744073709551615
+  b: byte = 255
+  return (smallest + 1) as int + largest as int + b as int
+}
+`, []string{}},
+		{"overflow", `func Main(): int {
+  b: byte = 300
+  return b as int
+}
+`, []string{errors.ConstantOverflow}},
+		{"narrowing", `func Main(): int {
+  n: i64 = 5
+  m: i32 = n
+  return m
+}
+`, []string{errors.ImplicitNarrowing}},
+		{"cast", `func Main(): int {
+  s: string = "1"
+  n: int = s as int
+  return n
+}
+`, []string{errors.InvalidCast}},
+		{"too large for any type", `func Main(): int {
+  n: int = 99999999999999999999999
+  return n
+}
+`, []string{errors.NumberOutOfRange}},
+		{"character", `func Main(): int {
+  c: char = '\q'
+  return c as int
+}
+`, []string{errors.InvalidCharLiteral}},
+	}
+
+	for _, test := range tests {
+		compilertest.ExpectCodes(t, test.name, test.source, test.codes, nil)
+	}
+}
+
+func TestInference(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		codes  []string
+	}{
+		{"inferred types", `func Main(): int {
+  n := 3
+  s := "a" + "b"
+  l := [1, 2]
+  return n + l.len + s.len
+}
+`, []string{}},
+		{"assignment of another type", `func Main(): int {
+  n := 3
+  s := "a"
+  n = s
+  return n
+}
+`, []string{errors.TypeMismatch}},
+		{"empty list", `func Main(): int {
+  xs := []
+  return 0
+}
+`, []string{errors.UnusedVariable, errors.CannotInferType}},
+		{"constructor", `class Box {
+  v: int = 0
+
+  func constructor(self: Box, v: int): Box {
+    self.v = v
+    return self
+  }
+}
+
+func Main(): int {
+  inferred := Box(v: 1)
+  declared: Box = Box(v: 2)
+  return inferred.v + declared.v
+}
+`, []string{}},
+		{"constructor not returning the class", `class Box {
+  v: int = 0
+
+  func constructor(self: Box, v: int): int {
+    return v
+  }
+}
+
+func Main(): int {
+  b := Box(v: 1)
+  return b.v
+}
+`, []string{errors.TypeMismatch}},
+	}
+
+	for _, test := range tests {
+		compilertest.ExpectCodes(t, test.name, test.source, test.codes, nil)
+	}
+}
+
+func TestConstructorRuns(t *testing.T) {
+	executable := compilertest.Build(t, `package Main
+
+class Box {
+  v: int = 1
+
+  func constructor(self: Box, v: int): Box {
+    self.v = self.v + v
+    return self
+  }
+}
+
+func Main(): int {
+  inferred := Box(v: 3)
+  declared: Box = Box(v: 5)
+  return inferred.v * 10 + declared.v
+}
+`, nil)
+
+	if _, status := compilertest.Run(t, executable); status != 46 {
+		t.Errorf("exits with %d, want 46", status)
+	}
+}
+
+func TestBindings(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		codes  []string
+	}{
+		{"const and let", `const limit: int = 3
+
+func Main(): int {
+  const double := limit * 2
+  let n: int = double + 1
+  return n
+}
+`, []string{}},
+		{"without a value", `func Main(): int {
+  let name: string
+  return name.len
+}
+`, []string{errors.UninitialisedBinding, errors.UseBeforeAssignment}},
+		{"const from a call", `func size(): int {
+  return 3
+}
+
+func Main(): int {
+  const n: int = size()
+  return n
+}
+`, []string{errors.NonConstantValue}},
+		{"assignment to let", `func Main(): int {
+  let limit: int = 3
+  limit = 4
+  return limit
+}
+`, []string{errors.AssignToImmutable}},
+		{"assignment to a package const", `const limit: int = 3
+
+func Main(): int {
+  limit = 4
+  return limit
+}
+`, []string{errors.AssignToImmutable}},
+	}
+
+	for _, test := range tests {
+		compilertest.ExpectCodes(t, test.name, test.source, test.codes, nil)
+	}
+}
@@ -63,6 +63,7 @@ type BuildConfig struct {
 	Config       string
 	Flags        []string
 	Compiler     string
+	Debug        bool
 
 	Command commander.Commandable
 }
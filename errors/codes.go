@@ -0,0 +1,293 @@
+package errors
+
+/*
+	Diagnostic codes
+
+	Every error and warning the compiler reports has a code from this registry.
+	Codes are stable: once one is released it always means the same thing, even
+	if the wording of its message changes, so they can be searched for and
+	explained with `gecko explain`. New codes are added to the end of the list.
+*/
+
+// Code : Describes one kind of diagnostic
+type Code struct {
+	ID          string
+	Title       string
+	Explanation string
+	Example     string
+	Fix         string
+}
+
+const (
+	SyntaxError          = "G0001"
+	UnknownSymbol        = "G0002"
+	UnknownMethod        = "G0003"
+	InvalidCharLiteral   = "G0004"
+	InvalidCast          = "G0005"
+	TypeMismatch         = "G0006"
+	UnknownArgument      = "G0007"
+	VoidReturnValue      = "G0008"
+	NonBoolCondition     = "G0009"
+	UninitialisedBinding = "G0010"
+	NonConstantValue     = "G0011"
+	AssignToImmutable    = "G0012"
+	InvalidForIn         = "G0013"
+	CannotInferType      = "G0014"
+	InvalidInterpolation = "G0015"
+	MissingMain          = "G0016"
+	MissingReturn        = "G0017"
+	UseBeforeAssignment  = "G0018"
+	ConstantOverflow     = "G0019"
+	ImplicitNarrowing    = "G0020"
+	UnusedImport         = "G0021"
+	UnusedFunction       = "G0022"
+	UnusedVariable       = "G0023"
+	UnreachableCode      = "G0024"
+	ShadowedVariable     = "G0025"
+	UnresolvedImport     = "G0026"
+	CompilerFailure      = "G0027"
+	NumberOutOfRange     = "G0028"
+	ToolchainFailure     = "G0029"
+	MissingArgument      = "G0030"
+	UnsupportedFeature   = "G0031"
+	InvalidBuildConfig   = "G0032"
+)
+
+// Codes : Every diagnostic code in the order they were added
+var Codes = []*Code{
+	{
+		ID:          SyntaxError,
+		Title:       "syntax error",
+		Explanation: "The source doesn't follow gecko's grammar. Every broken declaration in a file is reported, the rest of the file is still checked.",
+		Example:     "func add(a: int, b: int): int {\n  return a +\n}",
+		Fix:         "Complete or remove the code the error points at.",
+	},
+	{
+		ID:          UnknownSymbol,
+		Title:       "unknown symbol",
+		Explanation: "A name was used that isn't declared in the current scope or any scope around it. Variables declared inside an if or a loop can't be used after it.",
+		Example:     "func Main() {\n  count: int = 1\n  printf(val: cuont)\n}",
+		Fix:         "Check the spelling, the compiler suggests the closest name it knows about, or declare the variable before using it.",
+	},
+	{
+		ID:          UnknownMethod,
+		Title:       "unknown method",
+		Explanation: "A method was called that isn't declared, imported or built into the type it is called on.",
+		Example:     "func Main() {\n  prnitf(val: 1)\n}",
+		Fix:         "Check the spelling or declare the method, use `external func` for methods that are implemented in C.",
+	},
+	{
+		ID:          InvalidCharLiteral,
+		Title:       "invalid character literal",
+		Explanation: "Character literals hold exactly one character, a simple escape like '\\n' or a hex escape like '\\x41'.",
+		Example:     "c: char = 'ab'",
+		Fix:         "Use a string for more than one character or fix the escape.",
+	},
+	{
+		ID:          InvalidCast,
+		Title:       "invalid cast",
+		Explanation: "`as` only converts between the built in numeric types.",
+		Example:     "s: string = \"1\"\nn: int = s as int",
+		Fix:         "Convert the value with a method instead of a cast.",
+	},
+	{
+		ID:          TypeMismatch,
+		Title:       "mismatched types",
+		Explanation: "A value was used where a value of a different type is expected, as an argument, in an assignment or as a return value. Numbers convert to each other implicitly, everything else has to match exactly.",
+		Example:     "func square(n: int): int {\n  return n * n\n}\n\nfunc Main() {\n  square(n: \"4\")\n}",
+		Fix:         "Pass a value of the expected type or change the declared type.",
+	},
+	{
+		ID:          UnknownArgument,
+		Title:       "unknown argument name",
+		Explanation: "A method was called with a named argument that it doesn't declare.",
+		Example:     "external func printf(format: string, val: int)\n\nfunc Main() {\n  printf(format: \"%d\", value: 1)\n}",
+		Fix:         "Use one of the names in the method's declaration.",
+	},
+	{
+		ID:          VoidReturnValue,
+		Title:       "value returned from a void method",
+		Explanation: "A method without a return type returned a value.",
+		Example:     "func log() {\n  return 1\n}",
+		Fix:         "Declare the type the method returns, `func log(): int`, or remove the value.",
+	},
+	{
+		ID:          NonBoolCondition,
+		Title:       "condition is not a bool",
+		Explanation: "The condition of an if or elif has to be a comparison or another value of type bool.",
+		Example:     "if (\"yes\") {\n}",
+		Fix:         "Compare the value with something, `if (count > 0)`.",
+	},
+	{
+		ID:          UninitialisedBinding,
+		Title:       "const or let without a value",
+		Explanation: "const and let bindings can't be assigned to after they are declared, so they need a value straight away.",
+		Example:     "let name: string",
+		Fix:         "Give the binding a value or declare a normal variable.",
+	},
+	{
+		ID:          NonConstantValue,
+		Title:       "const value is not known at compile time",
+		Explanation: "The value of a const is folded into the code that uses it so it can only use literals and other constants.",
+		Example:     "const size: int = readSize()",
+		Fix:         "Declare it with let if its value is only known when the program runs.",
+	},
+	{
+		ID:          AssignToImmutable,
+		Title:       "assignment to a const or let binding",
+		Explanation: "Bindings declared with const or let keep the value they were declared with.",
+		Example:     "let limit: int = 3\nlimit = 4",
+		Fix:         "Declare a normal variable if it needs to change.",
+	},
+	{
+		ID:          InvalidForIn,
+		Title:       "for-in over something that isn't a map",
+		Explanation: "`for key in value` walks the keys of a map. Lists and arrays are walked with `of`.",
+		Example:     "for item: int in [1, 2, 3] {\n}",
+		Fix:         "Use `for item: int of [1, 2, 3]`.",
+	},
+	{
+		ID:          CannotInferType,
+		Title:       "cannot infer type",
+		Explanation: "Variables declared with := take the type of their value, which has to have a type the compiler can work out.",
+		Example:     "items := []",
+		Fix:         "Declare the type explicitly, `items: [int] = []`.",
+	},
+	{
+		ID:          InvalidInterpolation,
+		Title:       "invalid string interpolation",
+		Explanation: "The code inside ${...} in a string has to be a valid expression.",
+		Example:     "puts(\"total: ${count +}\")",
+		Fix:         "Fix the expression inside the braces.",
+	},
+	{
+		ID:          MissingMain,
+		Title:       "missing Main function",
+		Explanation: "Executables start in the Main function of the file that is being built.",
+		Example:     "package Main\n\nfunc start() {\n}",
+		Fix:         "Add `func Main()` or build a library with `--type library`.",
+	},
+	{
+		ID:          MissingReturn,
+		Title:       "missing return",
+		Explanation: "A method with a return type can reach its end without returning a value. C would return whatever happens to be in memory.",
+		Example:     "func sign(n: int): int {\n  if (n > 0) {\n    return 1\n  }\n}",
+		Fix:         "Return a value on every path, an if without an else and a loop can always be skipped.",
+	},
+	{
+		ID:          UseBeforeAssignment,
+		Title:       "use of unassigned variable",
+		Explanation: "A variable declared without a value is read on a path where nothing was assigned to it yet.",
+		Example:     "r: int\nif (n > 0) {\n  r = 1\n}\nreturn r",
+		Fix:         "Give the variable a value when it is declared or assign it on every path.",
+	},
+	{
+		ID:          ConstantOverflow,
+		Title:       "constant overflows its type",
+		Explanation: "A constant doesn't fit in the numeric type it is stored in and is truncated the way C would truncate it.",
+		Example:     "small: u8 = 300",
+		Fix:         "Use a larger type or `as` if the truncation is intended.",
+	},
+	{
+		ID:          ImplicitNarrowing,
+		Title:       "implicit narrowing conversion",
+		Explanation: "A value is stored in a numeric type that can't hold every value of its own type.",
+		Example:     "big: i64 = 1\nsmall: i32 = big",
+		Fix:         "Use `as` to convert it explicitly.",
+	},
+	{
+		ID:          UnusedImport,
+		Title:       "unused import",
+		Explanation: "A package is imported but nothing it declares is used.",
+		Example:     "import strings\n\nfunc Main() {\n}",
+		Fix:         "Remove the import.",
+	},
+	{
+		ID:          UnusedFunction,
+		Title:       "unused private function",
+		Explanation: "Functions whose name starts with _ are private to their file and this one is never called.",
+		Example:     "func _helper() {\n}",
+		Fix:         "Remove the function or call it.",
+	},
+	{
+		ID:          UnusedVariable,
+		Title:       "unused variable",
+		Explanation: "A local variable is declared but never read. Names starting with _ are never reported.",
+		Example:     "func Main() {\n  unused := 4\n}",
+		Fix:         "Remove the variable or start its name with _.",
+	},
+	{
+		ID:          UnreachableCode,
+		Title:       "unreachable code",
+		Explanation: "Code that follows a return in the same block can never run.",
+		Example:     "return 1\nprintf(val: 2)",
+		Fix:         "Remove the code or move it before the return.",
+	},
+	{
+		ID:          ShadowedVariable,
+		Title:       "shadowed variable",
+		Explanation: "A variable declared inside an if or a loop has the same name as one declared outside it, so the outer one can't be used in the block.",
+		Example:     "n: int = 2\nif (n == 2) {\n  n := 5\n}",
+		Fix:         "Rename one of the variables, or assign to the outer one with = instead of declaring a new one.",
+	},
+	{
+		ID:          UnresolvedImport,
+		Title:       "unresolved import",
+		Explanation: "An imported package couldn't be found. Imports are looked for in the working directory, the directory of the importing file, the standard library and the modules directory, either as a .g file or as a directory with an index.g file in it.",
+		Example:     "import utils.strings",
+		Fix:         "Check the spelling of the import, `import utils.strings` looks for utils/strings.g or utils/strings/index.g.",
+	},
+	{
+		ID:          CompilerFailure,
+		Title:       "compilation stopped",
+		Explanation: "The compiler ran into a problem it couldn't continue from and that isn't caused by the code it was given. The message says what went wrong.",
+		Example:     "",
+		Fix:         "This is most likely a bug in gecko, report it with the code that caused it.",
+	},
+	{
+		ID:          NumberOutOfRange,
+		Title:       "number out of range",
+		Explanation: "An integer literal is larger than 18446744073709551615, the largest u64, so no integer type can hold it.",
+		Example:     "big: u64 = 18446744073709551616",
+		Fix:         "Use a smaller number, or write it as a float if losing precision is fine.",
+	},
+	{
+		ID:          ToolchainFailure,
+		Title:       "C toolchain failed",
+		Explanation: "A command gecko runs to turn the generated C into a program failed, or its files couldn't be written. The C compiler prints why before the error, a build command in build.json is run the same way.",
+		Example:     "{\"type\": \"executable\", \"compiler\": \"missing-cc\", \"sources\": [\"main.g\"]}",
+		Fix:         "Check that the compiler in the build configuration is installed, and fix the problems it printed.",
+	},
+	{
+		ID:          MissingArgument,
+		Title:       "missing argument",
+		Explanation: "A call doesn't pass an argument that has no default value. Arguments can be passed by name or, for the ones that weren't named, as one unnamed value.",
+		Example:     "func greet(name: string) {}\ngreet()",
+		Fix:         "Pass the argument, `greet(name: \"gecko\")`, or give it a default value.",
+	},
+	{
+		ID:          UnsupportedFeature,
+		Title:       "unsupported feature",
+		Explanation: "The code is valid gecko but uses something the compiler can't build yet, like indexing with [...], or something the target it builds for doesn't support, like bytecode for a program made of several modules.",
+		Example:     "first := items[0]",
+		Fix:         "Write the code another way, a for-of loop over the items instead of indexing, or build a native executable instead of bytecode.",
+	},
+	{
+		ID:          InvalidBuildConfig,
+		Title:       "invalid build configuration",
+		Explanation: "A build.json, given with --build or found next to an imported module or named by the config field of another build.json, couldn't be read or isn't valid JSON, or it is for another platform or architecture than the one gecko runs on.",
+		Example:     "gecko compile --build missing.json",
+		Fix:         "Check that the file exists and holds a JSON object with the fields of a build configuration, and that its platform and arch match the machine.",
+	},
+}
+
+// Lookup : Returns the code with the given ID or nil if there is none
+func Lookup(id string) *Code {
+	for _, code := range Codes {
+		if code.ID == id {
+			return code
+		}
+	}
+
+	return nil
+}
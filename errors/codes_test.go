@@ -0,0 +1,32 @@
+package errors_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+func TestCodes(t *testing.T) {
+	for i, code := range errors.Codes {
+		if want := fmt.Sprintf("G%04d", i+1); code.ID != want {
+			t.Errorf("code %d is %s, want %s", i, code.ID, want)
+		}
+		if code.Title == "" || code.Explanation == "" || code.Fix == "" {
+			t.Errorf("%s isn't fully described", code.ID)
+		}
+		// Internal errors are the only ones no gecko code causes
+		if (code.Example == "") != (code.ID == errors.CompilerFailure) {
+			t.Errorf("%s has the example %q", code.ID, code.Example)
+		}
+		if errors.Lookup(code.ID) != code {
+			t.Errorf("Lookup(%q) doesn't find it", code.ID)
+		}
+	}
+
+	for _, id := range []string{"", "G0000", "g0001", fmt.Sprintf("G%04d", len(errors.Codes)+1)} {
+		if code := errors.Lookup(id); code != nil {
+			t.Errorf("Lookup(%q) = %s, want nil", id, code.ID)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package errors
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Label : Points at another piece of source that helps explain an error, like where a variable was declared
+type Label struct {
+	Pos     lexer.Position
+	Message string
+}
+
+var (
+	sources      = map[string][]string{}
+	sourcesMutex sync.RWMutex
+)
+
+// CacheSource : Stores the contents of filename so errors in it don't have to read it again
+func CacheSource(filename string, source string) {
+	cacheLines(filename, source)
+}
+
+func cacheLines(filename string, source string) []string {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+
+	sourcesMutex.Lock()
+	sources[filename] = lines
+	sourcesMutex.Unlock()
+
+	return lines
+}
+
+func sourceLine(pos lexer.Position) (string, bool) {
+	sourcesMutex.RLock()
+	lines, ok := sources[pos.Filename]
+	sourcesMutex.RUnlock()
+	if !ok {
+		byts, err := ioutil.ReadFile(pos.Filename)
+		if err != nil {
+			return "", false
+		}
+		lines = cacheLines(pos.Filename, string(byts))
+	}
+
+	if pos.Line < 1 || pos.Line > len(lines) {
+		return "", false
+	}
+
+	return lines[pos.Line-1], true
+}
+
+func isWordChar(c rune) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// spanLength : Returns how many characters the token at column takes up in line
+func spanLength(line []rune, column int) int {
+	start := column - 1
+	if start < 0 || start >= len(line) {
+		return 1
+	}
+
+	end := start + 1
+	if line[start] == '"' || line[start] == '\'' {
+		for end < len(line) && line[end] != line[start] {
+			end++
+		}
+		if end < len(line) {
+			end++
+		}
+	} else if isWordChar(line[start]) {
+		for end < len(line) && isWordChar(line[end]) {
+			end++
+		}
+	}
+
+	return end - start
+}
+
+// underline : Marks the token at pos in its line with marker and follows it with message
+func underline(gutter string, pos lexer.Position, marker string, message string) string {
+	line, ok := sourceLine(pos)
+	if !ok {
+		return ""
+	}
+
+	runes := []rune(line)
+	padding := ""
+	for i := 0; i < pos.Column-1 && i < len(runes); i++ {
+		// Tabs are kept so the marker lines up with the code above it
+		if runes[i] == '\t' {
+			padding += "\t"
+		} else {
+			padding += " "
+		}
+	}
+
+	number := strconv.Itoa(pos.Line)
+	r := gutter[len(number):] + number + " | " + line + "\n"
+	r += gutter + " | " + padding + strings.Repeat(marker, spanLength(runes, pos.Column))
+	if len(message) > 0 {
+		r += " " + message
+	}
+
+	return r + "\n"
+}
+
+// snippet : Renders the source around e with its labels, notes and suggestion
+func (e *Error) snippet() string {
+	if e.Pos.Line == 0 {
+		// Problems that aren't in the source, like a missing build file, have nothing to show
+		return ""
+	}
+
+	width := len(strconv.Itoa(e.Pos.Line))
+	for _, label := range e.Labels {
+		if w := len(strconv.Itoa(label.Pos.Line)); w > width {
+			width = w
+		}
+	}
+	gutter := strings.Repeat(" ", width)
+
+	r := gutter + " |\n" + underline(gutter, e.Pos, "^", "")
+	for _, label := range e.Labels {
+		if label.Pos.Filename != e.Pos.Filename {
+			r += gutter + " ::: " + label.Pos.String() + "\n"
+		}
+		r += underline(gutter, label.Pos, "-", label.Message)
+	}
+
+	for _, note := range e.Notes {
+		r += gutter + " = note: " + note + "\n"
+	}
+
+	if len(e.Suggestion) > 0 {
+		r += gutter + " = help: did you mean `" + e.Suggestion + "`?\n"
+	}
+
+	return r
+}
+
+// WithLabel : Adds a message pointing at another position in the source to e
+func (e *Error) WithLabel(pos lexer.Position, message string) *Error {
+	e.Labels = append(e.Labels, &Label{Pos: pos, Message: message})
+	return e
+}
+
+// WithNote : Adds a note that explains e to it
+func (e *Error) WithNote(note string) *Error {
+	e.Notes = append(e.Notes, note)
+	return e
+}
+
+// WithSuggestion : Suggests suggestion as a replacement for the code e points at, nothing is suggested if it is empty
+func (e *Error) WithSuggestion(suggestion string) *Error {
+	e.Suggestion = suggestion
+	return e
+}
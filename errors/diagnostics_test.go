@@ -0,0 +1,47 @@
+package errors_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+func TestSnippet(t *testing.T) {
+	errors.CacheSource("snippet.g", "func Main() {\n\tcount: int = 1\n\tprintf(val: cuont)\n}\n")
+
+	err := errors.NewError(errors.UnknownSymbol, lexer.Position{Filename: "snippet.g", Line: 3, Column: 14}, "Symbol 'cuont' not found", nil).
+		WithLabel(lexer.Position{Filename: "snippet.g", Line: 2, Column: 2}, "'count' is declared here").
+		WithNote("variables are looked up in the scopes around the code that uses them").
+		WithSuggestion("count")
+
+	want := "Error[G0002]: Symbol 'cuont' not found [snippet.g:3:14]\n" +
+		"  |\n" +
+		"3 | \tprintf(val: cuont)\n" +
+		"  | \t            ^^^^^\n" +
+		"2 | \tcount: int = 1\n" +
+		"  | \t----- 'count' is declared here\n" +
+		"  = note: variables are looked up in the scopes around the code that uses them\n" +
+		"  = help: did you mean `count`?\n"
+	if got := err.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLocations(t *testing.T) {
+	tests := []struct {
+		pos      lexer.Position
+		location string
+	}{
+		{lexer.Position{}, "Error[G0032]: reason\n"},
+		{lexer.Position{Filename: "build.json"}, "Error[G0032]: reason [build.json]\n"},
+		{lexer.Position{Filename: "missing.g", Line: 2, Column: 1}, "Error[G0032]: reason [missing.g:2:1]\n"},
+	}
+
+	for _, test := range tests {
+		if got := errors.NewError(errors.InvalidBuildConfig, test.pos, "reason", nil).String(); !strings.HasPrefix(got, test.location) {
+			t.Errorf("%v: got %q, want it to start with %q", test.pos, got, test.location)
+		}
+	}
+}
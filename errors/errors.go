@@ -2,8 +2,7 @@ package errors
 
 import (
 	"fmt"
-	"io/ioutil"
-	"strings"
+	"sync"
 
 	"github.com/neutrino2211/Gecko/logger"
 
@@ -12,9 +11,14 @@ import (
 )
 
 type Error struct {
-	Pos    lexer.Position
-	Reason string
-	Scope  *ast.Ast
+	Pos        lexer.Position
+	Code       string
+	Reason     string
+	Scope      *ast.Ast
+	Warning    bool
+	Labels     []*Label
+	Notes      []string
+	Suggestion string
 }
 
 var (
@@ -24,6 +28,9 @@ var (
 func computeStackTrace(scope *ast.Ast) string {
 	var s = ""
 	currScope := scope
+	if currScope == nil {
+		return s
+	}
 	for currScope.Parent != nil {
 		s += "\t-> " + currScope.Parent.Name + "." + currScope.Name + "\n"
 		currScope = currScope.Parent
@@ -32,59 +39,122 @@ func computeStackTrace(scope *ast.Ast) string {
 	return s
 }
 
-func (e *Error) getErrorLine() string {
-	byts, err := ioutil.ReadFile(e.Pos.Filename)
-	if err == nil {
-		lines := strings.Split(string(byts), "\n")
-		return lines[e.Pos.Line-1]
-	}
-
-	return ""
-}
-
 func (e *Error) String() string {
-	return fmt.Sprintf("Error: %s [%s]\n\t%s\n\n%s\n", e.Reason, e.Pos.String(), e.getErrorLine(), computeStackTrace(e.Scope))
+	kind := "Error"
+	if e.Warning {
+		kind = "Warning"
+	}
+	if len(e.Code) > 0 {
+		kind += "[" + e.Code + "]"
+	}
+	// Problems that aren't in the source, like a build file that can't be read, are at most in a file
+	location := " [" + e.Pos.String() + "]"
+	if e.Pos.Line == 0 && e.Pos.Filename == "" {
+		location = ""
+	} else if e.Pos.Line == 0 {
+		location = " [" + e.Pos.Filename + "]"
+	}
+	return fmt.Sprintf(kind+": %s%s\n%s\n%s\n", e.Reason, location, e.snippet(), computeStackTrace(e.Scope))
 }
 
 func (e *Error) Error() string {
 	return e.String()
 }
 
-func NewError(pos lexer.Position, reason string, scope *ast.Ast) *Error {
+// NewError : Creates a diagnostic with one of the codes in Codes
+func NewError(code string, pos lexer.Position, reason string, scope *ast.Ast) *Error {
 	return &Error{
+		Code:   code,
 		Pos:    pos,
 		Reason: reason,
 		Scope:  scope,
 	}
 }
 
-var errors = []*Error{}
-var ignoreNext = false
-var errorWasIgnored = false
+// Diagnostics : The errors and warnings reported by one compilation
+type Diagnostics struct {
+	mutex    sync.Mutex
+	errors   []*Error
+	warnings []*Error
+}
 
-func AddError(err *Error) {
-	if ignoreNext && !errorWasIgnored {
-		errorWasIgnored = true
-		return
+// reporter : Implemented by the compilation of a scope so errors in it are reported to the right place
+type reporter interface {
+	Diagnostics() *Diagnostics
+}
+
+// Detached : Receives the errors of scopes that aren't part of a compilation
+var Detached = &Diagnostics{}
+
+func diagnosticsOf(scope *ast.Ast) *Diagnostics {
+	if scope != nil {
+		if r, ok := scope.FindCompilation().(reporter); ok {
+			return r.Diagnostics()
+		}
+	}
+
+	return Detached
+}
+
+// Add : Reports an error. The same error is only reported once
+func (d *Diagnostics) Add(err *Error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, e := range d.errors {
+		if e.Pos == err.Pos && e.Reason == err.Reason {
+			return
+		}
 	}
-	errorWasIgnored = false
-	ignoreNext = false
-	errors = append(errors, err)
+	d.errors = append(d.errors, err)
 }
 
-func GetErrors() []*Error {
-	return errors
+// AddWarning : Reports a problem that does not stop compilation. The same warning is only reported once
+func (d *Diagnostics) AddWarning(warning *Error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, w := range d.warnings {
+		if w.Pos == warning.Pos && w.Reason == warning.Reason {
+			return
+		}
+	}
+	warning.Warning = true
+	d.warnings = append(d.warnings, warning)
 }
 
-func IgnoreNextError() {
-	ignoreNext = true
-	// println("Error:::", ignoreNext)
+func (d *Diagnostics) Warnings() []*Error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return append([]*Error{}, d.warnings...)
 }
 
-func ErrorWasIgnored() bool {
-	return errorWasIgnored
+func (d *Diagnostics) Errors() []*Error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return append([]*Error{}, d.errors...)
+}
+
+// All : Returns every warning followed by every error
+func (d *Diagnostics) All() []*Error {
+	return append(d.Warnings(), d.Errors()...)
+}
+
+func (d *Diagnostics) HaveErrors() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return len(d.errors) != 0
+}
+
+// AddError : Reports err to the compilation its scope belongs to
+func AddError(err *Error) {
+	diagnosticsOf(err.Scope).Add(err)
 }
 
-func HaveErrors() bool {
-	return len(errors) != 0
+// AddWarning : Reports warning to the compilation its scope belongs to
+func AddWarning(warning *Error) {
+	diagnosticsOf(warning.Scope).AddWarning(warning)
 }
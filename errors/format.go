@@ -0,0 +1,161 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/neutrino2211/Gecko/ast"
+)
+
+// Formats : The machine readable formats diagnostics can be written in
+var Formats = []string{"json", "sarif"}
+
+type jsonLabel struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+type jsonDiagnostic struct {
+	File       string       `json:"file"`
+	Line       int          `json:"line"`
+	Column     int          `json:"column"`
+	Severity   string       `json:"severity"`
+	Code       string       `json:"code,omitempty"`
+	Message    string       `json:"message"`
+	Scope      []string     `json:"scope"`
+	Labels     []*jsonLabel `json:"labels,omitempty"`
+	Notes      []string     `json:"notes,omitempty"`
+	Suggestion string       `json:"suggestion,omitempty"`
+}
+
+// Severity : Returns "warning" for warnings and "error" for everything else
+func (e *Error) Severity() string {
+	if e.Warning {
+		return "warning"
+	}
+
+	return "error"
+}
+
+// scopeChain : Returns the names of scope and every scope around it, innermost first
+func scopeChain(scope *ast.Ast) []string {
+	chain := []string{}
+	for s := scope; s != nil; s = s.Parent {
+		chain = append(chain, s.Name)
+	}
+
+	return chain
+}
+
+func toJSON(diagnostics []*Error) interface{} {
+	r := []*jsonDiagnostic{}
+	for _, d := range diagnostics {
+		diagnostic := &jsonDiagnostic{
+			File:       d.Pos.Filename,
+			Line:       d.Pos.Line,
+			Column:     d.Pos.Column,
+			Severity:   d.Severity(),
+			Code:       d.Code,
+			Message:    d.Reason,
+			Scope:      scopeChain(d.Scope),
+			Notes:      d.Notes,
+			Suggestion: d.Suggestion,
+		}
+		for _, label := range d.Labels {
+			diagnostic.Labels = append(diagnostic.Labels, &jsonLabel{label.Pos.Filename, label.Pos.Line, label.Pos.Column, label.Message})
+		}
+		r = append(r, diagnostic)
+	}
+
+	return map[string]interface{}{"diagnostics": r}
+}
+
+func sarifLocation(file string, line int, column int) map[string]interface{} {
+	return map[string]interface{}{
+		"physicalLocation": map[string]interface{}{
+			"artifactLocation": map[string]interface{}{"uri": filepath.ToSlash(file)},
+			"region":           map[string]interface{}{"startLine": line, "startColumn": column},
+		},
+	}
+}
+
+// toSARIF : Builds a SARIF 2.1.0 log, the format code scanning tools use to annotate pull requests
+func toSARIF(diagnostics []*Error) interface{} {
+	results := []interface{}{}
+	rules := []interface{}{}
+	described := map[string]bool{}
+	for _, d := range diagnostics {
+		if code := Lookup(d.Code); code != nil && !described[code.ID] {
+			described[code.ID] = true
+			rules = append(rules, map[string]interface{}{
+				"id":               code.ID,
+				"name":             code.Title,
+				"shortDescription": map[string]interface{}{"text": code.Title},
+				"fullDescription":  map[string]interface{}{"text": code.Explanation},
+			})
+		}
+
+		text := d.Reason
+		for _, note := range d.Notes {
+			text += "\nnote: " + note
+		}
+		if len(d.Suggestion) > 0 {
+			text += "\nhelp: did you mean `" + d.Suggestion + "`?"
+		}
+
+		ruleID := d.Code
+		if len(ruleID) == 0 {
+			ruleID = "gecko"
+		}
+
+		related := []interface{}{}
+		for i, label := range d.Labels {
+			location := sarifLocation(label.Pos.Filename, label.Pos.Line, label.Pos.Column)
+			location["id"] = i
+			location["message"] = map[string]interface{}{"text": label.Message}
+			related = append(related, location)
+		}
+
+		result := map[string]interface{}{
+			"ruleId":    ruleID,
+			"level":     d.Severity(),
+			"message":   map[string]interface{}{"text": text},
+			"locations": []interface{}{sarifLocation(d.Pos.Filename, d.Pos.Line, d.Pos.Column)},
+		}
+		if len(related) > 0 {
+			result["relatedLocations"] = related
+		}
+		results = append(results, result)
+	}
+
+	return map[string]interface{}{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool":    map[string]interface{}{"driver": map[string]interface{}{"name": "gecko", "rules": rules}},
+				"results": results,
+			},
+		},
+	}
+}
+
+// Format : Serialises diagnostics to one of Formats
+func Format(format string, diagnostics []*Error) (string, error) {
+	var document interface{}
+
+	switch format {
+	case "json":
+		document = toJSON(diagnostics)
+	case "sarif":
+		document = toSARIF(diagnostics)
+	default:
+		return "", fmt.Errorf("unknown diagnostics format '%s'", format)
+	}
+
+	byts, err := json.MarshalIndent(document, "", "  ")
+	return string(byts), err
+}
@@ -0,0 +1,113 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+)
+
+func diagnostics() []*errors.Error {
+	scope := &ast.Ast{Name: "Main", Parent: &ast.Ast{Name: "pkg"}}
+	warning := errors.NewError(errors.UnusedVariable, lexer.Position{Filename: "a.g", Line: 4, Column: 3}, "variable 'n' is declared but never used", scope)
+	warning.Warning = true
+
+	return []*errors.Error{
+		warning,
+		errors.NewError(errors.UnknownSymbol, lexer.Position{Filename: "a.g", Line: 5, Column: 10}, "Symbol 'countr' not found", scope).
+			WithLabel(lexer.Position{Filename: "a.g", Line: 3, Column: 3}, "'counter' is declared here").
+			WithSuggestion("counter"),
+	}
+}
+
+func TestJSON(t *testing.T) {
+	out, err := errors.Format("json", diagnostics())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var document struct {
+		Diagnostics []struct {
+			File       string
+			Line       int
+			Column     int
+			Severity   string
+			Code       string
+			Message    string
+			Scope      []string
+			Labels     []map[string]interface{}
+			Suggestion string
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &document); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+
+	if len(document.Diagnostics) != 2 {
+		t.Fatalf("got %d diagnostics, want 2\n%s", len(document.Diagnostics), out)
+	}
+
+	warning, e := document.Diagnostics[0], document.Diagnostics[1]
+	if warning.Severity != "warning" || warning.Code != errors.UnusedVariable || warning.Line != 4 || warning.Column != 3 || warning.File != "a.g" {
+		t.Errorf("the warning is %+v", warning)
+	}
+	if !reflect.DeepEqual(warning.Scope, []string{"Main", "pkg"}) {
+		t.Errorf("the scope is %v, want innermost first", warning.Scope)
+	}
+	if e.Severity != "error" || e.Code != errors.UnknownSymbol || e.Suggestion != "counter" || len(e.Labels) != 1 || e.Labels[0]["line"] != 3.0 {
+		t.Errorf("the error is %+v", e)
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	out, err := errors.Format("sarif", diagnostics())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string
+					}
+				}
+			}
+			Results []struct {
+				RuleID           string
+				Level            string
+				Message          struct{ Text string }
+				RelatedLocations []interface{}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &log); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("not a SARIF 2.1.0 log with one run:\n%s", out)
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != errors.UnusedVariable || run.Tool.Driver.Rules[1].ID != errors.UnknownSymbol {
+		t.Errorf("the rules are %+v", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 2 || run.Results[0].Level != "warning" || run.Results[1].RuleID != errors.UnknownSymbol || len(run.Results[1].RelatedLocations) != 1 {
+		t.Fatalf("the results are %+v", run.Results)
+	}
+	if want := "Symbol 'countr' not found\nhelp: did you mean `counter`?"; run.Results[1].Message.Text != want {
+		t.Errorf("the message is %q, want %q", run.Results[1].Message.Text, want)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := errors.Format("xml", diagnostics()); err == nil {
+		t.Error("formatting as xml succeeded")
+	}
+}
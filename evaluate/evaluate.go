@@ -27,6 +27,8 @@ func flattenValue(value *tokens.Literal, geckoAst *ast.Ast) {
 			value.Number = strconv.Itoa(v.(int))
 		case string:
 			value.String = v.(string)
+		case byte:
+			value.Char = utils.FormatChar(v.(byte))
 		case bool:
 			b := v.(bool)
 			if b {
@@ -56,6 +58,8 @@ func parseLiteral(lit *tokens.Literal) (interface{}, error) {
 		r, err = strconv.Atoi(lit.Number)
 	} else if len(lit.String) > 0 {
 		r = lit.String
+	} else if len(lit.Char) > 0 {
+		r, err = utils.ParseChar(lit.Char)
 	} else if len(lit.Symbol) > 0 {
 		r = lit.Symbol
 	}
@@ -63,39 +67,125 @@ func parseLiteral(lit *tokens.Literal) (interface{}, error) {
 	return r, err
 }
 
+func number(v interface{}) (int, bool) {
+	switch v.(type) {
+	case int:
+		return v.(int), true
+	case byte:
+		return int(v.(byte)), true
+	}
+
+	return 0, false
+}
+
+// numbers : Returns both operands as ints if they are numbers or chars
+func numbers(m interface{}, n interface{}) (int, int, bool) {
+	mNumber, okm := number(m)
+	nNumber, okn := number(n)
+	return mNumber, nNumber, okm && okn
+}
+
+func isChar(v interface{}) bool {
+	_, ok := v.(byte)
+	return ok
+}
+
+func isStringLiteral(s string) bool {
+	return len(s) > 1 && s[0] == '"'
+}
+
 func unary(un *tokens.Unary, scope *ast.Ast) (interface{}, error) {
+	if number, ok := NegatedNumber(un); ok {
+		if n, err := strconv.ParseInt(strings.ReplaceAll(number, "_", ""), 0, 64); err == nil {
+			return int(n), nil
+		}
+	}
+
 	if len(un.Op) > 0 {
+		v, err := unary(un.Unary, scope)
+		switch un.Op {
+		case "-":
+			if n, ok := v.(int); ok {
+				return -n, err
+			}
+		case "+":
+			if n, ok := v.(int); ok {
+				return n, err
+			}
+		case "!":
+			if b, ok := v.(bool); ok {
+				return !b, err
+			}
+		}
 
+		// Operators on runtime values are left for code generation
+		return nil, err
 	}
 
-	var r interface{}
-	var err error
 	if un.Primary == nil {
 		return unary(un.Unary, scope)
 	}
-	if len(un.Primary.Bool) > 0 {
-		if un.Primary.Bool == "true" {
+
+	r, err := primary(un.Primary, scope)
+	if un.Cast != nil {
+		return cast(r, un, scope), err
+	}
+
+	return r, err
+}
+
+func primary(p *tokens.Primary, scope *ast.Ast) (interface{}, error) {
+	var r interface{}
+	var err error
+	if len(p.Bool) > 0 {
+		if p.Bool == "true" {
 			r = true
 		} else {
 			r = false
 		}
-	} else if un.Primary.Nil != nil {
-		r = un.Primary.Nil
-	} else if len(un.Primary.Number) > 0 {
-		un.Primary.Number = strings.ReplaceAll(un.Primary.Number, "_", "")
-		r, err = strconv.Atoi(un.Primary.Number)
-	} else if len(un.Primary.String) > 0 {
-		r = un.Primary.String
-	} else if un.Primary.SubExpression != nil {
-		r, err = Evaluate(un.Primary.SubExpression, scope)
-	} else if len(un.Primary.Symbol) > 0 {
-		variable := utils.ResolveVariable(scope, un.Primary.Symbol)
+	} else if p.Nil != nil {
+		r = p.Nil
+	} else if len(p.Number) > 0 {
+		p.Number = strings.ReplaceAll(p.Number, "_", "")
+		if strings.Contains(p.Number, ".") && !strings.HasPrefix(p.Number, "0x") {
+			// Floats are not folded, they are left for the C compiler
+			return nil, nil
+		}
+		var n int64
+		n, err = strconv.ParseInt(p.Number, 0, 64)
+		if err != nil {
+			// Numbers above the largest int64 can still be stored in a u64
+			if u, uerr := strconv.ParseUint(p.Number, 0, 64); uerr == nil {
+				return u, nil
+			}
+			errors.AddError(errors.NewError(errors.NumberOutOfRange, p.Pos, "the number "+p.Number+" is too large for any integer type", scope))
+			return nil, err
+		}
+		r = int(n)
+	} else if len(p.String) > 0 {
+		r = p.String
+	} else if len(p.Char) > 0 {
+		r, err = utils.ParseChar(p.Char)
+		if err != nil {
+			errors.AddError(errors.NewError(errors.InvalidCharLiteral, p.Pos, err.Error(), scope))
+		}
+	} else if p.SubExpression != nil {
+		r, err = Evaluate(p.SubExpression, scope)
+	} else if len(p.Symbol) > 0 {
+		variable := utils.ResolveVariable(scope, p.Symbol)
+
+		// Constants are inlined so expressions using them can be folded
+		if variable != nil && variable.IsConst() && variable.Value != nil && variable.Value.Expression == nil {
+			if v := constantValue(variable.Value); v != nil {
+				return v, nil
+			}
+		}
 
 		// println(color.HiYellowString("%s", variable))
 		// repr.Println(variable == nil, scope.GetFullPath())
 
-		if strings.Contains(un.Primary.Symbol, ".") && variable != nil {
-			r = strings.Replace(un.Primary.Symbol, strings.Split(un.Primary.Symbol, ".")[0], variable.GetFullPath(), 1)
+		if strings.Contains(p.Symbol, ".") && variable != nil {
+			r = strings.Replace(p.Symbol, strings.Split(p.Symbol, ".")[0], variable.GetFullPath(), 1)
 			return r, err
 		}
 
@@ -106,19 +196,19 @@ func unary(un *tokens.Unary, scope *ast.Ast) (interface{}, error) {
 				// repr.Println(variable.Value)
 				// variable.Scope = scope
 				r = variable.GetFullPath()
-			} else if len(un.Primary.Symbol) > 0 {
+			} else if len(p.Symbol) > 0 {
 				r = variable.GetFullPath()
 			} else {
 				r, err = parseLiteral(variable.Value)
 			}
 
 		} else {
-			// repr.Println(scope, un.Pos.String())
-			err := errors.NewError(un.Pos, "Symbol '"+un.Primary.Symbol+"' not found", scope)
-			errors.AddError(err)
+			// repr.Println(scope, p.Pos.String())
+			err := errors.NewError(errors.UnknownSymbol, p.Pos, "Symbol '"+p.Symbol+"' not found", scope)
+			errors.AddError(err.WithSuggestion(utils.SimilarSymbol(scope, p.Symbol)))
 		}
-	} else if un.Primary.FuncCall != nil {
-		r = un.Primary.FuncCall
+	} else if p.FuncCall != nil {
+		r = p.FuncCall
 	}
 
 	return r, err
@@ -131,9 +221,8 @@ func multiplication(mult *tokens.Multiplication, scope *ast.Ast) (interface{}, e
 			var err error
 			m, err := unary(mult.Unary, scope)
 			n, err := multiplication(mult.Next, scope)
-			mNumber, okm := m.(int)
-			nNumber, okn := n.(int)
-			if okm && okn {
+			mNumber, nNumber, ok := numbers(m, n)
+			if ok {
 				return (mNumber * nNumber), err
 			}
 			return nil, err
@@ -142,9 +231,8 @@ func multiplication(mult *tokens.Multiplication, scope *ast.Ast) (interface{}, e
 			var err error
 			m, err := unary(mult.Unary, scope)
 			n, err := multiplication(mult.Next, scope)
-			mNumber, okm := m.(int)
-			nNumber, okn := n.(int)
-			if okm && okn {
+			mNumber, nNumber, ok := numbers(m, n)
+			if ok {
 				return (mNumber / nNumber), err
 			}
 			return nil, err
@@ -162,9 +250,10 @@ func addition(add *tokens.Addition, scope *ast.Ast) (interface{}, error) {
 			var err error
 			m, err := multiplication(add.Multiplication, scope)
 			n, err := addition(add.Next, scope)
-			mNumber, okm := m.(int)
-			nNumber, okn := n.(int)
-			if okm && okn {
+			mNumber, nNumber, ok := numbers(m, n)
+			if ok && (isChar(m) || isChar(n)) {
+				return byte(mNumber + nNumber), nil
+			} else if ok {
 				return (mNumber + nNumber), nil
 			}
 
@@ -172,7 +261,8 @@ func addition(add *tokens.Addition, scope *ast.Ast) (interface{}, error) {
 			mString, okm := m.(string)
 			nString, okn := n.(string)
 
-			if okm && okn {
+			// Only literals can be joined here, symbols are concatenated at runtime
+			if okm && okn && isStringLiteral(mString) && isStringLiteral(nString) {
 				return (mString[:len(mString)-1] + nString[1:]), nil
 			}
 
@@ -182,9 +272,10 @@ func addition(add *tokens.Addition, scope *ast.Ast) (interface{}, error) {
 			var err error
 			m, err := multiplication(add.Multiplication, scope)
 			n, err := addition(add.Next, scope)
-			mNumber, okm := m.(int)
-			nNumber, okn := n.(int)
-			if okm && okn {
+			mNumber, nNumber, ok := numbers(m, n)
+			if ok && (isChar(m) || isChar(n)) {
+				return byte(mNumber - nNumber), err
+			} else if ok {
 				return (mNumber - nNumber), err
 			}
 			return nil, err
@@ -203,9 +294,8 @@ func comparison(cmp *tokens.Comparison, scope *ast.Ast) (interface{}, error) {
 			var err error
 			m, err := addition(cmp.Addition, scope)
 			n, err := comparison(cmp.Next, scope)
-			mNumber, okm := m.(int)
-			nNumber, okn := n.(int)
-			if okm && okn {
+			mNumber, nNumber, ok := numbers(m, n)
+			if ok {
 				return (mNumber > nNumber), nil
 			}
 
@@ -215,9 +305,8 @@ func comparison(cmp *tokens.Comparison, scope *ast.Ast) (interface{}, error) {
 			var err error
 			m, err := addition(cmp.Addition, scope)
 			n, err := comparison(cmp.Next, scope)
-			mNumber, okm := m.(int)
-			nNumber, okn := n.(int)
-			if okm && okn {
+			mNumber, nNumber, ok := numbers(m, n)
+			if ok {
 				return (mNumber < nNumber), err
 			}
 			return nil, err
@@ -226,9 +315,8 @@ func comparison(cmp *tokens.Comparison, scope *ast.Ast) (interface{}, error) {
 			var err error
 			m, err := addition(cmp.Addition, scope)
 			n, err := comparison(cmp.Next, scope)
-			mNumber, okm := m.(int)
-			nNumber, okn := n.(int)
-			if okm && okn {
+			mNumber, nNumber, ok := numbers(m, n)
+			if ok {
 				return (mNumber >= nNumber), nil
 			}
 
@@ -238,9 +326,8 @@ func comparison(cmp *tokens.Comparison, scope *ast.Ast) (interface{}, error) {
 			var err error
 			m, err := addition(cmp.Addition, scope)
 			n, err := comparison(cmp.Next, scope)
-			mNumber, okm := m.(int)
-			nNumber, okn := n.(int)
-			if okm && okn {
+			mNumber, nNumber, ok := numbers(m, n)
+			if ok {
 				return (mNumber <= nNumber), nil
 			}
 
@@ -258,15 +345,14 @@ func equality(eq *tokens.Equality, scope *ast.Ast) (interface{}, error) {
 			var err error
 			eql, err := equality(eq.Next, scope)
 			cmp, err := comparison(eq.Comparison, scope)
-			eqlNum, okeqlnum := eql.(int)
-			cmpNum, okcmpnum := cmp.(int)
-			if okeqlnum && okcmpnum {
+			eqlNum, cmpNum, ok := numbers(eql, cmp)
+			if ok {
 				return (eqlNum != cmpNum), err
 			}
 
 			eqlStr, okeqlstring := eql.(string)
 			cmpStr, okcmpstring := cmp.(string)
-			if okeqlstring && okcmpstring {
+			if okeqlstring && okcmpstring && isStringLiteral(eqlStr) && isStringLiteral(cmpStr) {
 				return (eqlStr != cmpStr), err
 			}
 
@@ -275,15 +361,14 @@ func equality(eq *tokens.Equality, scope *ast.Ast) (interface{}, error) {
 			var err error
 			eql, err := equality(eq.Next, scope)
 			cmp, err := comparison(eq.Comparison, scope)
-			eqlNum, okeqlnum := eql.(int)
-			cmpNum, okcmpnum := cmp.(int)
-			if okeqlnum && okcmpnum {
+			eqlNum, cmpNum, ok := numbers(eql, cmp)
+			if ok {
 				return (eqlNum == cmpNum), err
 			}
 
 			eqlStr, okeqlstring := eql.(string)
 			cmpStr, okcmpstring := cmp.(string)
-			if okeqlstring && okcmpstring {
+			if okeqlstring && okcmpstring && isStringLiteral(eqlStr) && isStringLiteral(cmpStr) {
 				return (eqlStr == cmpStr), nil
 			}
 
@@ -0,0 +1,314 @@
+package evaluate
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/tokens"
+	"github.com/neutrino2211/Gecko/utils"
+)
+
+func namedType(name string) *tokens.TypeRef {
+	return &tokens.TypeRef{
+		Type: name,
+	}
+}
+
+// IsStringType : Checks if t is gecko's built in string type
+func IsStringType(t *tokens.TypeRef) bool {
+	return t != nil && t.Array == nil && t.Map == nil && t.Type == "string"
+}
+
+// IsBoolType : Checks if t is gecko's built in bool type
+func IsBoolType(t *tokens.TypeRef) bool {
+	return t != nil && t.Array == nil && t.Map == nil && t.Type == "bool"
+}
+
+func findClass(name string, scope *ast.Ast) *ast.Class {
+	for s := scope; s != nil; s = s.Parent {
+		if s.Classes[name] != nil {
+			return s.Classes[name]
+		}
+	}
+
+	return nil
+}
+
+func findMethod(name string, scope *ast.Ast) *ast.Method {
+	for s := scope; s != nil; s = s.Parent {
+		if s.Methods[name] != nil {
+			return s.Methods[name]
+		}
+	}
+
+	return nil
+}
+
+func findVariable(name string, scope *ast.Ast) *ast.Variable {
+	if variable := utils.ResolveVariable(scope, name); variable != nil {
+		return variable
+	}
+
+	for s := scope.Parent; s != nil; s = s.Parent {
+		if s.Variables[name] != nil {
+			return s.Variables[name]
+		}
+	}
+
+	// Values that were already flattened refer to variables by their full path
+	for s := scope; s != nil && strings.Contains(name, "__"); s = s.Parent {
+		for _, variable := range s.Variables {
+			if variable.Scope != nil && variable.GetFullPath() == name {
+				return variable
+			}
+		}
+	}
+
+	return nil
+}
+
+func methodType(mthd *ast.Method) *tokens.TypeRef {
+	if mthd.Type == nil {
+		return namedType("void")
+	}
+
+	return mthd.Type
+}
+
+func fieldType(t *tokens.TypeRef, field string, scope *ast.Ast) *tokens.TypeRef {
+	if t.Array != nil || t.Map != nil || IsStringType(t) {
+		if field == "len" {
+			return namedType("int")
+		}
+		return nil
+	}
+
+	class := findClass(t.Type, scope)
+	if class == nil || class.Variables[field] == nil {
+		return nil
+	}
+
+	return class.Variables[field].Type
+}
+
+// SymbolType : Resolves the type of a (possibly dotted) symbol such as `self.name.len`
+func SymbolType(symbol string, scope *ast.Ast) *tokens.TypeRef {
+	levels := strings.Split(symbol, ".")
+	variable := findVariable(levels[0], scope)
+
+	if variable == nil {
+		return nil
+	}
+
+	t := variable.Type
+	for _, field := range levels[1:] {
+		if t == nil {
+			return nil
+		}
+		t = fieldType(t, field, scope)
+	}
+
+	return t
+}
+
+// CallType : Resolves the return type of a function, method or built in method call
+func CallType(call *tokens.FuncCall, scope *ast.Ast) *tokens.TypeRef {
+	if mthd := findMethod(call.Function, scope); mthd != nil {
+		return methodType(mthd)
+	}
+
+	if class := findClass(call.Function, scope); class != nil {
+		return namedType(call.Function)
+	}
+
+	dot := strings.LastIndex(call.Function, ".")
+	if dot < 0 {
+		return nil
+	}
+
+	receiver := SymbolType(call.Function[:dot], scope)
+	method := call.Function[dot+1:]
+
+	if receiver == nil {
+		return nil
+	} else if receiver.Array != nil {
+		switch method {
+		case "pop":
+			return receiver.Array
+		case "push":
+			return namedType("void")
+		}
+	} else if receiver.Map != nil {
+		switch method {
+		case "get":
+			return receiver.Map.Value
+		case "has", "delete":
+			return namedType("bool")
+		case "set":
+			return namedType("void")
+		}
+	} else if class := findClass(receiver.Type, scope); class != nil && class.Methods[method] != nil {
+		return methodType(class.Methods[method])
+	}
+
+	return nil
+}
+
+func numericType(a *tokens.TypeRef, b *tokens.TypeRef) *tokens.TypeRef {
+	if a == nil {
+		return b
+	} else if b != nil && b.Type == "float" {
+		return b
+	}
+
+	return a
+}
+
+// InferType : Returns the gecko type an expression node evaluates to at runtime.
+// node can be a Literal or any of the expression tokens (Expression, Equality,
+// ..., Primary). nil is returned when the type can't be determined.
+func InferType(node interface{}, scope *ast.Ast) *tokens.TypeRef {
+	switch n := node.(type) {
+	case *tokens.Literal:
+		if n.Expression != nil {
+			return InferType(n.Expression, scope)
+		} else if n.FuncCall != nil {
+			return CallType(n.FuncCall, scope)
+		} else if len(n.Bool) > 0 {
+			return namedType("bool")
+		} else if len(n.String) > 0 {
+			return namedType("string")
+		} else if len(n.Char) > 0 {
+			return namedType("char")
+		} else if len(n.Number) > 0 {
+			return numberType(n.Number)
+		} else if len(n.Symbol) > 0 {
+			return SymbolType(n.Symbol, scope)
+		} else if len(n.Array) > 0 {
+			if item := InferType(n.Array[0], scope); item != nil {
+				return &tokens.TypeRef{Array: item}
+			}
+		}
+	case *tokens.Expression:
+		return InferType(n.Equality, scope)
+	case *tokens.Equality:
+		if len(n.Op) > 0 {
+			return namedType("bool")
+		}
+		return InferType(n.Comparison, scope)
+	case *tokens.Comparison:
+		if len(n.Op) > 0 {
+			return namedType("bool")
+		}
+		return InferType(n.Addition, scope)
+	case *tokens.Addition:
+		t := InferType(n.Multiplication, scope)
+		if len(n.Op) == 0 {
+			return t
+		}
+		next := InferType(n.Next, scope)
+		if n.Op == "+" && (IsStringType(t) || IsStringType(next)) {
+			return namedType("string")
+		}
+		return numericType(t, next)
+	case *tokens.Multiplication:
+		t := InferType(n.Unary, scope)
+		if len(n.Op) == 0 {
+			return t
+		}
+		return numericType(t, InferType(n.Next, scope))
+	case *tokens.Unary:
+		if number, ok := NegatedNumber(n); ok {
+			return numberType(number)
+		} else if n.Cast != nil {
+			return n.Cast
+		} else if n.Primary != nil {
+			return InferType(n.Primary, scope)
+		} else if n.Op == "!" {
+			return namedType("bool")
+		}
+		return InferType(n.Unary, scope)
+	case *tokens.Primary:
+		if n.FuncCall != nil {
+			return CallType(n.FuncCall, scope)
+		} else if len(n.Bool) > 0 {
+			return namedType("bool")
+		} else if len(n.String) > 0 {
+			return namedType("string")
+		} else if len(n.Char) > 0 {
+			return namedType("char")
+		} else if len(n.Number) > 0 {
+			return numberType(n.Number)
+		} else if len(n.Symbol) > 0 {
+			return SymbolType(n.Symbol, scope)
+		} else if n.SubExpression != nil {
+			return InferType(n.SubExpression, scope)
+		}
+	}
+
+	return nil
+}
+
+func numberType(number string) *tokens.TypeRef {
+	if strings.Contains(number, ".") && !strings.HasPrefix(number, "0x") {
+		return namedType("float")
+	} else if _, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return namedType("int")
+	} else if _, err := strconv.ParseUint(number, 0, 64); err == nil {
+		// Only a u64 can hold numbers too large for an int64
+		return namedType("u64")
+	}
+
+	// Numbers too large for any integer type have no type, they were reported when they were evaluated
+	return nil
+}
+
+// SameType : Checks if a and b name exactly the same type
+func SameType(a *tokens.TypeRef, b *tokens.TypeRef) bool {
+	if a.Array != nil || b.Array != nil {
+		return a.Array != nil && b.Array != nil && SameType(a.Array, b.Array)
+	} else if a.Map != nil || b.Map != nil {
+		return a.Map != nil && b.Map != nil && SameType(a.Map.Key, b.Map.Key) && SameType(a.Map.Value, b.Map.Value)
+	}
+
+	return a.Type == b.Type && a.Pointer == b.Pointer
+}
+
+func isCheckedType(t *tokens.TypeRef, scope *ast.Ast) bool {
+	return IsStringType(t) || IsBoolType(t) || IsNumericType(t) || t.Type == "void" || findClass(t.Type, scope) != nil
+}
+
+// IsAssignable : Checks if a value of type from can be used where a value of type to is expected.
+// Types the compiler knows nothing about, like those of external C symbols, are always accepted.
+func IsAssignable(from *tokens.TypeRef, to *tokens.TypeRef, scope *ast.Ast) bool {
+	if from == nil || to == nil {
+		return true
+	} else if from.Array != nil || to.Array != nil || from.Map != nil || to.Map != nil {
+		return SameType(from, to)
+	} else if from.Pointer || to.Pointer {
+		return true
+	} else if IsNumericType(from) && IsNumericType(to) {
+		// Narrowing is only a warning, see CheckConversion
+		return true
+	} else if !isCheckedType(from, scope) || !isCheckedType(to, scope) {
+		return true
+	}
+
+	return from.Type == to.Type
+}
+
+// TypeName : Returns t the way it is written in gecko source
+func TypeName(t *tokens.TypeRef) string {
+	if t == nil {
+		return "unknown"
+	} else if t.Array != nil {
+		return "[" + TypeName(t.Array) + "]"
+	} else if t.Map != nil {
+		return "map[" + TypeName(t.Map.Key) + "]" + TypeName(t.Map.Value)
+	} else if t.Pointer {
+		return t.Type + "*"
+	}
+
+	return t.Type
+}
@@ -0,0 +1,172 @@
+package evaluate
+
+import (
+	"strconv"
+
+	"github.com/neutrino2211/Gecko/ast"
+	"github.com/neutrino2211/Gecko/errors"
+	"github.com/neutrino2211/Gecko/tokens"
+)
+
+// NumericType : Describes the size and representation of a built in numeric type
+type NumericType struct {
+	Bits   uint
+	Signed bool
+	Float  bool
+}
+
+// NumericTypes : Every built in numeric type. Sized types are mapped to <stdint.h>
+var NumericTypes = map[string]*NumericType{
+	"i8":    &NumericType{Bits: 8, Signed: true},
+	"i16":   &NumericType{Bits: 16, Signed: true},
+	"i32":   &NumericType{Bits: 32, Signed: true},
+	"i64":   &NumericType{Bits: 64, Signed: true},
+	"u8":    &NumericType{Bits: 8},
+	"u16":   &NumericType{Bits: 16},
+	"u32":   &NumericType{Bits: 32},
+	"u64":   &NumericType{Bits: 64},
+	"usize": &NumericType{Bits: 64},
+	"int":   &NumericType{Bits: 32, Signed: true},
+	"char":  &NumericType{Bits: 8, Signed: true},
+	"byte":  &NumericType{Bits: 8},
+	"f32":   &NumericType{Bits: 32, Signed: true, Float: true},
+	"f64":   &NumericType{Bits: 64, Signed: true, Float: true},
+	"float": &NumericType{Bits: 32, Signed: true, Float: true},
+}
+
+// GetNumericType : Returns the numeric type t refers to or nil if t is not numeric
+func GetNumericType(t *tokens.TypeRef) *NumericType {
+	if t == nil || t.Array != nil || t.Map != nil || t.Pointer {
+		return nil
+	}
+
+	return NumericTypes[t.Type]
+}
+
+// IsNumericType : Checks if t is one of the built in numeric types
+func IsNumericType(t *tokens.TypeRef) bool {
+	return GetNumericType(t) != nil
+}
+
+// CanWiden : Checks if a value of type from can be implicitly converted to type to
+// without losing information. Conversions the other way need an explicit `as`.
+func CanWiden(from *tokens.TypeRef, to *tokens.TypeRef) bool {
+	f := GetNumericType(from)
+	t := GetNumericType(to)
+
+	if f == nil || t == nil {
+		return true
+	} else if t.Float {
+		return f.Float && f.Bits <= t.Bits || !f.Float && f.Bits < t.Bits
+	} else if f.Float {
+		return false
+	} else if f.Signed == t.Signed {
+		return f.Bits <= t.Bits
+	}
+
+	// Unsigned values fit in any larger signed type but signed values never fit in unsigned ones
+	return !f.Signed && f.Bits < t.Bits
+}
+
+// Fits : Checks if the constant n can be represented by the numeric type t
+func (t *NumericType) Fits(n int) bool {
+	if t.Float || t.Bits >= 64 && (t.Signed || n >= 0) {
+		return true
+	} else if t.Signed {
+		limit := 1 << (t.Bits - 1)
+		return n >= -limit && n < limit
+	}
+
+	return n >= 0 && n < 1<<t.Bits
+}
+
+// Truncate : Converts the constant n the way C would when it is stored in type t
+func (t *NumericType) Truncate(n int) int {
+	if t.Float || t.Bits >= 64 {
+		return n
+	}
+
+	mask := 1<<t.Bits - 1
+	n &= mask
+	if t.Signed && n >= 1<<(t.Bits-1) {
+		n -= mask + 1
+	}
+
+	return n
+}
+
+// cast : Folds `value as T` when value is a constant and checks that the conversion is allowed
+func cast(value interface{}, un *tokens.Unary, scope *ast.Ast) interface{} {
+	target := GetNumericType(un.Cast)
+	from := InferType(un.Primary, scope)
+
+	if target == nil || from != nil && !IsNumericType(from) {
+		errors.AddError(errors.NewError(errors.InvalidCast, un.Pos, "cannot cast "+TypeName(from)+" to "+TypeName(un.Cast), scope))
+		return nil
+	}
+
+	n, ok := number(value)
+	if !ok {
+		// Casts of runtime values are generated as C casts
+		return nil
+	} else if un.Cast.Type == "char" || un.Cast.Type == "byte" {
+		return byte(n)
+	}
+
+	return target.Truncate(n)
+}
+
+// constant : Returns the integer value is if it is known at compile time. Unsigned is set for
+// numbers too large for an int, n holds their bits
+func constant(value *tokens.Literal, scope *ast.Ast) (n int, unsigned bool, ok bool) {
+	var v interface{}
+	if value.Expression != nil {
+		v, _ = Evaluate(value.Expression, scope)
+	} else {
+		v = constantValue(value)
+	}
+
+	switch v := v.(type) {
+	case int:
+		return v, false, true
+	case byte:
+		return int(v), false, true
+	case uint64:
+		return int(v), true, true
+	}
+
+	return 0, false, false
+}
+
+// NegatedNumber : Returns "-<number>" when un negates a number literal. It is one signed
+// value, the smallest i64 can only be written this way and the number alone would be a u64
+func NegatedNumber(un *tokens.Unary) (string, bool) {
+	if un.Op != "-" || un.Unary == nil || un.Unary.Primary == nil || un.Unary.Cast != nil || len(un.Unary.Primary.Number) == 0 {
+		return "", false
+	}
+
+	return "-" + un.Unary.Primary.Number, true
+}
+
+// CheckConversion : Warns when value, of type from before it was folded, is implicitly
+// narrowed to the numeric type t
+func CheckConversion(value *tokens.Literal, from *tokens.TypeRef, t *tokens.TypeRef, scope *ast.Ast) {
+	target := GetNumericType(t)
+	if value == nil || target == nil {
+		return
+	}
+
+	if n, unsigned, ok := constant(value, scope); ok {
+		if unsigned && !target.Signed && target.Bits >= 64 || !unsigned && target.Fits(n) {
+			return
+		}
+
+		text := strconv.Itoa(n)
+		if unsigned {
+			text = strconv.FormatUint(uint64(n), 10)
+		}
+		errors.AddWarning(errors.NewError(errors.ConstantOverflow, value.Pos, "constant "+text+" overflows "+t.Type+" and becomes "+strconv.Itoa(target.Truncate(n))+", use `as "+t.Type+"` to convert it explicitly", scope))
+	} else if !CanWiden(from, t) {
+		errors.AddWarning(errors.NewError(errors.ImplicitNarrowing, value.Pos, "implicit narrowing conversion from "+TypeName(from)+" to "+t.Type+", use `as "+t.Type+"` to convert it explicitly", scope))
+	}
+}
@@ -1,8 +1,12 @@
 package evaluate
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/neutrino2211/Gecko/ast"
 	"github.com/neutrino2211/Gecko/tokens"
+	"github.com/neutrino2211/Gecko/utils"
 )
 
 func IsFalse(expr *tokens.Expression, ast *ast.Ast) bool {
@@ -41,16 +45,70 @@ func CouldBeBool(expr *tokens.Expression, ast *ast.Ast) bool {
 		break
 	case *tokens.FuncCall:
 		e := e.(*tokens.FuncCall)
-		mthd := ast.Methods[e.Function]
-		if mthd.Method.Type.Type == "bool" && mthd.Method.Type.Array == nil {
-			r = true
-		}
+		r = IsBoolType(CallType(e, ast))
 		break
 	case int:
 		e := e.(int)
 		r = e > -1 && e < 2
 		break
+	case nil, string:
+		// Not a constant, check what it evaluates to at runtime
+		r = IsBoolType(InferType(expr, ast))
+		break
 	}
 	// fmt.Println(r)
 	return r
 }
+
+// IsConstant : Checks if node (a Literal or any expression token) is made up of nothing but literals
+func IsConstant(node interface{}) bool {
+	switch n := node.(type) {
+	case *tokens.Literal:
+		if n.Expression != nil {
+			return IsConstant(n.Expression.Equality)
+		}
+		return len(n.Number) > 0 || len(n.Char) > 0 || len(n.Bool) > 0 || len(n.String) > 0 && !strings.Contains(n.String, "${")
+	case *tokens.Equality:
+		return n == nil || IsConstant(n.Comparison) && IsConstant(n.Next)
+	case *tokens.Comparison:
+		return n == nil || IsConstant(n.Addition) && IsConstant(n.Next)
+	case *tokens.Addition:
+		return n == nil || IsConstant(n.Multiplication) && IsConstant(n.Next)
+	case *tokens.Multiplication:
+		return n == nil || IsConstant(n.Unary) && IsConstant(n.Next)
+	case *tokens.Unary:
+		if n == nil {
+			return true
+		} else if n.Primary == nil {
+			return IsConstant(n.Unary)
+		}
+		p := n.Primary
+		if p.SubExpression != nil {
+			return IsConstant(p.SubExpression.Equality)
+		}
+		return len(p.Number) > 0 || len(p.Char) > 0 || len(p.Bool) > 0 || len(p.String) > 0 && !strings.Contains(p.String, "${")
+	}
+
+	return false
+}
+
+// constantValue : Returns the value of a folded constant the same way Evaluate would
+func constantValue(lit *tokens.Literal) interface{} {
+	if len(lit.Bool) > 0 {
+		return lit.Bool == "true"
+	} else if len(lit.Char) > 0 {
+		c, _ := utils.ParseChar(lit.Char)
+		return c
+	} else if len(lit.String) > 0 {
+		return lit.String
+	} else if len(lit.Number) > 0 && !strings.Contains(lit.Number, ".") {
+		n, err := strconv.ParseInt(lit.Number, 0, 64)
+		if err == nil {
+			return int(n)
+		} else if u, err := strconv.ParseUint(lit.Number, 0, 64); err == nil {
+			return u
+		}
+	}
+
+	return nil
+}
@@ -7,7 +7,7 @@ class FancyType {
     my_variable: int = 2
     
     func constructor(self: FancyType, my_variable: int = 2): FancyType {
-        lib.print(val: self.my_variable, format: "My Variable %u\n")
+        lib.print(val: "My Variable ${self.my_variable}")
         self.my_variable = my_variable
         return self
     }
@@ -33,10 +33,10 @@ func Main(argc: int, argv: [string]): int {
     point: int = typeTest.point(index: 22)
 
     // Printing
-    lib.print(val: "Printing Time!!\n\n")
-    lib.print(format: "My Variable dereferencing => %u\n", val: typeTest.my_variable)
-    lib.print(format:"Gecko version: %s\n", val: returnsString())
-    lib.print(format: "Point => %u\n", val: point)
+    lib.print(val: "Printing Time!!\n")
+    lib.print(val: "My Variable dereferencing => ${typeTest.my_variable}")
+    lib.print(val: "Gecko version: ${returnsString()}")
+    lib.print(val: "Point => ${point}")
 
     return 2
 }
\ No newline at end of file
@@ -2,8 +2,8 @@ package lib
 
 ##include<stdio.h>
 
-external func printf(format: string, a0: int)
+external func puts(val: string)
 
-func print(val: string, format: string = "%s\n"): void {
-    printf(format: format, a0: val)
-}
\ No newline at end of file
+func print(val: string): void {
+    puts(val)
+}
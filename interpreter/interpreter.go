@@ -0,0 +1,452 @@
+// Package interpreter runs lowered gecko programs without compiling them to C.
+//
+// The interpreter walks the blocks of an ir.Module and computes the value
+// trees of their instructions directly. Values behave the way they do in the
+// generated C: integers wrap at the size of their type, lists are copied with
+// the storage they share and maps are the runtime's hash table, so a program
+// prints the same when it runs here as when it was built. External C functions
+// can't be called, a small set of libc functions, like printf, is provided by
+// the interpreter instead. Calls can't be nested deeper than maxCallDepth, a
+// program that recurses further stops with a runtime error where a built one
+// would crash.
+package interpreter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/neutrino2211/Gecko/ir"
+)
+
+// Interpreter : Runs the functions of a module
+type Interpreter struct {
+	Stdout io.Writer
+	Stdin  io.Reader
+
+	module  *ir.Module
+	globals frame
+	out     *bufio.Writer
+	in      *bufio.Reader
+	// pos : Where the instruction running is, runtime errors are reported there
+	pos lexer.Position
+	// depth : How many calls are running, see enter
+	depth int
+}
+
+// maxCallDepth : How deep calls can be nested before the program is stopped, instead of overflowing the stack of gecko
+const maxCallDepth = 10000
+
+// Error : A runtime error, like popping from an empty list
+type Error struct {
+	Pos     lexer.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Pos.Filename == "" {
+		return "gecko: " + e.Message
+	}
+
+	return "gecko: " + e.Message + " [" + e.Pos.String() + "]"
+}
+
+// exit : Unwinds the interpreter when the program calls exit
+type exit struct {
+	status int
+}
+
+// frame : The variables of a running function
+type frame map[*ir.Variable]*value
+
+// New : Creates an interpreter for m that uses the standard streams of the process
+func New(m *ir.Module) *Interpreter {
+	return &Interpreter{
+		Stdout: os.Stdout,
+		Stdin:  os.Stdin,
+		module: m,
+	}
+}
+
+// Run : Runs Main with args, the program's name included, and returns the status the program exits with
+func (in *Interpreter) Run(args []string) (int, error) {
+	main := in.module.Main
+	if main == nil {
+		return 1, &Error{Message: "package " + in.module.Name + " has no Main function"}
+	}
+
+	return in.start(func() int {
+		in.globals = frame{}
+		for _, g := range in.module.Globals {
+			v := zero(g.Type)
+			if g.Value != nil {
+				v = convert(in.eval(g.Value, in.globals), g.Value.Type(), g.Type)
+			}
+			in.globals[g] = &v
+		}
+
+		return status(main.Result, in.call(main, mainArgs(main.Params, args)))
+	})
+}
+
+// start : Runs a program, run returns the status it exits with unless it calls exit or fails first
+func (in *Interpreter) start(run func() int) (status int, err error) {
+	in.out = bufio.NewWriter(in.Stdout)
+	in.in = bufio.NewReader(in.Stdin)
+	defer in.out.Flush()
+
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case exit:
+				status = r.status
+			case *Error:
+				status, err = 1, r
+			default:
+				panic(r)
+			}
+		}
+	}()
+
+	return run(), nil
+}
+
+// mainArgs : Returns the arguments Main is called with, its params can be the list of args or their count
+func mainArgs(params []*ir.Variable, args []string) []value {
+	arguments := []value{}
+	for _, p := range params {
+		if p.Type.Kind == ir.List {
+			l := &list{}
+			for _, arg := range args {
+				l.push(arg)
+			}
+			arguments = append(arguments, l)
+		} else {
+			arguments = append(arguments, int64(len(args)))
+		}
+	}
+
+	return arguments
+}
+
+// status : Returns the status a program exits with when Main returns result
+func status(t *ir.Type, result value) int {
+	if t.GeckoName == "int" {
+		return int(int32(asInt(result)))
+	}
+
+	return 0
+}
+
+// fail : Stops the program with a runtime error at the instruction running
+func (in *Interpreter) fail(format string, args ...interface{}) {
+	panic(&Error{Pos: in.pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// enter : Counts a call to the function name, the program fails if calls are nested too deep. leave ends it
+func (in *Interpreter) enter(name string) {
+	in.depth++
+	if in.depth > maxCallDepth {
+		in.fail("stack overflow, calls to %s are nested more than %d deep", name, maxCallDepth)
+	}
+}
+
+func (in *Interpreter) leave() {
+	in.depth--
+}
+
+func (in *Interpreter) call(f *ir.Function, args []value) value {
+	in.enter(f.Name)
+	defer in.leave()
+
+	vars := frame{}
+	for i, p := range f.Params {
+		v := args[i]
+		vars[p] = &v
+	}
+
+	for _, l := range f.Locals {
+		v := zero(l.Type)
+		vars[l] = &v
+	}
+	// Constants are initialised before anything runs, like the static constants they become in C
+	for _, l := range f.Locals {
+		if l.Value != nil {
+			*vars[l] = convert(in.eval(l.Value, vars), l.Value.Type(), l.Type)
+		}
+	}
+
+	block := f.Blocks[0]
+	for {
+		for _, i := range block.Instrs {
+			in.pos = i.Position()
+			in.exec(i, vars)
+		}
+
+		in.pos = block.Term.Position()
+		switch t := block.Term.(type) {
+		case *ir.Jump:
+			block = t.Target
+		case *ir.Branch:
+			if truthy(in.eval(t.Cond, vars)) {
+				block = t.Then
+			} else {
+				block = t.Else
+			}
+		case *ir.Return:
+			if t.Value == nil {
+				return nil
+			}
+			return convert(in.eval(t.Value, vars), t.Value.Type(), f.Result)
+		default:
+			in.fail("%s reached the end of a block it can't leave", f.Name)
+		}
+	}
+}
+
+func (in *Interpreter) exec(i ir.Instr, vars frame) {
+	switch i := i.(type) {
+	case *ir.Assign:
+		v := convert(in.eval(i.Value, vars), i.Value.Type(), i.Dest.Type())
+		*in.locate(i.Dest, vars) = v
+	case *ir.Eval:
+		in.eval(i.Value, vars)
+	}
+}
+
+// locate : Returns where the variable or field v is stored
+func (in *Interpreter) locate(v ir.Value, vars frame) *value {
+	switch v := v.(type) {
+	case *ir.Ref:
+		if slot := vars[v.Var]; slot != nil {
+			return slot
+		} else if slot := in.globals[v.Var]; slot != nil {
+			return slot
+		}
+		in.fail("'%s' isn't a variable of the function running", v.Var.Name)
+	case *ir.Member:
+		o, ok := (*in.locate(v.X, vars)).(*object)
+		if !ok {
+			in.fail("'%s' can't be assigned to", v.Name)
+		}
+		return &o.fields[o.field(v.Name)]
+	}
+
+	in.fail("%s can't be assigned to", ir.Repr(v))
+	return nil
+}
+
+func (in *Interpreter) evalAll(values []ir.Value, vars frame) []value {
+	r := []value{}
+	for _, v := range values {
+		r = append(r, in.eval(v, vars))
+	}
+
+	return r
+}
+
+// eval : Computes v. Instances of classes are copied, they are values in C too
+func (in *Interpreter) eval(v ir.Value, vars frame) value {
+	switch v := v.(type) {
+	case *ir.Const:
+		return constant(v)
+	case *ir.Null:
+		return nil
+	case *ir.Ref:
+		return copyValue(*in.locate(v, vars))
+	case *ir.Extern:
+		if c, ok := externs[v.Name]; ok {
+			return c
+		}
+		in.fail("the C symbol '%s' isn't available when running without a C compiler", v.Name)
+	case *ir.Member:
+		return in.member(in.eval(v.X, vars), v.Name)
+	case *ir.Unary:
+		return in.unary(v.Op, in.eval(v.X, vars), v.X.Type())
+	case *ir.Binary:
+		return in.binary(v.Op, in.eval(v.X, vars), v.X.Type(), in.eval(v.Y, vars), v.Y.Type(), v.T)
+	case *ir.Cast:
+		return convert(in.eval(v.X, vars), v.X.Type(), v.T)
+	case *ir.Format:
+		r := v.Parts[0]
+		for i, arg := range v.Args {
+			r += formatArg(in.eval(arg, vars), arg.Type()) + v.Parts[i+1]
+		}
+		return r
+	case *ir.CString:
+		return in.eval(v.X, vars)
+	case *ir.Call:
+		return in.callValue(v, vars)
+	case *ir.Intrinsic:
+		return in.intrinsic(v, vars)
+	case *ir.ListLit:
+		l := &list{}
+		for _, item := range v.Items {
+			l.push(convert(in.eval(item, vars), item.Type(), v.T.Elem))
+		}
+		return l
+	case *ir.MapLit:
+		t := newTable(v.T)
+		for i := range v.Keys {
+			t.set(key(in.eval(v.Keys[i], vars), v.T), convert(in.eval(v.Values[i], vars), v.Values[i].Type(), v.T.Elem))
+		}
+		return t
+	case *ir.StructLit:
+		o := zero(v.T).(*object)
+		for i, name := range v.Fields {
+			index := o.field(name)
+			o.fields[index] = convert(in.eval(v.Values[i], vars), v.Values[i].Type(), v.T.Struct.Fields[index].Type)
+		}
+		return o
+	}
+
+	in.fail("%s can't be computed", ir.Repr(v))
+	return nil
+}
+
+func (in *Interpreter) member(x value, name string) value {
+	switch x := x.(type) {
+	case *object:
+		return copyValue(x.fields[x.field(name)])
+	case *list:
+		if name == "len" {
+			return int64(len(x.items))
+		} else if name == "cap" {
+			return int64(x.cap)
+		}
+	case *table:
+		if name == "len" {
+			return int64(x.len)
+		} else if name == "cap" {
+			return int64(len(x.slots))
+		}
+	case string:
+		if name == "len" {
+			return int64(len(x))
+		}
+	}
+
+	in.fail("values of this type have no field '%s'", name)
+	return nil
+}
+
+func (in *Interpreter) callValue(v *ir.Call, vars frame) value {
+	args := in.evalAll(v.Args, vars)
+
+	if v.External {
+		fn := libc[v.Func]
+		if fn == nil {
+			in.fail("the C function '%s' isn't available when running without a C compiler", v.Func)
+		}
+		return convert(fn(in, args), ir.UnknownType, v.T)
+	}
+
+	f := in.module.Function(v.Func)
+	if f == nil {
+		in.fail("'%s' belongs to a module built on its own, which can't be run without a C compiler", v.Func)
+	}
+	if len(args) != len(f.Params) {
+		in.fail("%s takes %d arguments but was called with %d", f.Name, len(f.Params), len(args))
+	}
+
+	for i, p := range f.Params {
+		args[i] = convert(args[i], v.Args[i].Type(), p.Type)
+	}
+
+	pos := in.pos
+	r := in.call(f, args)
+	in.pos = pos
+	return r
+}
+
+func (in *Interpreter) intrinsic(v *ir.Intrinsic, vars frame) value {
+	t := v.Args[0].Type()
+	slot := in.locate(v.Args[0], vars)
+	args := in.evalAll(v.Args[1:], vars)
+
+	switch v.Op {
+	case ir.ListPush:
+		args[0] = convert(args[0], v.Args[1].Type(), t.Elem)
+	case ir.MapSet:
+		args[1] = convert(args[1], v.Args[2].Type(), t.Elem)
+	}
+
+	return in.runIntrinsic(v.Op, t, v.T, slot, args)
+}
+
+// runIntrinsic : Runs op on the list or map of type t in slot, items and values stored by it have to
+// be converted to the type of the list or map already
+func (in *Interpreter) runIntrinsic(op ir.Op, t *ir.Type, result *ir.Type, slot *value, args []value) value {
+	switch op {
+	case ir.ListPush:
+		in.list(slot).push(args[0])
+		return nil
+	case ir.ListPop:
+		l := in.list(slot)
+		if len(l.items) == 0 {
+			in.fail("pop from empty list")
+		}
+		item := l.items[len(l.items)-1]
+		l.items = l.items[:len(l.items)-1]
+		return copyValue(item)
+	case ir.ListAt:
+		l := in.list(slot)
+		i := asInt(args[0])
+		if i < 0 || i >= int64(len(l.items)) {
+			in.fail("index %d is out of the bounds of a list of %d items", i, len(l.items))
+		}
+		return copyValue(l.items[i])
+	case ir.ListFree:
+		l := in.list(slot)
+		l.items, l.cap = nil, 0
+		return nil
+	}
+
+	m, ok := (*slot).(*table)
+	if !ok {
+		in.fail("the intrinsic %s needs a map", op)
+	}
+
+	switch op {
+	case ir.MapGet:
+		k := key(args[0], t)
+		value, found := m.get(k)
+		if !found && m.stringKeys {
+			in.fail("key \"%s\" not found in map", k.str)
+		} else if !found {
+			in.fail("key %d not found in map", k.num)
+		}
+		return copyValue(value)
+	case ir.MapSet:
+		m.set(key(args[0], t), args[1])
+		return nil
+	case ir.MapHas:
+		_, found := m.get(key(args[0], t))
+		return boolean(found)
+	case ir.MapDelete:
+		return boolean(m.delete(key(args[0], t)))
+	case ir.MapSlotUsed:
+		return boolean(m.slots[asInt(args[0])].state == slotFull)
+	case ir.MapSlotKey:
+		k := m.slots[asInt(args[0])].key
+		if m.stringKeys {
+			return k.str
+		}
+		return convert(k.num, ir.UnknownType, result)
+	}
+
+	in.fail("the intrinsic %s isn't implemented", op)
+	return nil
+}
+
+// list : Returns the list in slot
+func (in *Interpreter) list(slot *value) *list {
+	l, ok := (*slot).(*list)
+	if !ok {
+		in.fail("the intrinsic needs a list")
+	}
+
+	return l
+}
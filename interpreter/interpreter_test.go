@@ -0,0 +1,46 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+	"github.com/neutrino2211/Gecko/interpreter"
+)
+
+func TestPrograms(t *testing.T) {
+	for _, program := range compilertest.Programs {
+		for _, level := range []string{"0", "2"} {
+			out := &bytes.Buffer{}
+			in := interpreter.New(compilertest.Module(t, program.Source, map[string]string{"O": level}))
+			in.Stdout = out
+
+			status, err := in.Run([]string{"a"})
+			if err != nil {
+				t.Errorf("%s -O%s: %v", program.Name, level, err)
+			} else if out.String() != program.Output || status != program.Status {
+				t.Errorf("%s -O%s prints\n%s\nand exits with %d, want\n%s\nand %d", program.Name, level, out.String(), status, program.Output, program.Status)
+			}
+		}
+	}
+}
+
+func TestCallDepthLimit(t *testing.T) {
+	module := compilertest.Module(t, `package Main
+
+func down(n: int): int {
+    next := down(n: n + 1)
+    return next
+}
+
+func Main(): int {
+    deep := down(n: 0)
+    return deep
+}
+`, nil)
+
+	if _, err := interpreter.New(module).Run([]string{"a"}); err == nil || !strings.Contains(err.Error(), "stack overflow") {
+		t.Errorf("endless recursion stops with %v, want a stack overflow", err)
+	}
+}
@@ -0,0 +1,243 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/neutrino2211/Gecko/ir"
+)
+
+/*
+	libc
+
+	Programs call C functions they declare with `external func`. Without a C
+	compiler there is nothing to link them with, so the interpreter implements
+	the common ones itself. Arguments arrive as the interpreter's values, strings
+	already turned into Go strings, and functions return an int64, a float64, a
+	string or nil. Calling a C function that isn't in libc is a runtime error.
+*/
+
+// cFunction : A C function the interpreter provides
+type cFunction func(in *Interpreter, args []value) value
+
+// libc : The C functions programs can call without a C compiler
+var libc = map[string]cFunction{
+	"printf": func(in *Interpreter, args []value) value {
+		s := in.cFormat(stringArg(in, args, 0), args[1:])
+		in.out.WriteString(s)
+		return int64(len(s))
+	},
+	"puts": func(in *Interpreter, args []value) value {
+		in.out.WriteString(stringArg(in, args, 0) + "\n")
+		return int64(0)
+	},
+	"putchar": func(in *Interpreter, args []value) value {
+		in.out.WriteByte(byte(intArg(in, args, 0)))
+		return intArg(in, args, 0)
+	},
+	"getchar": func(in *Interpreter, args []value) value {
+		in.out.Flush()
+		c, err := in.in.ReadByte()
+		if err != nil {
+			return int64(-1)
+		}
+		return int64(c)
+	},
+	"strlen": func(in *Interpreter, args []value) value {
+		return int64(len(stringArg(in, args, 0)))
+	},
+	"strcmp": func(in *Interpreter, args []value) value {
+		return int64(strings.Compare(stringArg(in, args, 0), stringArg(in, args, 1)))
+	},
+	"atoi": func(in *Interpreter, args []value) value {
+		return int64(int32(leadingInt(stringArg(in, args, 0))))
+	},
+	"atol": func(in *Interpreter, args []value) value {
+		return leadingInt(stringArg(in, args, 0))
+	},
+	"abs": func(in *Interpreter, args []value) value {
+		n := int64(int32(intArg(in, args, 0)))
+		if n < 0 {
+			return truncate(-n, 32, true)
+		}
+		return n
+	},
+	"exit": func(in *Interpreter, args []value) value {
+		panic(exit{status: int(int32(intArg(in, args, 0)))})
+	},
+	"abort": func(in *Interpreter, args []value) value {
+		in.fail("abort was called")
+		return nil
+	},
+	"toupper": func(in *Interpreter, args []value) value {
+		return int64(unicode.ToUpper(rune(intArg(in, args, 0))))
+	},
+	"tolower": func(in *Interpreter, args []value) value {
+		return int64(unicode.ToLower(rune(intArg(in, args, 0))))
+	},
+	"sqrt":  mathFunction(math.Sqrt),
+	"fabs":  mathFunction(math.Abs),
+	"floor": mathFunction(math.Floor),
+	"ceil":  mathFunction(math.Ceil),
+	"sin":   mathFunction(math.Sin),
+	"cos":   mathFunction(math.Cos),
+	"tan":   mathFunction(math.Tan),
+	"exp":   mathFunction(math.Exp),
+	"log":   mathFunction(math.Log),
+	"pow": func(in *Interpreter, args []value) value {
+		return math.Pow(floatArg(in, args, 0), floatArg(in, args, 1))
+	},
+}
+
+func mathFunction(fn func(float64) float64) cFunction {
+	return func(in *Interpreter, args []value) value {
+		return fn(floatArg(in, args, 0))
+	}
+}
+
+func arg(in *Interpreter, args []value, i int) value {
+	if i >= len(args) {
+		in.fail("a C function was called with %d arguments, it needs at least %d", len(args), i+1)
+	}
+
+	return args[i]
+}
+
+func stringArg(in *Interpreter, args []value, i int) string {
+	s, ok := arg(in, args, i).(string)
+	if !ok {
+		in.fail("argument %d of a C function should be a string", i+1)
+	}
+
+	return s
+}
+
+func intArg(in *Interpreter, args []value, i int) int64 {
+	return asInt(arg(in, args, i))
+}
+
+func floatArg(in *Interpreter, args []value, i int) float64 {
+	switch n := arg(in, args, i).(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+
+	in.fail("argument %d of a C function should be a number", i+1)
+	return 0
+}
+
+// leadingInt : Reads the number s starts with like atoi, 0 if it doesn't start with one
+func leadingInt(s string) int64 {
+	s = strings.TrimLeft(s, " \t\n\v\f\r")
+	end := 0
+	if end < len(s) && (s[end] == '-' || s[end] == '+') {
+		end++
+	}
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+
+	n, _ := strconv.ParseInt(s[:end], 10, 64)
+	return n
+}
+
+// cFloat : Formats f with a C conversion, which names infinities and NaN differently from Go
+func cFloat(verb string, f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+
+	return fmt.Sprintf(verb, f)
+}
+
+// cFormat : Formats args the way printf formats them with format
+func (in *Interpreter) cFormat(format string, args []value) string {
+	r := strings.Builder{}
+	next := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			r.WriteByte(format[i])
+			continue
+		}
+
+		// %[flags][width][.precision][length]conversion
+		start := i
+		i++
+		for i < len(format) && strings.IndexByte("-+ #0", format[i]) >= 0 {
+			i++
+		}
+		for i < len(format) && (format[i] >= '0' && format[i] <= '9' || format[i] == '.') {
+			i++
+		}
+		spec := format[start:i]
+
+		length := ""
+		for i < len(format) && strings.IndexByte("hlzjtL", format[i]) >= 0 {
+			length += string(format[i])
+			i++
+		}
+
+		if i >= len(format) {
+			r.WriteString(format[start:])
+			break
+		} else if format[i] == '%' {
+			r.WriteByte('%')
+			continue
+		}
+
+		var v value
+		if next < len(args) {
+			v = args[next]
+		}
+		next++
+
+		switch conversion := format[i]; conversion {
+		case 'd', 'i':
+			r.WriteString(fmt.Sprintf(spec+"d", sized(asInt(v), length, true)))
+		case 'u', 'x', 'X', 'o':
+			verb := map[byte]string{'u': "d", 'x': "x", 'X': "X", 'o': "o"}[conversion]
+			r.WriteString(fmt.Sprintf(spec+verb, uint64(sized(asInt(v), length, false))))
+		case 'c':
+			r.WriteString(fmt.Sprintf(strings.Replace(spec, "0", "", -1)+"s", string([]byte{byte(asInt(v))})))
+		case 's':
+			s, _ := v.(string)
+			r.WriteString(fmt.Sprintf(spec+"s", s))
+		case 'f', 'F', 'e', 'E', 'g', 'G':
+			verb := spec + string(conversion)
+			if !strings.Contains(spec, ".") && (conversion == 'g' || conversion == 'G') {
+				// Go's %g is as precise as needed, C's has 6 digits unless it is told otherwise
+				verb = spec + ".6" + string(conversion)
+			}
+			r.WriteString(cFloat(verb, asFloat(v, ir.UnknownType)))
+		default:
+			in.fail("printf's %%%c conversion isn't available without a C compiler", conversion)
+		}
+	}
+
+	return r.String()
+}
+
+// sized : Truncates n to the integer a printf length modifier reads
+func sized(n int64, length string, signed bool) int64 {
+	switch length {
+	case "hh":
+		return truncate(n, 8, signed)
+	case "h":
+		return truncate(n, 16, signed)
+	case "":
+		return truncate(n, 32, signed)
+	}
+
+	return n
+}
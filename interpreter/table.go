@@ -0,0 +1,174 @@
+package interpreter
+
+import (
+	"github.com/neutrino2211/Gecko/ir"
+)
+
+/*
+	Maps
+
+	Maps are the open addressing hash table of the runtime, with the same hash,
+	probing and growth. Loops over a map walk its slots, so keeping the layout
+	the same keeps the order keys are visited in the same as in built programs.
+	Like the runtime's maps, copies of a map refer to the same table.
+*/
+
+// The states of a slot
+const (
+	slotEmpty = iota
+	slotFull
+	slotDeleted
+)
+
+// mapKey : A key of a map, str is used by maps with string keys and num by the others
+type mapKey struct {
+	str string
+	num int64
+}
+
+type slot struct {
+	key   mapKey
+	state int
+}
+
+// table : A gecko map
+type table struct {
+	slots      []slot
+	values     []value
+	len        int
+	used       int
+	stringKeys bool
+}
+
+func newTable(t *ir.Type) *table {
+	return &table{stringKeys: t.Key.Kind == ir.String}
+}
+
+// key : Returns v as a key of maps of type t
+func key(v value, t *ir.Type) mapKey {
+	if t.Key.Kind == ir.String {
+		s, _ := v.(string)
+		return mapKey{str: s}
+	}
+
+	return mapKey{num: asInt(v)}
+}
+
+func (m *table) hash(k mapKey) uint64 {
+	hash := uint64(14695981039346656037)
+
+	if m.stringKeys {
+		for i := 0; i < len(k.str) && k.str[i] != 0; i++ {
+			hash ^= uint64(k.str[i])
+			hash *= 1099511628211
+		}
+		return hash
+	}
+
+	// The bytes of a long long, least significant first
+	n := uint64(k.num)
+	for i := 0; i < 8; i++ {
+		hash ^= n >> (8 * uint(i)) & 0xff
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+// find : Returns the slot holding k, or the slot k should be inserted into
+func (m *table) find(k mapKey) (int, bool) {
+	capacity := len(m.slots)
+	mask := capacity - 1
+	index := int(m.hash(k) & uint64(mask))
+	tombstone := capacity
+
+	for i := 0; i < capacity; i++ {
+		s := &m.slots[index]
+
+		if s.state == slotEmpty {
+			if tombstone != capacity {
+				return tombstone, false
+			}
+			return index, false
+		} else if s.state == slotDeleted {
+			if tombstone == capacity {
+				tombstone = index
+			}
+		} else if s.key == k {
+			return index, true
+		}
+
+		index = (index + 1) & mask
+	}
+
+	return tombstone, false
+}
+
+func (m *table) resize(capacity int) {
+	old := *m
+	m.slots = make([]slot, capacity)
+	m.values = make([]value, capacity)
+	m.len = 0
+	m.used = 0
+
+	for i, s := range old.slots {
+		if s.state == slotFull {
+			index, _ := m.find(s.key)
+			m.slots[index] = s
+			m.values[index] = old.values[i]
+			m.len++
+			m.used++
+		}
+	}
+}
+
+func (m *table) set(k mapKey, v value) {
+	capacity := len(m.slots)
+	if (m.used+1)*4 > capacity*3 {
+		if capacity == 0 {
+			m.resize(8)
+		} else if m.len*2 >= capacity {
+			m.resize(capacity * 2)
+		} else {
+			m.resize(capacity)
+		}
+	}
+
+	index, found := m.find(k)
+	if !found {
+		if m.slots[index].state == slotEmpty {
+			m.used++
+		}
+
+		m.slots[index] = slot{key: k, state: slotFull}
+		m.len++
+	}
+
+	m.values[index] = v
+}
+
+func (m *table) get(k mapKey) (value, bool) {
+	if len(m.slots) == 0 {
+		return nil, false
+	}
+
+	index, found := m.find(k)
+	if !found {
+		return nil, false
+	}
+	return m.values[index], true
+}
+
+func (m *table) delete(k mapKey) bool {
+	if len(m.slots) == 0 {
+		return false
+	}
+
+	index, found := m.find(k)
+	if !found {
+		return false
+	}
+
+	m.slots[index].state = slotDeleted
+	m.len--
+	return true
+}
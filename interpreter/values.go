@@ -0,0 +1,398 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/neutrino2211/Gecko/ir"
+)
+
+/*
+	Values
+
+	Integers and bools are int64s, unsigned integers keep their bits in one,
+	floats are float64s and strings are Go strings. Lists and maps are handles
+	like the runtime's, copies of them refer to the same items. Instances of
+	classes are objects that are copied whenever they are read, C structs are
+	values.
+
+	Arithmetic follows C, operands narrower than an int are promoted to one
+	first and the result has the type the operands were converted to, it is
+	only truncated to the type of a variable when it is stored.
+*/
+
+type value interface{}
+
+// list : A gecko list, cap grows the way the runtime's does
+type list struct {
+	items []value
+	cap   int
+}
+
+func (l *list) push(v value) {
+	if len(l.items) == l.cap {
+		if l.cap == 0 {
+			l.cap = 8
+		} else {
+			l.cap *= 2
+		}
+	}
+
+	l.items = append(l.items, v)
+}
+
+// object : An instance of a class
+type object struct {
+	s      *ir.Struct
+	fields []value
+}
+
+// field : Returns the index of the field called name
+func (o *object) field(name string) int {
+	for i, f := range o.s.Fields {
+		if f.Name == name {
+			return i
+		}
+	}
+
+	panic(&Error{Message: o.s.GeckoName + " has no field '" + name + "'"})
+}
+
+// externs : The C symbols programs can read without a C compiler
+var externs = map[string]value{
+	"NULL":         nil,
+	"EOF":          int64(-1),
+	"EXIT_SUCCESS": int64(0),
+	"EXIT_FAILURE": int64(1),
+	"INT_MAX":      int64(math.MaxInt32),
+	"INT_MIN":      int64(math.MinInt32),
+}
+
+// zero : Returns the value a variable of type t starts with
+func zero(t *ir.Type) value {
+	switch t.Kind {
+	case ir.Bool, ir.Int:
+		return int64(0)
+	case ir.Float:
+		return float64(0)
+	case ir.String:
+		return ""
+	case ir.List:
+		return &list{}
+	case ir.Map:
+		return newTable(t)
+	case ir.Class:
+		o := &object{s: t.Struct}
+		for _, f := range t.Struct.Fields {
+			o.fields = append(o.fields, zero(f.Type))
+		}
+		return o
+	}
+
+	return nil
+}
+
+func constant(c *ir.Const) value {
+	switch c.T.Kind {
+	case ir.Float:
+		return c.Float
+	case ir.String:
+		return c.Str
+	}
+
+	return c.Int
+}
+
+// copyValue : Copies the objects in v
+func copyValue(v value) value {
+	o, ok := v.(*object)
+	if !ok {
+		return v
+	}
+
+	c := &object{s: o.s}
+	for _, f := range o.fields {
+		c.fields = append(c.fields, copyValue(f))
+	}
+	return c
+}
+
+func boolean(b bool) value {
+	if b {
+		return int64(1)
+	}
+
+	return int64(0)
+}
+
+func truthy(v value) bool {
+	switch v := v.(type) {
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case nil:
+		return false
+	}
+
+	return true
+}
+
+// truncate : Wraps n around to an integer of bits bits, integers of unknown size are left alone
+func truncate(n int64, bits uint, signed bool) int64 {
+	if bits == 0 || bits >= 64 {
+		return n
+	} else if signed {
+		shift := 64 - bits
+		return n << shift >> shift
+	}
+
+	return n & (1<<bits - 1)
+}
+
+// unsigned64 : Checks if the bits of integers of type t are read as an unsigned 64 bit number
+func unsigned64(t *ir.Type) bool {
+	return t.Kind == ir.Int && !t.Signed && t.Bits == 64
+}
+
+// convert : Converts v of type from to type to, the way assigning or casting it in C does
+func convert(v value, from *ir.Type, to *ir.Type) value {
+	switch n := v.(type) {
+	case int64:
+		switch to.Kind {
+		case ir.Int:
+			return truncate(n, to.Bits, to.Signed)
+		case ir.Bool:
+			return boolean(n != 0)
+		case ir.Float:
+			f := float64(n)
+			if unsigned64(from) {
+				f = float64(uint64(n))
+			}
+			return toFloat(f, to)
+		}
+	case float64:
+		switch to.Kind {
+		case ir.Int:
+			if !to.Signed && n >= math.MaxInt64 {
+				return truncate(int64(uint64(n)), to.Bits, to.Signed)
+			}
+			return truncate(int64(n), to.Bits, to.Signed)
+		case ir.Bool:
+			return boolean(n != 0)
+		case ir.Float:
+			return toFloat(n, to)
+		}
+	}
+
+	return v
+}
+
+// toFloat : Rounds f to the precision of floats of type t
+func toFloat(f float64, t *ir.Type) float64 {
+	if t.Bits == 32 {
+		return float64(float32(f))
+	}
+
+	return f
+}
+
+// promote : Returns the size and signedness C computes integers of types x and y in
+func promote(x *ir.Type, y *ir.Type) (uint, bool) {
+	xb, xs := promoteOne(x)
+	yb, ys := promoteOne(y)
+
+	switch {
+	case xb > yb:
+		return xb, xs
+	case yb > xb:
+		return yb, ys
+	}
+
+	return xb, xs && ys
+}
+
+func promoteOne(t *ir.Type) (uint, bool) {
+	if t.Kind != ir.Int || t.Bits == 0 {
+		// Bools and integers of unknown size, like those returned by C functions
+		if t.Kind == ir.Opaque {
+			return 64, true
+		}
+		return 32, true
+	} else if t.Bits < 32 {
+		return 32, true
+	}
+
+	return t.Bits, t.Signed
+}
+
+func asFloat(v value, t *ir.Type) float64 {
+	switch n := v.(type) {
+	case int64:
+		if unsigned64(t) {
+			return float64(uint64(n))
+		}
+		return float64(n)
+	case float64:
+		return n
+	}
+
+	return 0
+}
+
+func asInt(v value) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+
+	return 0
+}
+
+func (in *Interpreter) unary(op string, x value, t *ir.Type) value {
+	if op == "!" {
+		return boolean(!truthy(x))
+	}
+
+	if f, ok := x.(float64); ok {
+		if op == "-" {
+			return toFloat(-f, t)
+		}
+		return f
+	}
+
+	bits, signed := promote(t, t)
+	n := truncate(asInt(x), bits, signed)
+	if op == "-" {
+		return truncate(-n, bits, signed)
+	}
+	return n
+}
+
+func (in *Interpreter) binary(op string, x value, xt *ir.Type, y value, yt *ir.Type, t *ir.Type) value {
+	xs, xString := x.(string)
+	ys, yString := y.(string)
+	if xString && yString {
+		if op == "+" {
+			return xs + ys
+		}
+		return in.order(op, strings.Compare(xs, ys))
+	}
+
+	_, xFloat := x.(float64)
+	_, yFloat := y.(float64)
+	if xFloat || yFloat {
+		a, b := asFloat(x, xt), asFloat(y, yt)
+		switch op {
+		case "+":
+			return toFloat(a+b, t)
+		case "-":
+			return toFloat(a-b, t)
+		case "*":
+			return toFloat(a*b, t)
+		case "/":
+			return toFloat(a/b, t)
+		case "==":
+			return boolean(a == b)
+		case "!=":
+			return boolean(a != b)
+		case "<":
+			return boolean(a < b)
+		case "<=":
+			return boolean(a <= b)
+		case ">":
+			return boolean(a > b)
+		case ">=":
+			return boolean(a >= b)
+		}
+	}
+
+	_, xInt := x.(int64)
+	_, yInt := y.(int64)
+	if !xInt || !yInt {
+		// Pointers and other values only compare
+		switch op {
+		case "==":
+			return boolean(x == y)
+		case "!=":
+			return boolean(x != y)
+		}
+		in.fail("'%s' can't be used on %s and %s", op, xt, yt)
+	}
+
+	bits, signed := promote(xt, yt)
+	a, b := truncate(x.(int64), bits, signed), truncate(y.(int64), bits, signed)
+	switch op {
+	case "+":
+		return truncate(a+b, bits, signed)
+	case "-":
+		return truncate(a-b, bits, signed)
+	case "*":
+		return truncate(a*b, bits, signed)
+	case "/":
+		if b == 0 {
+			in.fail("division by zero")
+		} else if !signed {
+			return truncate(int64(uint64(a)/uint64(b)), bits, signed)
+		} else if a == math.MinInt64 && b == -1 {
+			return a
+		}
+		return truncate(a/b, bits, signed)
+	}
+
+	if !signed {
+		if uint64(a) < uint64(b) {
+			return in.order(op, -1)
+		} else if uint64(a) > uint64(b) {
+			return in.order(op, 1)
+		}
+		return in.order(op, 0)
+	}
+
+	if a < b {
+		return in.order(op, -1)
+	} else if a > b {
+		return in.order(op, 1)
+	}
+	return in.order(op, 0)
+}
+
+// order : Turns the result of comparing two values, negative, zero or positive, into the value of op
+func (in *Interpreter) order(op string, r int) value {
+	switch op {
+	case "==":
+		return boolean(r == 0)
+	case "!=":
+		return boolean(r != 0)
+	case "<":
+		return boolean(r < 0)
+	case "<=":
+		return boolean(r <= 0)
+	case ">":
+		return boolean(r > 0)
+	case ">=":
+		return boolean(r >= 0)
+	}
+
+	in.fail("'%s' isn't an operator", op)
+	return nil
+}
+
+// formatArg : Formats a value interpolated into a string like the generated C does
+func formatArg(v value, t *ir.Type) string {
+	switch {
+	case t.Kind == ir.String:
+		s, _ := v.(string)
+		return s
+	case t.Kind == ir.Float:
+		return cFloat("%f", asFloat(v, t))
+	case t.GeckoName == "char" || t.GeckoName == "byte" || t.GeckoName == "u8":
+		return string([]byte{byte(asInt(v))})
+	}
+
+	// %d reads an int
+	return fmt.Sprint(int32(asInt(v)))
+}
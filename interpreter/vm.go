@@ -0,0 +1,236 @@
+package interpreter
+
+import (
+	"io"
+	"os"
+
+	"github.com/neutrino2211/Gecko/bytecode"
+	"github.com/neutrino2211/Gecko/ir"
+)
+
+/*
+	Virtual machine
+
+	The VM runs programs compiled to bytecode. Values are the interpreter's,
+	so arithmetic, lists, maps and the C functions programs can call behave
+	the same whether a program is run from its source or from a .gbc file.
+	Functions are decoded once, before the program starts, and every call
+	gets a stack of its own.
+*/
+
+// VM : Runs a bytecode program
+type VM struct {
+	Stdout io.Writer
+	Stdin  io.Reader
+
+	in      Interpreter
+	program *bytecode.Program
+	code    [][]bytecode.Instruction
+	globals []value
+}
+
+// reference : Where a variable or field is stored, addr instructions push them
+type reference *value
+
+// NewVM : Creates a VM for p that uses the standard streams of the process, it fails if the code of p doesn't decode
+func NewVM(p *bytecode.Program) (*VM, error) {
+	vm := &VM{Stdout: os.Stdout, Stdin: os.Stdin, program: p}
+
+	for _, f := range p.Functions {
+		code, err := f.Instructions(p)
+		if err != nil {
+			return nil, err
+		}
+
+		// Jumps go to the index of the instruction at their target from now on
+		indexes := map[int]int{}
+		for i, instr := range code {
+			indexes[instr.Offset] = i
+		}
+		for _, instr := range code {
+			if instr.Op == bytecode.OpJump || instr.Op == bytecode.OpJumpIfFalse {
+				instr.Operands[0] = indexes[instr.Operands[0]]
+			}
+		}
+
+		vm.code = append(vm.code, code)
+	}
+
+	return vm, nil
+}
+
+// Run : Runs the program's Main with args, the program's name included, and returns the status the program exits with
+func (vm *VM) Run(args []string) (int, error) {
+	p := vm.program
+	if p.Main < 0 {
+		return 1, &Error{Message: "package " + p.Name + " has no Main function"}
+	}
+
+	vm.in.Stdout, vm.in.Stdin = vm.Stdout, vm.Stdin
+	return vm.in.start(func() int {
+		vm.globals = []value{}
+		for _, g := range p.Globals {
+			vm.globals = append(vm.globals, zero(g.Type))
+		}
+		if p.Init >= 0 {
+			vm.call(p.Init, nil)
+		}
+
+		main := p.Functions[p.Main]
+		return status(main.Result, vm.call(p.Main, mainArgs(main.Vars[:main.Params], args)))
+	})
+}
+
+func (vm *VM) call(index int, args []value) value {
+	f := vm.program.Functions[index]
+	code := vm.code[index]
+	in := &vm.in
+	in.enter(f.Name)
+	defer in.leave()
+
+	vars := make([]value, len(f.Vars))
+	copy(vars, args)
+	for i := len(args); i < len(vars); i++ {
+		vars[i] = zero(f.Vars[i].Type)
+	}
+
+	stack := []value{}
+	pop := func() value {
+		if len(stack) == 0 {
+			in.fail("%s pops from an empty stack", f.Name)
+		}
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+	popN := func(n int) []value {
+		if n > len(stack) {
+			in.fail("%s pops %d values from a stack of %d", f.Name, n, len(stack))
+		}
+		r := append([]value{}, stack[len(stack)-n:]...)
+		stack = stack[:len(stack)-n]
+		return r
+	}
+	popReference := func() reference {
+		r, ok := pop().(reference)
+		if !ok {
+			in.fail("%s stores into something that isn't a variable", f.Name)
+		}
+		return r
+	}
+
+	p := vm.program
+	for pc := 0; pc < len(code); {
+		i := &code[pc]
+		pc++
+		in.pos = i.Pos
+		operands := i.Operands
+
+		switch i.Op {
+		case bytecode.OpConst:
+			stack = append(stack, constant(p.Constants[operands[0]]))
+		case bytecode.OpNull:
+			stack = append(stack, nil)
+		case bytecode.OpLoad:
+			stack = append(stack, copyValue(vars[operands[0]]))
+		case bytecode.OpLoadGlobal:
+			stack = append(stack, copyValue(vm.globals[operands[0]]))
+		case bytecode.OpExtern:
+			name := p.Strings[operands[0]]
+			c, ok := externs[name]
+			if !ok {
+				in.fail("the C symbol '%s' isn't available when running without a C compiler", name)
+			}
+			stack = append(stack, c)
+		case bytecode.OpAddr:
+			stack = append(stack, reference(&vars[operands[0]]))
+		case bytecode.OpAddrGlobal:
+			stack = append(stack, reference(&vm.globals[operands[0]]))
+		case bytecode.OpAddrField:
+			name := p.Strings[operands[0]]
+			o, ok := (*popReference()).(*object)
+			if !ok {
+				in.fail("'%s' can't be assigned to", name)
+			}
+			stack = append(stack, reference(&o.fields[o.field(name)]))
+		case bytecode.OpStore:
+			r := popReference()
+			*r = pop()
+		case bytecode.OpField:
+			stack = append(stack, in.member(pop(), p.Strings[operands[0]]))
+		case bytecode.OpUnary:
+			stack = append(stack, in.unary(p.Strings[operands[0]], pop(), p.Types[operands[1]]))
+		case bytecode.OpBinary:
+			y := pop()
+			x := pop()
+			stack = append(stack, in.binary(p.Strings[operands[0]], x, p.Types[operands[1]], y, p.Types[operands[2]], p.Types[operands[3]]))
+		case bytecode.OpConvert:
+			stack = append(stack, convert(pop(), p.Types[operands[0]], p.Types[operands[1]]))
+		case bytecode.OpFormat:
+			args := popN(operands[0])
+			r := p.Strings[operands[1]]
+			for j, arg := range args {
+				r += formatArg(arg, p.Types[operands[2+2*j]]) + p.Strings[operands[3+2*j]]
+			}
+			stack = append(stack, r)
+		case bytecode.OpCall:
+			callee := p.Functions[operands[0]]
+			args := popN(callee.Params)
+			pos := in.pos
+			stack = append(stack, vm.call(operands[0], args))
+			in.pos = pos
+		case bytecode.OpCallC:
+			name := p.Strings[operands[0]]
+			fn := libc[name]
+			if fn == nil {
+				in.fail("the C function '%s' isn't available when running without a C compiler", name)
+			}
+			stack = append(stack, fn(in, popN(operands[1])))
+		case bytecode.OpIntrinsic:
+			args := popN(operands[3])
+			slot := popReference()
+			stack = append(stack, in.runIntrinsic(ir.Op(p.Strings[operands[0]]), p.Types[operands[1]], p.Types[operands[2]], slot, args))
+		case bytecode.OpList:
+			l := &list{}
+			for _, item := range popN(operands[1]) {
+				l.push(item)
+			}
+			stack = append(stack, l)
+		case bytecode.OpMap:
+			t := p.Types[operands[0]]
+			pairs := popN(2 * operands[1])
+			m := newTable(t)
+			for j := 0; j < len(pairs); j += 2 {
+				m.set(key(pairs[j], t), pairs[j+1])
+			}
+			stack = append(stack, m)
+		case bytecode.OpStruct:
+			t := p.Types[operands[0]]
+			if t.Kind != ir.Class {
+				in.fail("%s isn't a class", t)
+			}
+			o := zero(t).(*object)
+			for j, v := range popN(operands[1]) {
+				o.fields[o.field(p.Strings[operands[2+j]])] = v
+			}
+			stack = append(stack, o)
+		case bytecode.OpPop:
+			pop()
+		case bytecode.OpJump:
+			pc = operands[0]
+		case bytecode.OpJumpIfFalse:
+			if !truthy(pop()) {
+				pc = operands[0]
+			}
+		case bytecode.OpReturn:
+			return pop()
+		case bytecode.OpReturnVoid:
+			return nil
+		case bytecode.OpUnreachable:
+			in.fail("%s reached code that can't be reached", f.Name)
+		}
+	}
+
+	in.fail("%s ran past the end of its code", f.Name)
+	return nil
+}
@@ -0,0 +1,70 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/bytecode"
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+	"github.com/neutrino2211/Gecko/interpreter"
+)
+
+// vm : Compiles source to bytecode and loads it the way `gecko run a.gbc` does
+func vm(t *testing.T, source string, options map[string]string) *interpreter.VM {
+	t.Helper()
+
+	p, err := bytecode.Compile(compilertest.Module(t, source, options))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The VM only runs programs that went through the file format
+	decoded, err := bytecode.Decode(p.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm, err := interpreter.NewVM(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return vm
+}
+
+func TestVMPrograms(t *testing.T) {
+	for _, program := range compilertest.Programs {
+		for _, level := range []string{"0", "2"} {
+			out := &bytes.Buffer{}
+			vm := vm(t, program.Source, map[string]string{"O": level})
+			vm.Stdout = out
+
+			status, err := vm.Run([]string{"a"})
+			if err != nil {
+				t.Errorf("%s -O%s: %v", program.Name, level, err)
+			} else if out.String() != program.Output || status != program.Status {
+				t.Errorf("%s -O%s prints\n%s\nand exits with %d, want\n%s\nand %d", program.Name, level, out.String(), status, program.Output, program.Status)
+			}
+		}
+	}
+}
+
+func TestVMCallDepthLimit(t *testing.T) {
+	vm := vm(t, `package Main
+
+func down(n: int): int {
+    next := down(n: n + 1)
+    return next
+}
+
+func Main(): int {
+    deep := down(n: 0)
+    return deep
+}
+`, nil)
+
+	if _, err := vm.Run([]string{"a"}); err == nil || !strings.Contains(err.Error(), "stack overflow") {
+		t.Errorf("endless recursion stops with %v, want a stack overflow", err)
+	}
+}
@@ -0,0 +1,347 @@
+package ir
+
+import (
+	"math"
+	"strings"
+)
+
+/*
+	Constants
+
+	Folding computes operators whose operands are constants the way the C code
+	they become would, so folded and unfolded programs print the same thing.
+	Anything where that isn't certain is left for the C compiler: integer
+	arithmetic narrower than an int, which C promotes first, 32 bit floats,
+	division by zero and conversions that overflow.
+*/
+
+// constants : What is known about the variables of a function at one point of it. A variable
+// missing from the map may hold anything
+type constants map[*Variable]*Const
+
+func (c constants) copy() constants {
+	r := constants{}
+	for v, value := range c {
+		r[v] = value
+	}
+
+	return r
+}
+
+func (c constants) equal(o constants) bool {
+	if len(c) != len(o) {
+		return false
+	}
+
+	for v, value := range c {
+		if other := o[v]; other == nil || !sameConst(value, other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// meet : Keeps the variables o knows the same value of
+func (c constants) meet(o constants) {
+	for v, value := range c {
+		if other := o[v]; other == nil || !sameConst(value, other) {
+			delete(c, v)
+		}
+	}
+}
+
+func sameConst(a *Const, b *Const) bool {
+	return sameType(a.T, b.T) && a.Int == b.Int && a.Float == b.Float && a.Str == b.Str
+}
+
+// sameType : Checks if a and b are the same scalar type, types are built anew wherever they are written
+func sameType(a *Type, b *Type) bool {
+	return a == b || a.Kind == b.Kind && a.Name == b.Name && a.Kind != Opaque
+}
+
+// propagatable : Checks if constants of type t are worth propagating
+func propagatable(t *Type) bool {
+	return t.Kind == Bool || t.Kind == Int || t.Kind == Float || t.Kind == String
+}
+
+// known : Returns the constant v holds, if it is one
+func (c constants) known(v *Variable) *Const {
+	if value := c[v]; value != nil {
+		return value
+	}
+
+	// Variables declared constant keep the value they were initialised with
+	if v.Const && v.Value != nil && propagatable(v.Type) {
+		if value := c.evaluate(v.Value); value != nil {
+			return convertConst(value, v.Type)
+		}
+	}
+
+	return nil
+}
+
+// evaluate : Computes v if all of it is known to be constant, without changing it
+func (c constants) evaluate(v Value) *Const {
+	switch v := v.(type) {
+	case *Const:
+		return v
+	case *Ref:
+		return c.known(v.Var)
+	case *Unary:
+		if x := c.evaluate(v.X); x != nil {
+			return foldUnary(v.Op, x, v.T)
+		}
+	case *Binary:
+		if x, y := c.evaluate(v.X), c.evaluate(v.Y); x != nil && y != nil {
+			return foldBinary(v.Op, x, y, v.T)
+		}
+	case *Cast:
+		if x := c.evaluate(v.X); x != nil {
+			return convertConst(x, v.T)
+		}
+	case *Format:
+		r := v.Parts[0]
+		for i, arg := range v.Args {
+			a := c.evaluate(arg)
+			if a == nil || a.T.Kind != String {
+				return nil
+			}
+			r += a.Str + v.Parts[i+1]
+		}
+		return StringConst(r)
+	}
+
+	return nil
+}
+
+// fold : Replaces v with its value if it can be computed now, the operands of v were folded already
+func fold(v Value) Value {
+	switch v := v.(type) {
+	case *Unary, *Binary, *Cast:
+		if c := (constants{}).evaluate(v); c != nil {
+			return c
+		}
+	case *Format:
+		return foldFormat(v)
+	}
+
+	return v
+}
+
+// foldFormat : Moves the constant strings formatted into the parts around them
+func foldFormat(v *Format) Value {
+	parts := []string{v.Parts[0]}
+	args := []Value{}
+
+	for i, arg := range v.Args {
+		if c, ok := arg.(*Const); ok && c.T.Kind == String {
+			parts[len(parts)-1] += c.Str + v.Parts[i+1]
+			continue
+		}
+
+		args = append(args, arg)
+		parts = append(parts, v.Parts[i+1])
+	}
+
+	if len(args) == 0 {
+		return StringConst(parts[0])
+	} else if len(args) == len(v.Args) {
+		return v
+	}
+
+	return &Format{Parts: parts, Args: args}
+}
+
+func foldUnary(op string, x *Const, t *Type) *Const {
+	switch {
+	case op == "+" && sameType(x.T, t):
+		return x
+	case op == "!" && x.T.Kind == Bool:
+		return BoolConst(x.Int == 0)
+	case op == "-" && x.T.Kind == Int && foldsIntegers(t) && sameType(x.T, t):
+		return IntConst(t, wrapInt(-x.Int, t))
+	case op == "-" && x.T.Kind == Float && foldsFloats(t):
+		return &Const{T: t, Float: -x.Float}
+	}
+
+	return nil
+}
+
+func foldBinary(op string, x *Const, y *Const, t *Type) *Const {
+	switch {
+	case x.T.Kind == String && y.T.Kind == String:
+		if op == "+" {
+			return StringConst(x.Str + y.Str)
+		}
+		return compare(op, strings.Compare(x.Str, y.Str))
+	case t.Kind == Bool && x.T.Kind == Bool && y.T.Kind == Bool:
+		return compare(op, int(x.Int-y.Int))
+	case t.Kind == Bool && (x.T.Kind == Float || y.T.Kind == Float) && x.T.IsNumeric() && y.T.IsNumeric():
+		a, b := toFloat(x), toFloat(y)
+		if a < b {
+			return compare(op, -1)
+		} else if a > b {
+			return compare(op, 1)
+		} else if a == b {
+			return compare(op, 0)
+		}
+	case t.Kind == Bool && x.T.Kind == Int && y.T.Kind == Int && comparable(x.T, y.T):
+		if !x.T.Signed && !y.T.Signed {
+			a, b := uint64(x.Int), uint64(y.Int)
+			if a < b {
+				return compare(op, -1)
+			} else if a > b {
+				return compare(op, 1)
+			}
+			return compare(op, 0)
+		}
+		if x.Int < y.Int {
+			return compare(op, -1)
+		} else if x.Int > y.Int {
+			return compare(op, 1)
+		}
+		return compare(op, 0)
+	case foldsFloats(t) && x.T.IsNumeric() && y.T.IsNumeric():
+		a, b := toFloat(x), toFloat(y)
+		switch op {
+		case "+":
+			return &Const{T: t, Float: a + b}
+		case "-":
+			return &Const{T: t, Float: a - b}
+		case "*":
+			return &Const{T: t, Float: a * b}
+		case "/":
+			if b != 0 {
+				return &Const{T: t, Float: a / b}
+			}
+		}
+	case foldsIntegers(t) && convertsTo(x.T, t) && convertsTo(y.T, t):
+		a, b := wrapInt(x.Int, t), wrapInt(y.Int, t)
+		switch op {
+		case "+":
+			return IntConst(t, wrapInt(a+b, t))
+		case "-":
+			return IntConst(t, wrapInt(a-b, t))
+		case "*":
+			return IntConst(t, wrapInt(a*b, t))
+		case "/":
+			if b == 0 {
+				return nil
+			} else if !t.Signed {
+				return IntConst(t, wrapInt(int64(uint64(a)/uint64(b)), t))
+			} else if a == math.MinInt64 && b == -1 {
+				return nil
+			}
+			return IntConst(t, wrapInt(a/b, t))
+		}
+	}
+
+	return nil
+}
+
+// compare : Turns the result of comparing two constants, negative, zero or positive, into the value of op
+func compare(op string, order int) *Const {
+	switch op {
+	case "==":
+		return BoolConst(order == 0)
+	case "!=":
+		return BoolConst(order != 0)
+	case "<":
+		return BoolConst(order < 0)
+	case "<=":
+		return BoolConst(order <= 0)
+	case ">":
+		return BoolConst(order > 0)
+	case ">=":
+		return BoolConst(order >= 0)
+	}
+
+	return nil
+}
+
+// convertConst : Converts c to t the way a C cast or assignment would
+func convertConst(c *Const, t *Type) *Const {
+	switch {
+	case sameType(c.T, t):
+		return c
+	case t.Kind == Bool && (c.T.Kind == Int || c.T.Kind == Bool):
+		return BoolConst(c.Int != 0)
+	case t.Kind == Int && t.Bits > 0 && (c.T.Kind == Int || c.T.Kind == Bool):
+		return IntConst(t, wrapInt(c.Int, t))
+	case t.Kind == Int && t.Bits > 0 && c.T.Kind == Float:
+		// Values that don't fit t are undefined in C
+		if f := math.Trunc(c.Float); f > -1<<63 && f < 1<<63 && (t.Signed || f >= 0) && wrapInt(int64(f), t) == int64(f) {
+			return IntConst(t, int64(f))
+		}
+	case foldsFloats(t) && c.T.IsNumeric():
+		return &Const{T: t, Float: toFloat(c)}
+	}
+
+	return nil
+}
+
+// convertsTo : Checks if C computes an integer of type from as one of type to, to being the type of
+// the arithmetic
+func convertsTo(from *Type, to *Type) bool {
+	if from.Kind != Int || from.Bits == 0 {
+		return false
+	}
+
+	return sameType(from, to) || from.Bits < to.Bits || from.Bits == to.Bits && (from.Signed || !to.Signed)
+}
+
+// comparable : Checks if two integers compare the same in C and Go, mixing signed and unsigned ones
+// of the same size converts the signed one
+func comparable(a *Type, b *Type) bool {
+	if a.Bits == 0 || b.Bits == 0 {
+		return false
+	} else if a.Signed == b.Signed {
+		return true
+	} else if a.Signed {
+		a, b = b, a
+	}
+
+	// a is unsigned, it is only promoted to a signed type wider than it
+	return a.Bits < b.Bits || a.Bits < 32
+}
+
+// foldsIntegers : Checks if arithmetic of type t can be folded, C computes narrower integers as ints
+func foldsIntegers(t *Type) bool {
+	return t.Kind == Int && t.Bits >= 32
+}
+
+// foldsFloats : Checks if arithmetic of type t can be folded, only doubles are computed like Go does
+func foldsFloats(t *Type) bool {
+	return t.Kind == Float && t.Bits == 64
+}
+
+func toFloat(c *Const) float64 {
+	if c.T.Kind == Float {
+		return c.Float
+	} else if c.T.Kind == Int && !c.T.Signed && c.T.Bits == 64 {
+		return float64(uint64(c.Int))
+	}
+
+	return float64(c.Int)
+}
+
+// wrapInt : Truncates n to the bits of t
+func wrapInt(n int64, t *Type) int64 {
+	switch {
+	case t.Bits == 8 && t.Signed:
+		return int64(int8(n))
+	case t.Bits == 8:
+		return int64(uint8(n))
+	case t.Bits == 16 && t.Signed:
+		return int64(int16(n))
+	case t.Bits == 16:
+		return int64(uint16(n))
+	case t.Bits == 32 && t.Signed:
+		return int64(int32(n))
+	case t.Bits == 32:
+		return int64(uint32(n))
+	}
+
+	return n
+}
@@ -0,0 +1,85 @@
+package ir
+
+import (
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Instr : An instruction inside a block
+type Instr interface {
+	Position() lexer.Position
+}
+
+// Terminator : The instruction that ends a block
+type Terminator interface {
+	Instr
+	Successors() []*Block
+}
+
+// Assign : Stores Value in Dest, which is a Ref or a Member of one
+type Assign struct {
+	Pos   lexer.Position
+	Dest  Value
+	Value Value
+}
+
+// Eval : Computes Value for what it does, like calling a function
+type Eval struct {
+	Pos   lexer.Position
+	Value Value
+}
+
+// Jump : Continues in Target
+type Jump struct {
+	Pos    lexer.Position
+	Target *Block
+}
+
+// Branch : Continues in Then if Cond is true and in Else if it isn't
+type Branch struct {
+	Pos  lexer.Position
+	Cond Value
+	Then *Block
+	Else *Block
+}
+
+// Return : Leaves the function, with Value unless it returns void
+type Return struct {
+	Pos   lexer.Position
+	Value Value
+}
+
+// Unreachable : Ends blocks no execution gets to the end of, like the block after an if whose
+// branches all return
+type Unreachable struct {
+	Pos lexer.Position
+}
+
+// Position : Returns where the instruction is in the gecko source
+func (i *Assign) Position() lexer.Position { return i.Pos }
+
+// Position : Returns where the instruction is in the gecko source
+func (i *Eval) Position() lexer.Position { return i.Pos }
+
+// Position : Returns where the instruction is in the gecko source
+func (i *Jump) Position() lexer.Position { return i.Pos }
+
+// Position : Returns where the instruction is in the gecko source
+func (i *Branch) Position() lexer.Position { return i.Pos }
+
+// Position : Returns where the instruction is in the gecko source
+func (i *Return) Position() lexer.Position { return i.Pos }
+
+// Position : Returns where the instruction is in the gecko source
+func (i *Unreachable) Position() lexer.Position { return i.Pos }
+
+// Successors : Returns Target
+func (i *Jump) Successors() []*Block { return []*Block{i.Target} }
+
+// Successors : Returns Then and Else
+func (i *Branch) Successors() []*Block { return []*Block{i.Then, i.Else} }
+
+// Successors : Returns nothing, returns leave the function
+func (i *Return) Successors() []*Block { return nil }
+
+// Successors : Returns nothing
+func (i *Unreachable) Successors() []*Block { return nil }
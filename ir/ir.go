@@ -0,0 +1,151 @@
+// Package ir contains the intermediate representation gecko code is lowered to
+// once it was checked.
+//
+// A Module holds the structs, globals and functions of a package. The body of
+// a Function is a list of basic blocks, each a run of instructions that ends in
+// a single Terminator that jumps, branches or returns. Values are typed trees
+// of constants, variables, operators and calls. Nothing in the IR refers to
+// the parser's tokens, so backends and optimisation passes only have to
+// understand the types in this package.
+package ir
+
+import (
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Module : A lowered package
+type Module struct {
+	Name    string
+	Structs []*Struct
+	// Globals : Variables declared at package level, their Value is what they are initialised with
+	Globals   []*Variable
+	Functions []*Function
+	// Main : The function programs start in, nil for libraries
+	Main *Function
+	// Preamble : C code written in the sources with "##", backends that can't use it ignore it
+	Preamble string
+}
+
+// Struct : A class
+type Struct struct {
+	// Name : The name of the struct in generated code, the class' ctype if it has one
+	Name      string
+	GeckoName string
+	Fields    []*Field
+}
+
+// Field : A member of a struct
+type Field struct {
+	Name string
+	Type *Type
+}
+
+// Variable : A global, parameter, local or temporary
+type Variable struct {
+	// Name : The symbol of the variable, unique in its function
+	Name string
+	Type *Type
+	// Const : Set for variables that are never assigned to after they were initialised with Value
+	Const bool
+	// Value : The initial value of globals and constants
+	Value Value
+	Pos   lexer.Position
+}
+
+// Function : A function and its body
+type Function struct {
+	Name   string
+	Params []*Variable
+	Result *Type
+	// Locals : Every variable declared in the body, temporaries included
+	Locals []*Variable
+	// Blocks : The body, execution starts in the first block
+	Blocks []*Block
+	Pos    lexer.Position
+}
+
+// Block : A basic block, its instructions run one after the other and Term decides what runs next
+type Block struct {
+	// ID : Numbers the blocks of a function, it is unique but not necessarily the block's index
+	ID     int
+	Instrs []Instr
+	Term   Terminator
+}
+
+// NewBlock : Adds an empty block to the end of f
+func (f *Function) NewBlock() *Block {
+	id := 0
+	for _, b := range f.Blocks {
+		if b.ID >= id {
+			id = b.ID + 1
+		}
+	}
+
+	b := &Block{ID: id}
+	f.Blocks = append(f.Blocks, b)
+	return b
+}
+
+// Successors : Returns the blocks b can continue in
+func (b *Block) Successors() []*Block {
+	if b.Term == nil {
+		return nil
+	}
+
+	return b.Term.Successors()
+}
+
+// Predecessors : Returns how many jumps and branches of f lead to every block, the entry block
+// counts as reached once
+func (f *Function) Predecessors() map[*Block]int {
+	counts := map[*Block]int{}
+	if len(f.Blocks) > 0 {
+		counts[f.Blocks[0]]++
+	}
+
+	for _, b := range f.Blocks {
+		for _, s := range b.Successors() {
+			counts[s]++
+		}
+	}
+
+	return counts
+}
+
+// RemoveUnreachable : Drops the blocks no path from the entry block leads to
+func (f *Function) RemoveUnreachable() {
+	if len(f.Blocks) == 0 {
+		return
+	}
+
+	reached := map[*Block]bool{}
+	work := []*Block{f.Blocks[0]}
+	for len(work) > 0 {
+		b := work[len(work)-1]
+		work = work[:len(work)-1]
+		if reached[b] {
+			continue
+		}
+		reached[b] = true
+		work = append(work, b.Successors()...)
+	}
+
+	blocks := []*Block{}
+	for _, b := range f.Blocks {
+		if reached[b] {
+			blocks = append(blocks, b)
+		}
+	}
+	f.Blocks = blocks
+}
+
+// Function : Returns the function of m called name or nil
+func (m *Module) Function(name string) *Function {
+	for _, f := range m.Functions {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
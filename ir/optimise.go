@@ -0,0 +1,696 @@
+package ir
+
+import (
+	"strings"
+)
+
+/*
+	Optimisation
+
+	The passes rewrite a module in place and report if they changed anything.
+	Passes find more to do once others ran, propagating a constant can decide a
+	branch and dropping a branch can make another variable constant, so
+	Optimise repeats them until none changes anything.
+
+	Level 0 leaves the module the way it was lowered. Level 1 folds constant
+	expressions and drops the branches whose condition is a constant, so the
+	body of an if that can never run isn't generated. Level 2 also inlines small
+	functions, propagates constants through the locals of functions, removes
+	stores nothing reads and, in programs, the functions Main never reaches.
+*/
+
+// The optimisation levels
+const (
+	DefaultOptimisation = 1
+	MaxOptimisation     = 2
+)
+
+// readOnly : The intrinsics that only read a list or map and can't fail, getting a key a map doesn't
+// have fails
+var readOnly = map[Op]bool{ListAt: true, MapHas: true, MapSlotUsed: true, MapSlotKey: true}
+
+// inlineLimit : How many values the body of a function can have for calls to it to be inlined
+const inlineLimit = 24
+
+// Optimise : Runs the passes of level on m
+func Optimise(m *Module, level int) {
+	if level <= 0 {
+		return
+	}
+
+	for _, g := range m.Globals {
+		if g.Value != nil {
+			g.Value = transform(g.Value, fold)
+		}
+	}
+
+	for changed := true; changed; {
+		changed = level >= 2 && Inline(m)
+
+		for _, f := range m.Functions {
+			changed = FoldConstants(f) || changed
+			if level >= 2 {
+				changed = PropagateConstants(f) || changed
+			}
+			changed = SimplifyBranches(f) || changed
+			if level >= 2 {
+				changed = RemoveDeadStores(f) || changed
+			}
+		}
+	}
+
+	if level >= 2 {
+		RemoveDeadFunctions(m)
+	}
+}
+
+// transform : Replaces every value in v, operands first, with what fn returns for it
+func transform(v Value, fn func(Value) Value) Value {
+	switch v := v.(type) {
+	case *Member:
+		v.X = transform(v.X, fn)
+	case *Unary:
+		v.X = transform(v.X, fn)
+	case *Binary:
+		v.X = transform(v.X, fn)
+		v.Y = transform(v.Y, fn)
+	case *Cast:
+		v.X = transform(v.X, fn)
+	case *CString:
+		v.X = transform(v.X, fn)
+	case *Format:
+		transformAll(v.Args, fn)
+	case *Call:
+		transformAll(v.Args, fn)
+	case *Intrinsic:
+		transformAll(v.Args, fn)
+	case *ListLit:
+		transformAll(v.Items, fn)
+	case *MapLit:
+		transformAll(v.Keys, fn)
+		transformAll(v.Values, fn)
+	case *StructLit:
+		transformAll(v.Values, fn)
+	}
+
+	return fn(v)
+}
+
+func transformAll(values []Value, fn func(Value) Value) {
+	for i := range values {
+		values[i] = transform(values[i], fn)
+	}
+}
+
+// transformInstr : Transforms the values i reads. What an assignment writes to is left alone
+func transformInstr(i Instr, fn func(Value) Value) {
+	switch i := i.(type) {
+	case *Assign:
+		if m, ok := i.Dest.(*Member); ok {
+			m.X = transform(m.X, fn)
+		}
+		i.Value = transform(i.Value, fn)
+	case *Eval:
+		i.Value = transform(i.Value, fn)
+	case *Branch:
+		i.Cond = transform(i.Cond, fn)
+	case *Return:
+		if i.Value != nil {
+			i.Value = transform(i.Value, fn)
+		}
+	}
+}
+
+// transformBlock : Transforms the values the instructions of b read
+func transformBlock(b *Block, fn func(Value) Value) {
+	for _, i := range b.Instrs {
+		transformInstr(i, fn)
+	}
+	if b.Term != nil {
+		transformInstr(b.Term, fn)
+	}
+}
+
+// walk : Calls fn for every value in v, v included
+func walk(v Value, fn func(Value)) {
+	transform(v, func(v Value) Value {
+		fn(v)
+		return v
+	})
+}
+
+// clone : Copies v so it can be used in another place, values are trees and never shared
+func clone(v Value) Value {
+	switch v := v.(type) {
+	case *Const:
+		c := *v
+		return &c
+	case *Ref:
+		return &Ref{Var: v.Var}
+	case *Member:
+		return &Member{X: clone(v.X), Name: v.Name, T: v.T}
+	case *Unary:
+		return &Unary{Op: v.Op, X: clone(v.X), T: v.T}
+	case *Binary:
+		return &Binary{Op: v.Op, X: clone(v.X), Y: clone(v.Y), T: v.T}
+	case *Cast:
+		return &Cast{X: clone(v.X), T: v.T}
+	case *CString:
+		return &CString{X: clone(v.X)}
+	case *Format:
+		return &Format{Parts: append([]string{}, v.Parts...), Args: cloneAll(v.Args)}
+	case *Call:
+		return &Call{Func: v.Func, Args: cloneAll(v.Args), T: v.T, External: v.External}
+	case *Intrinsic:
+		return &Intrinsic{Op: v.Op, Args: cloneAll(v.Args), T: v.T}
+	case *ListLit:
+		return &ListLit{T: v.T, Items: cloneAll(v.Items)}
+	case *MapLit:
+		return &MapLit{T: v.T, Keys: cloneAll(v.Keys), Values: cloneAll(v.Values)}
+	case *StructLit:
+		return &StructLit{T: v.T, Fields: v.Fields, Values: cloneAll(v.Values)}
+	}
+
+	// Null and Extern values don't have anything that could change
+	return v
+}
+
+func cloneAll(values []Value) []Value {
+	r := []Value{}
+	for _, v := range values {
+		r = append(r, clone(v))
+	}
+
+	return r
+}
+
+// pure : Checks if computing v does nothing but compute it, so it can be dropped or computed again
+func pure(v Value) bool {
+	r := true
+	walk(v, func(v Value) {
+		switch v := v.(type) {
+		case *Call:
+			r = false
+		case *Intrinsic:
+			r = r && readOnly[v.Op]
+		}
+	})
+
+	return r
+}
+
+// HasEffects : Checks if computing v does more than compute it, like calling a function
+func HasEffects(v Value) bool {
+	return !pure(v)
+}
+
+// size : Counts the values in v
+func size(v Value) int {
+	n := 0
+	walk(v, func(Value) { n++ })
+	return n
+}
+
+// FoldConstants : Computes the operators of f whose operands are constants
+func FoldConstants(f *Function) bool {
+	changed := false
+	folder := func(v Value) Value {
+		r := fold(v)
+		changed = changed || r != v
+		return r
+	}
+
+	for _, b := range f.Blocks {
+		transformBlock(b, folder)
+	}
+
+	return changed
+}
+
+// PropagateConstants : Replaces the locals of f with the constant they hold wherever every path
+// leading there assigned the same constant
+func PropagateConstants(f *Function) bool {
+	if len(f.Blocks) == 0 {
+		return false
+	}
+
+	tracked := map[*Variable]bool{}
+	for _, v := range f.Locals {
+		tracked[v] = propagatable(v.Type)
+	}
+
+	predecessors := map[*Block][]*Block{}
+	for _, b := range f.Blocks {
+		for _, s := range b.Successors() {
+			predecessors[s] = append(predecessors[s], b)
+		}
+	}
+
+	// Blocks are only visited once a path to them was, what isn't known about a predecessor that
+	// wasn't visited yet doesn't count until it is
+	entry := f.Blocks[0]
+	in := map[*Block]constants{}
+	out := map[*Block]constants{}
+	work := []*Block{entry}
+	for len(work) > 0 {
+		b := work[0]
+		work = work[1:]
+
+		var state constants
+		if b != entry {
+			for _, p := range predecessors[b] {
+				if out[p] == nil {
+					continue
+				} else if state == nil {
+					state = out[p].copy()
+				} else {
+					state.meet(out[p])
+				}
+			}
+		}
+		if state == nil {
+			state = constants{}
+		}
+
+		in[b] = state.copy()
+		for _, i := range b.Instrs {
+			state.assign(i, tracked)
+		}
+
+		if out[b] == nil || !out[b].equal(state) {
+			out[b] = state
+			work = append(work, b.Successors()...)
+		}
+	}
+
+	changed := false
+	for _, b := range f.Blocks {
+		state := in[b]
+		if state == nil {
+			continue
+		}
+
+		replace := func(v Value) Value {
+			if r, ok := v.(*Ref); ok {
+				if c := state.known(r.Var); c != nil {
+					changed = true
+					return clone(c)
+				}
+				return v
+			}
+
+			r := fold(v)
+			changed = changed || r != v
+			return r
+		}
+
+		for _, i := range b.Instrs {
+			transformInstr(i, replace)
+			state.assign(i, tracked)
+		}
+		if b.Term != nil {
+			transformInstr(b.Term, replace)
+		}
+	}
+
+	return changed
+}
+
+// assign : Updates what is known after i ran
+func (c constants) assign(i Instr, tracked map[*Variable]bool) {
+	a, ok := i.(*Assign)
+	if !ok {
+		return
+	}
+
+	r, ok := a.Dest.(*Ref)
+	if !ok || !tracked[r.Var] {
+		return
+	}
+
+	if value := c.evaluate(a.Value); value != nil {
+		if value = convertConst(value, r.Var.Type); value != nil {
+			c[r.Var] = value
+			return
+		}
+	}
+
+	delete(c, r.Var)
+}
+
+// SimplifyBranches : Turns branches whose condition is a constant into jumps, skips blocks that
+// only jump somewhere else, merges blocks with the only block jumping to them and drops the
+// blocks that can't be reached any more
+func SimplifyBranches(f *Function) bool {
+	if len(f.Blocks) == 0 {
+		return false
+	}
+
+	changed := false
+	for _, b := range f.Blocks {
+		branch, ok := b.Term.(*Branch)
+		if !ok {
+			continue
+		}
+
+		if c, ok := branch.Cond.(*Const); ok && c.T.Kind == Bool {
+			target := branch.Else
+			if c.Int != 0 {
+				target = branch.Then
+			}
+			b.Term = &Jump{Pos: branch.Pos, Target: target}
+			changed = true
+		} else if branch.Then == branch.Else {
+			b.Term = &Jump{Pos: branch.Pos, Target: branch.Then}
+			changed = true
+		}
+	}
+
+	for _, b := range f.Blocks {
+		switch t := b.Term.(type) {
+		case *Jump:
+			changed = retarget(&t.Target) || changed
+		case *Branch:
+			changed = retarget(&t.Then) || changed
+			changed = retarget(&t.Else) || changed
+		}
+	}
+
+	predecessors := f.Predecessors()
+	merged := map[*Block]bool{}
+	for _, b := range f.Blocks {
+		if merged[b] {
+			continue
+		}
+
+		for {
+			j, ok := b.Term.(*Jump)
+			if !ok || j.Target == b || predecessors[j.Target] != 1 {
+				break
+			}
+
+			next := j.Target
+			b.Instrs = append(b.Instrs, next.Instrs...)
+			b.Term = next.Term
+			next.Instrs = nil
+			next.Term = nil
+			merged[next] = true
+			changed = true
+		}
+	}
+
+	blocks := len(f.Blocks)
+	f.RemoveUnreachable()
+	return changed || len(f.Blocks) != blocks
+}
+
+// retarget : Makes a jump or branch to a block that does nothing but jump go straight to where it jumps
+func retarget(target **Block) bool {
+	seen := map[*Block]bool{}
+	changed := false
+
+	for {
+		b := *target
+		j, ok := b.Term.(*Jump)
+		if !ok || len(b.Instrs) > 0 || seen[b] {
+			return changed
+		}
+
+		seen[b] = true
+		*target = j.Target
+		changed = true
+	}
+}
+
+// RemoveDeadStores : Removes the assignments to locals of f that are never read and the locals that
+// aren't used at all. Assignments whose value does more than compute it are kept as evaluations
+func RemoveDeadStores(f *Function) bool {
+	read := map[*Variable]bool{}
+	used := map[*Variable]bool{}
+	reads := func(v Value) Value {
+		if r, ok := v.(*Ref); ok {
+			read[r.Var] = true
+			used[r.Var] = true
+		}
+		return v
+	}
+
+	for _, b := range f.Blocks {
+		for _, i := range b.Instrs {
+			transformInstr(i, reads)
+			if a, ok := i.(*Assign); ok {
+				if r, ok := a.Dest.(*Ref); ok {
+					used[r.Var] = true
+				}
+			}
+		}
+		if b.Term != nil {
+			transformInstr(b.Term, reads)
+		}
+	}
+
+	local := map[*Variable]bool{}
+	for _, v := range f.Locals {
+		local[v] = true
+		// The values of constants are read when they are declared
+		if v.Value != nil {
+			transform(v.Value, reads)
+		}
+	}
+
+	changed := false
+	for _, b := range f.Blocks {
+		instrs := []Instr{}
+		for _, i := range b.Instrs {
+			switch i := i.(type) {
+			case *Assign:
+				if r, ok := i.Dest.(*Ref); ok && local[r.Var] && !read[r.Var] {
+					changed = true
+					if !pure(i.Value) {
+						instrs = append(instrs, &Eval{Pos: i.Pos, Value: i.Value})
+					}
+					continue
+				}
+			case *Eval:
+				if pure(i.Value) {
+					changed = true
+					continue
+				}
+			}
+
+			instrs = append(instrs, i)
+		}
+		b.Instrs = instrs
+	}
+
+	locals := []*Variable{}
+	for _, v := range f.Locals {
+		if used[v] {
+			locals = append(locals, v)
+		}
+	}
+	changed = changed || len(locals) != len(f.Locals)
+	f.Locals = locals
+
+	return changed
+}
+
+// Inline : Replaces the calls to small functions that only compute a value from their arguments
+// with that computation
+func Inline(m *Module) bool {
+	changed := false
+	inline := func(v Value) Value {
+		call, ok := v.(*Call)
+		if !ok || call.External {
+			return v
+		}
+
+		callee := m.Function(call.Func)
+		if callee == nil {
+			return v
+		}
+
+		if r := inlined(callee, call.Args); r != nil {
+			changed = true
+			return r
+		}
+		return v
+	}
+
+	for _, f := range m.Functions {
+		for _, b := range f.Blocks {
+			transformBlock(b, inline)
+		}
+	}
+
+	return changed
+}
+
+// inlined : Returns what calling f with args computes, nil if f can't be inlined there. Only
+// functions of one block that assign their locals and return are inlined, and only when the
+// arguments can be read again without a difference
+func inlined(f *Function, args []Value) Value {
+	if len(f.Blocks) != 1 || len(f.Params) != len(args) {
+		return nil
+	}
+
+	ret, ok := f.Blocks[0].Term.(*Return)
+	if !ok || ret.Value == nil || !pure(ret.Value) {
+		return nil
+	}
+
+	own := map[*Variable]bool{}
+	values := map[*Variable]Value{}
+	for i, p := range f.Params {
+		if !simple(args[i]) {
+			return nil
+		}
+		own[p] = true
+		values[p] = args[i]
+	}
+	for _, v := range f.Locals {
+		own[v] = true
+	}
+
+	n := size(ret.Value)
+	for _, i := range f.Blocks[0].Instrs {
+		a, ok := i.(*Assign)
+		if !ok || !pure(a.Value) {
+			return nil
+		}
+
+		r, ok := a.Dest.(*Ref)
+		if !ok {
+			return nil
+		}
+
+		value := substitute(a.Value, values, own)
+		if value == nil {
+			return nil
+		}
+		values[r.Var] = value
+		n += size(a.Value)
+	}
+
+	if n > inlineLimit {
+		return nil
+	}
+
+	r := substitute(ret.Value, values, own)
+	if r == nil {
+		return nil
+	}
+	return convert(r, f.Result)
+}
+
+// simple : Checks if v reads something without computing anything
+func simple(v Value) bool {
+	switch v := v.(type) {
+	case *Const, *Null, *Ref, *Extern:
+		return true
+	case *Member:
+		return simple(v.X)
+	}
+
+	return false
+}
+
+// substitute : Copies v with the variables in values replaced by their value, converted to the
+// type of the variable. Returns nil if v reads a variable of its own function that has no value
+func substitute(v Value, values map[*Variable]Value, own map[*Variable]bool) Value {
+	ok := true
+	r := transform(clone(v), func(v Value) Value {
+		ref, isRef := v.(*Ref)
+		if !isRef || !own[ref.Var] {
+			return v
+		}
+
+		value := values[ref.Var]
+		if value != nil {
+			value = convert(clone(value), ref.Var.Type)
+		}
+		if value == nil {
+			ok = false
+			return v
+		}
+		return value
+	})
+
+	if !ok {
+		return nil
+	}
+	return r
+}
+
+// convert : Returns v as a value of type t the way assigning it or passing it as an argument would
+// convert it, nil if that would need more than a cast
+func convert(v Value, t *Type) Value {
+	vt := v.Type()
+	switch {
+	case sameType(vt, t):
+		return v
+	case (vt.IsNumeric() || vt.Kind == Bool) && (t.IsNumeric() || t.Kind == Bool):
+		return &Cast{X: v, T: t}
+	}
+
+	return nil
+}
+
+// RemoveDeadFunctions : Removes the functions a program never calls. Libraries keep all of theirs,
+// they are called from outside. Functions the C preamble mentions are kept too
+func RemoveDeadFunctions(m *Module) bool {
+	if m.Main == nil {
+		return false
+	}
+
+	reached := map[*Function]bool{}
+	work := []*Function{m.Main}
+	calls := func(v Value) Value {
+		if call, ok := v.(*Call); ok && !call.External {
+			if f := m.Function(call.Func); f != nil {
+				work = append(work, f)
+			}
+		}
+		return v
+	}
+
+	for _, g := range m.Globals {
+		if g.Value != nil {
+			transform(g.Value, calls)
+		}
+	}
+	for _, f := range m.Functions {
+		if strings.Contains(m.Preamble, f.Name) {
+			work = append(work, f)
+		}
+	}
+
+	for len(work) > 0 {
+		f := work[len(work)-1]
+		work = work[:len(work)-1]
+		if reached[f] {
+			continue
+		}
+		reached[f] = true
+
+		for _, v := range f.Locals {
+			if v.Value != nil {
+				transform(v.Value, calls)
+			}
+		}
+		for _, b := range f.Blocks {
+			transformBlock(b, calls)
+		}
+	}
+
+	functions := []*Function{}
+	for _, f := range m.Functions {
+		if reached[f] {
+			functions = append(functions, f)
+		}
+	}
+
+	changed := len(functions) != len(m.Functions)
+	m.Functions = functions
+	return changed
+}
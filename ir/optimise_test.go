@@ -0,0 +1,54 @@
+package ir_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neutrino2211/Gecko/compiler/compilertest"
+)
+
+func TestOptimisationLevels(t *testing.T) {
+	source := `package Main
+
+##include<stdio.h>
+
+external func puts(val: string)
+
+func twice(n: int): int {
+    return n * 2
+}
+
+func Main(): int {
+    a := 2 + 3
+    b := twice(n: a)
+    if (false) {
+        puts("never")
+    }
+    return b
+}
+`
+
+	unoptimised := compilertest.Module(t, source, map[string]string{"O": "0"}).String()
+	for _, kept := range []string{"func Main__twice", "Main__twice(Main__Main__a)", "branch false", `puts(cstring("never"))`} {
+		if !strings.Contains(unoptimised, kept) {
+			t.Errorf("-O0 doesn't keep %q:\n%s", kept, unoptimised)
+		}
+	}
+
+	optimised := compilertest.Module(t, source, map[string]string{"O": "2"}).String()
+	if want := "module Main\n\nfunc Main__Main() int {\nb0:\n\treturn 10\n}\n"; optimised != want {
+		t.Errorf("-O2 gives\n%s\nwant\n%s", optimised, want)
+	}
+}
+
+func TestOptimisedProgramsRun(t *testing.T) {
+	for _, program := range compilertest.Programs {
+		for _, level := range []string{"0", "2"} {
+			executable := compilertest.Build(t, program.Source, map[string]string{"O": level})
+			out, status := compilertest.Run(t, executable)
+			if out != program.Output || status != program.Status {
+				t.Errorf("%s -O%s prints\n%s\nand exits with %d, want\n%s\nand %d", program.Name, level, out, status, program.Output, program.Status)
+			}
+		}
+	}
+}
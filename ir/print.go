@@ -0,0 +1,173 @@
+package ir
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+	Listing
+
+	Modules are printed as text so the IR can be read while working on the
+	compiler, `gecko compile --emit=ir` writes it. The listing is for people,
+	nothing parses it.
+*/
+
+// String : Lists the module
+func (m *Module) String() string {
+	r := "module " + m.Name + "\n"
+
+	for _, s := range m.Structs {
+		r += "\nstruct " + s.Name + " {\n"
+		for _, f := range s.Fields {
+			r += "\t" + f.Name + " " + f.Type.String() + "\n"
+		}
+		r += "}\n"
+	}
+
+	if len(m.Globals) > 0 {
+		r += "\n"
+	}
+	for _, g := range m.Globals {
+		r += "global " + variableString(g) + "\n"
+	}
+
+	for _, f := range m.Functions {
+		r += "\n" + f.String()
+	}
+
+	return r
+}
+
+// String : Lists the function
+func (f *Function) String() string {
+	params := []string{}
+	for _, p := range f.Params {
+		params = append(params, p.Name+" "+p.Type.String())
+	}
+
+	r := "func " + f.Name + "(" + strings.Join(params, ", ") + ") " + f.Result.String() + " {\n"
+	for _, l := range f.Locals {
+		r += "\tlocal " + variableString(l) + "\n"
+	}
+
+	for _, b := range f.Blocks {
+		r += blockName(b) + ":\n"
+		for _, i := range b.Instrs {
+			r += "\t" + instrString(i) + "\n"
+		}
+		if b.Term != nil {
+			r += "\t" + instrString(b.Term) + "\n"
+		}
+	}
+
+	return r + "}\n"
+}
+
+func variableString(v *Variable) string {
+	r := v.Name + " " + v.Type.String()
+	if v.Const {
+		r = "const " + r
+	}
+	if v.Value != nil {
+		r += " = " + Repr(v.Value)
+	}
+
+	return r
+}
+
+func blockName(b *Block) string {
+	return "b" + strconv.Itoa(b.ID)
+}
+
+func instrString(i Instr) string {
+	switch i := i.(type) {
+	case *Assign:
+		return Repr(i.Dest) + " = " + Repr(i.Value)
+	case *Eval:
+		return Repr(i.Value)
+	case *Jump:
+		return "jump " + blockName(i.Target)
+	case *Branch:
+		return "branch " + Repr(i.Cond) + " " + blockName(i.Then) + " " + blockName(i.Else)
+	case *Return:
+		if i.Value == nil {
+			return "return"
+		}
+		return "return " + Repr(i.Value)
+	case *Unreachable:
+		return "unreachable"
+	}
+
+	return "?"
+}
+
+func reprs(values []Value) string {
+	r := []string{}
+	for _, v := range values {
+		r = append(r, Repr(v))
+	}
+
+	return strings.Join(r, ", ")
+}
+
+// Repr : Returns v as text
+func Repr(v Value) string {
+	switch v := v.(type) {
+	case *Const:
+		switch v.T.Kind {
+		case Bool:
+			return strconv.FormatBool(v.Int != 0)
+		case Float:
+			return strconv.FormatFloat(v.Float, 'g', -1, 64)
+		case String:
+			return strconv.Quote(v.Str)
+		}
+		return strconv.FormatInt(v.Int, 10)
+	case *Null:
+		return "nil"
+	case *Ref:
+		return v.Var.Name
+	case *Extern:
+		return "extern " + v.Name
+	case *Member:
+		return Repr(v.X) + "." + v.Name
+	case *Unary:
+		return v.Op + Repr(v.X)
+	case *Binary:
+		return "(" + Repr(v.X) + " " + v.Op + " " + Repr(v.Y) + ")"
+	case *Cast:
+		return "(" + Repr(v.X) + " as " + v.T.String() + ")"
+	case *Format:
+		parts := []string{}
+		for _, p := range v.Parts {
+			parts = append(parts, strconv.Quote(p))
+		}
+		return "format(" + strings.Join(parts, " ") + "; " + reprs(v.Args) + ")"
+	case *CString:
+		return "cstring(" + Repr(v.X) + ")"
+	case *Call:
+		if v.External {
+			return "extern " + v.Func + "(" + reprs(v.Args) + ")"
+		}
+		return v.Func + "(" + reprs(v.Args) + ")"
+	case *Intrinsic:
+		return string(v.Op) + "(" + reprs(v.Args) + ")"
+	case *ListLit:
+		return v.T.String() + "{" + reprs(v.Items) + "}"
+	case *MapLit:
+		entries := []string{}
+		for i := range v.Keys {
+			entries = append(entries, Repr(v.Keys[i])+": "+Repr(v.Values[i]))
+		}
+		return v.T.String() + "{" + strings.Join(entries, ", ") + "}"
+	case *StructLit:
+		fields := []string{}
+		for i := range v.Fields {
+			fields = append(fields, v.Fields[i]+": "+Repr(v.Values[i]))
+		}
+		return v.T.String() + "{" + strings.Join(fields, ", ") + "}"
+	}
+
+	return "?"
+}
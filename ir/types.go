@@ -0,0 +1,74 @@
+package ir
+
+// Kind : What sort of values a type describes
+type Kind int
+
+// The kinds of types
+const (
+	Void Kind = iota
+	Bool
+	Int
+	Float
+	String
+	List
+	Map
+	// Class : Instances of a class, Type.Struct describes their fields
+	Class
+	// Opaque : A type gecko knows nothing about, like the types of external C symbols
+	Opaque
+)
+
+// Type : The type of a value
+type Type struct {
+	Kind Kind
+	// Name : How generated code spells the type, the C type for numbers and opaque types
+	Name string
+	// GeckoName : How the type is written in gecko
+	GeckoName string
+	// Bits and Signed describe Int and Float types
+	Bits    uint
+	Signed  bool
+	Pointer bool
+	// Elem : The items of a List or the values of a Map
+	Elem *Type
+	// Key : The keys of a Map
+	Key *Type
+	// Struct : The fields of Class types
+	Struct *Struct
+}
+
+var (
+	// VoidType : The type of calls that don't return anything
+	VoidType = &Type{Kind: Void, Name: "void", GeckoName: "void"}
+	// BoolType : The type of comparisons
+	BoolType = &Type{Kind: Bool, Name: "bool", GeckoName: "bool"}
+	// IntType : The type of counters and lengths
+	IntType = &Type{Kind: Int, Name: "int", GeckoName: "int", Bits: 32, Signed: true}
+	// StringType : Gecko strings
+	StringType = &Type{Kind: String, Name: "gecko_string", GeckoName: "string"}
+	// CStringType : What gecko strings become when they are passed to C
+	CStringType = &Type{Kind: Opaque, Name: "const char *", GeckoName: "string", Pointer: true}
+	// UnknownType : The type of values the compiler can't find the type of, like external C symbols
+	UnknownType = &Type{Kind: Opaque}
+)
+
+// IsNumeric : Checks if t is an Int or a Float
+func (t *Type) IsNumeric() bool {
+	return t.Kind == Int || t.Kind == Float
+}
+
+// String : Returns t the way it is written in gecko
+func (t *Type) String() string {
+	switch {
+	case t.Kind == List:
+		return "[" + t.Elem.String() + "]"
+	case t.Kind == Map:
+		return "map[" + t.Key.String() + "]" + t.Elem.String()
+	case t.GeckoName != "":
+		return t.GeckoName
+	case t.Name != "":
+		return t.Name
+	}
+
+	return "unknown"
+}
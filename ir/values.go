@@ -0,0 +1,196 @@
+package ir
+
+// Value : Something that can be computed, every value knows its type
+type Value interface {
+	Type() *Type
+}
+
+// Const : A constant. Bools and Ints keep their value in Int, Floats in Float and Strings in Str
+type Const struct {
+	T     *Type
+	Int   int64
+	Float float64
+	Str   string
+}
+
+// Null : The nil pointer
+type Null struct {
+	T *Type
+}
+
+// Ref : Reads or, as the destination of an Assign, writes a variable
+type Ref struct {
+	Var *Variable
+}
+
+// Extern : A symbol gecko doesn't declare, like a C constant
+type Extern struct {
+	Name string
+	T    *Type
+}
+
+// Member : A field of a struct or the length of a list, map or string
+type Member struct {
+	X    Value
+	Name string
+	T    *Type
+}
+
+// Unary : -X, +X or !X
+type Unary struct {
+	Op string
+	X  Value
+	T  *Type
+}
+
+// Binary : An arithmetic operator or a comparison. + joins strings and comparisons of strings
+// compare their contents
+type Binary struct {
+	Op string
+	X  Value
+	Y  Value
+	T  *Type
+}
+
+// Cast : Converts X to T
+type Cast struct {
+	X Value
+	T *Type
+}
+
+// Format : Builds a string, Args are formatted in between Parts, so there is one more part than there are args
+type Format struct {
+	Parts []string
+	Args  []Value
+}
+
+// CString : Passes a gecko string to an external C function
+type CString struct {
+	X Value
+}
+
+// Call : Calls the gecko function or, if External is set, the C function Func
+type Call struct {
+	Func     string
+	Args     []Value
+	T        *Type
+	External bool
+}
+
+// Intrinsic : An operation of the runtime on lists and maps
+type Intrinsic struct {
+	Op   Op
+	Args []Value
+	T    *Type
+}
+
+// ListLit : A new list holding Items
+type ListLit struct {
+	T     *Type
+	Items []Value
+}
+
+// MapLit : A new map, Keys[i] is set to Values[i]
+type MapLit struct {
+	T      *Type
+	Keys   []Value
+	Values []Value
+}
+
+// StructLit : A struct with Fields[i] set to Values[i]
+type StructLit struct {
+	T      *Type
+	Fields []string
+	Values []Value
+}
+
+// Op : Names an intrinsic
+type Op string
+
+// The intrinsics. The first argument of every one is the list or map it works on
+const (
+	// ListPush : (list, item) adds item to the end of list
+	ListPush Op = "list.push"
+	// ListPop : (list) removes the last item of list and returns it
+	ListPop Op = "list.pop"
+	// ListAt : (list, index) returns an item of list
+	ListAt Op = "list.at"
+	// ListFree : (list) releases list and its items
+	ListFree Op = "list.free"
+	// MapGet : (map, key) returns the value of key
+	MapGet Op = "map.get"
+	// MapSet : (map, key, value) sets key to value
+	MapSet Op = "map.set"
+	// MapHas : (map, key) checks if key was set
+	MapHas Op = "map.has"
+	// MapDelete : (map, key) removes key
+	MapDelete Op = "map.delete"
+	// MapSlotUsed : (map, slot) checks if a slot of map holds a key. Slots go from 0 to the map's cap
+	MapSlotUsed Op = "map.slot_used"
+	// MapSlotKey : (map, slot) returns the key in a used slot
+	MapSlotKey Op = "map.slot_key"
+)
+
+// Type : Returns the type of the constant
+func (v *Const) Type() *Type { return v.T }
+
+// Type : Returns the type nil was given
+func (v *Null) Type() *Type { return v.T }
+
+// Type : Returns the type of the variable
+func (v *Ref) Type() *Type { return v.Var.Type }
+
+// Type : Returns the type of the symbol, UnknownType unless it was declared
+func (v *Extern) Type() *Type { return v.T }
+
+// Type : Returns the type of the field
+func (v *Member) Type() *Type { return v.T }
+
+// Type : Returns the type of the result
+func (v *Unary) Type() *Type { return v.T }
+
+// Type : Returns the type of the result
+func (v *Binary) Type() *Type { return v.T }
+
+// Type : Returns the type converted to
+func (v *Cast) Type() *Type { return v.T }
+
+// Type : Returns StringType
+func (v *Format) Type() *Type { return StringType }
+
+// Type : Returns CStringType
+func (v *CString) Type() *Type { return CStringType }
+
+// Type : Returns what the function returns
+func (v *Call) Type() *Type { return v.T }
+
+// Type : Returns what the intrinsic returns
+func (v *Intrinsic) Type() *Type { return v.T }
+
+// Type : Returns the type of the list
+func (v *ListLit) Type() *Type { return v.T }
+
+// Type : Returns the type of the map
+func (v *MapLit) Type() *Type { return v.T }
+
+// Type : Returns the type of the struct
+func (v *StructLit) Type() *Type { return v.T }
+
+// BoolConst : Returns the constant for b
+func BoolConst(b bool) *Const {
+	if b {
+		return &Const{T: BoolType, Int: 1}
+	}
+
+	return &Const{T: BoolType}
+}
+
+// IntConst : Returns n as a constant of type t
+func IntConst(t *Type, n int64) *Const {
+	return &Const{T: t, Int: n}
+}
+
+// StringConst : Returns a string constant
+func StringConst(s string) *Const {
+	return &Const{T: StringType, Str: s}
+}
@@ -0,0 +1,31 @@
+// Package libgecko contains the C sources for gecko's runtime library.
+//
+// The runtime is bundled with the compiler as plain strings so that a gecko
+// installation does not need to locate any extra files on disk. The compiler
+// prepends Header() to every generated source file and builds each Source into
+// an object file that is linked into the final executable.
+package libgecko
+
+// Source : A single C translation unit of the runtime library
+type Source struct {
+	Name   string
+	Header string
+	Code   string
+}
+
+// Sources : All translation units that make up the runtime library
+var Sources = []*Source{
+	listSource,
+	mapSource,
+	stringSource,
+}
+
+// Header : Returns the declarations of every runtime source
+func Header() string {
+	r := "#ifndef GECKO_RUNTIME_H\n#define GECKO_RUNTIME_H\n#include <stdint.h>\n"
+	for _, source := range Sources {
+		r += source.Header + "\n"
+	}
+	r += "#endif\n"
+	return r
+}
@@ -0,0 +1,108 @@
+package libgecko
+
+// listSource : A growable list of fixed size items. A gecko_list is a handle
+// to the list, copies of it refer to the same list so a list grown through
+// one of them is grown for all. Items are stored contiguously in `data` so a
+// list can be indexed like a C array with GECKO_LIST_AT.
+var listSource = &Source{
+	Name: "gecko_list",
+	Header: `#include <stddef.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+typedef struct {
+	void *data;
+	size_t len;
+	size_t cap;
+	size_t item_size;
+} gecko_list_data;
+
+typedef gecko_list_data *gecko_list;
+
+gecko_list gecko_list_new(size_t item_size);
+gecko_list gecko_list_from(size_t item_size, size_t len, const void *items);
+void gecko_list_push(gecko_list list, const void *item);
+void *gecko_list_pop(gecko_list list);
+void gecko_list_free(gecko_list list);
+
+#ifdef __cplusplus
+}
+#endif
+
+#define GECKO_LIST_PUSH(list, type, item) do { type gecko_list_item__ = (item); gecko_list_push((list), &gecko_list_item__); } while (0)
+#define GECKO_LIST_POP(list, type) (*(type *)gecko_list_pop(list))
+#define GECKO_LIST_AT(list, type, index) (((type *)(list)->data)[index])
+`,
+	Code: `#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+static void gecko_list_grow(gecko_list list, size_t cap) {
+	void *data;
+
+	if (cap <= list->cap) {
+		return;
+	}
+
+	data = realloc(list->data, cap * list->item_size);
+	if (data == NULL) {
+		fprintf(stderr, "gecko: out of memory\n");
+		abort();
+	}
+
+	list->data = data;
+	list->cap = cap;
+}
+
+gecko_list gecko_list_new(size_t item_size) {
+	gecko_list list = (gecko_list)malloc(sizeof(gecko_list_data));
+	if (list == NULL) {
+		fprintf(stderr, "gecko: out of memory\n");
+		abort();
+	}
+
+	list->data = NULL;
+	list->len = 0;
+	list->cap = 0;
+	list->item_size = item_size;
+	return list;
+}
+
+gecko_list gecko_list_from(size_t item_size, size_t len, const void *items) {
+	gecko_list list = gecko_list_new(item_size);
+	gecko_list_grow(list, len);
+	if (len > 0) {
+		memcpy(list->data, items, len * item_size);
+	}
+	list->len = len;
+	return list;
+}
+
+void gecko_list_push(gecko_list list, const void *item) {
+	if (list->len == list->cap) {
+		gecko_list_grow(list, list->cap == 0 ? 8 : list->cap * 2);
+	}
+
+	memcpy((char *)list->data + list->len * list->item_size, item, list->item_size);
+	list->len++;
+}
+
+void *gecko_list_pop(gecko_list list) {
+	if (list->len == 0) {
+		fprintf(stderr, "gecko: pop from empty list\n");
+		abort();
+	}
+
+	list->len--;
+	return (char *)list->data + list->len * list->item_size;
+}
+
+/* Frees the items and the handle, no copy of the list may be used after it */
+void gecko_list_free(gecko_list list) {
+	free(list->data);
+	free(list);
+}
+`,
+}
@@ -0,0 +1,279 @@
+package libgecko
+
+// mapSource : An open addressing hash table with either string or integer
+// keys. Values are copied into the table so any fixed size type can be
+// stored. String keys are duplicated on insert and owned by the map. Like a
+// gecko_list a gecko_map is a handle, copies of it refer to the same map.
+var mapSource = &Source{
+	Name: "gecko_map",
+	Header: `#include <stddef.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+typedef struct {
+	const char *str;
+	long long num;
+} gecko_map_key;
+
+typedef struct {
+	gecko_map_key key;
+	unsigned char state;
+} gecko_map_slot;
+
+typedef struct {
+	gecko_map_slot *slots;
+	void *values;
+	size_t len;
+	size_t used;
+	size_t cap;
+	size_t value_size;
+	int string_keys;
+} gecko_map_data;
+
+typedef gecko_map_data *gecko_map;
+
+gecko_map_key gecko_map_skey(const char *key);
+gecko_map_key gecko_map_ikey(long long key);
+gecko_map gecko_map_new(size_t value_size, int string_keys);
+void gecko_map_set(gecko_map map, gecko_map_key key, const void *value);
+void *gecko_map_get(gecko_map map, gecko_map_key key);
+int gecko_map_has(gecko_map map, gecko_map_key key);
+int gecko_map_delete(gecko_map map, gecko_map_key key);
+int gecko_map_slot_used(gecko_map map, size_t index);
+void gecko_map_free(gecko_map map);
+
+#ifdef __cplusplus
+}
+#endif
+
+#define GECKO_MAP_SET(map, type, key, value) do { type gecko_map_value__ = (value); gecko_map_set((map), key, &gecko_map_value__); } while (0)
+#define GECKO_MAP_GET(map, type, key) (*(type *)gecko_map_get((map), key))
+`,
+	Code: `#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#define GECKO_MAP_EMPTY 0
+#define GECKO_MAP_FULL 1
+#define GECKO_MAP_DELETED 2
+
+static void *gecko_map_alloc(size_t size) {
+	void *data = calloc(1, size);
+	if (data == NULL) {
+		fprintf(stderr, "gecko: out of memory\n");
+		abort();
+	}
+	return data;
+}
+
+static unsigned long long gecko_map_hash(const gecko_map_data *map, gecko_map_key key) {
+	unsigned long long hash = 14695981039346656037ULL;
+	const unsigned char *p;
+	size_t i;
+
+	if (map->string_keys) {
+		for (p = (const unsigned char *)key.str; *p; p++) {
+			hash ^= *p;
+			hash *= 1099511628211ULL;
+		}
+	} else {
+		p = (const unsigned char *)&key.num;
+		for (i = 0; i < sizeof(key.num); i++) {
+			hash ^= p[i];
+			hash *= 1099511628211ULL;
+		}
+	}
+
+	return hash;
+}
+
+static int gecko_map_key_equal(const gecko_map_data *map, gecko_map_key a, gecko_map_key b) {
+	if (map->string_keys) {
+		return strcmp(a.str, b.str) == 0;
+	}
+	return a.num == b.num;
+}
+
+/* Returns the slot holding key, or the slot key should be inserted into */
+static size_t gecko_map_find(const gecko_map_data *map, gecko_map_key key, int *found) {
+	size_t mask = map->cap - 1;
+	size_t index = (size_t)(gecko_map_hash(map, key) & mask);
+	size_t tombstone = map->cap;
+	size_t i;
+
+	*found = 0;
+
+	for (i = 0; i < map->cap; i++) {
+		gecko_map_slot *slot = &map->slots[index];
+
+		if (slot->state == GECKO_MAP_EMPTY) {
+			return tombstone != map->cap ? tombstone : index;
+		} else if (slot->state == GECKO_MAP_DELETED) {
+			if (tombstone == map->cap) {
+				tombstone = index;
+			}
+		} else if (gecko_map_key_equal(map, slot->key, key)) {
+			*found = 1;
+			return index;
+		}
+
+		index = (index + 1) & mask;
+	}
+
+	return tombstone;
+}
+
+static void gecko_map_resize(gecko_map map, size_t cap) {
+	gecko_map_data old = *map;
+	size_t i;
+	int found;
+
+	map->slots = (gecko_map_slot *)gecko_map_alloc(cap * sizeof(gecko_map_slot));
+	map->values = gecko_map_alloc(cap * map->value_size);
+	map->cap = cap;
+	map->len = 0;
+	map->used = 0;
+
+	for (i = 0; i < old.cap; i++) {
+		if (old.slots[i].state == GECKO_MAP_FULL) {
+			size_t index = gecko_map_find(map, old.slots[i].key, &found);
+			map->slots[index] = old.slots[i];
+			memcpy((char *)map->values + index * map->value_size, (char *)old.values + i * map->value_size, map->value_size);
+			map->len++;
+			map->used++;
+		}
+	}
+
+	free(old.slots);
+	free(old.values);
+}
+
+gecko_map_key gecko_map_skey(const char *key) {
+	gecko_map_key k;
+	k.str = key;
+	k.num = 0;
+	return k;
+}
+
+gecko_map_key gecko_map_ikey(long long key) {
+	gecko_map_key k;
+	k.str = NULL;
+	k.num = key;
+	return k;
+}
+
+gecko_map gecko_map_new(size_t value_size, int string_keys) {
+	gecko_map map = (gecko_map)gecko_map_alloc(sizeof(gecko_map_data));
+	map->value_size = value_size;
+	map->string_keys = string_keys;
+	return map;
+}
+
+void gecko_map_set(gecko_map map, gecko_map_key key, const void *value) {
+	size_t index;
+	int found;
+
+	if ((map->used + 1) * 4 > map->cap * 3) {
+		gecko_map_resize(map, map->cap == 0 ? 8 : (map->len * 2 >= map->cap ? map->cap * 2 : map->cap));
+	}
+
+	index = gecko_map_find(map, key, &found);
+
+	if (!found) {
+		if (map->slots[index].state == GECKO_MAP_EMPTY) {
+			map->used++;
+		}
+
+		if (map->string_keys) {
+			size_t size = strlen(key.str) + 1;
+			char *str = (char *)gecko_map_alloc(size);
+			memcpy(str, key.str, size);
+			key.str = str;
+		}
+
+		map->slots[index].key = key;
+		map->slots[index].state = GECKO_MAP_FULL;
+		map->len++;
+	}
+
+	memcpy((char *)map->values + index * map->value_size, value, map->value_size);
+}
+
+void *gecko_map_get(gecko_map map, gecko_map_key key) {
+	size_t index;
+	int found = 0;
+
+	if (map->cap > 0) {
+		index = gecko_map_find(map, key, &found);
+	}
+
+	if (!found) {
+		if (map->string_keys) {
+			fprintf(stderr, "gecko: key \"%s\" not found in map\n", key.str);
+		} else {
+			fprintf(stderr, "gecko: key %lld not found in map\n", key.num);
+		}
+		abort();
+	}
+
+	return (char *)map->values + index * map->value_size;
+}
+
+int gecko_map_has(gecko_map map, gecko_map_key key) {
+	int found = 0;
+
+	if (map->cap > 0) {
+		gecko_map_find(map, key, &found);
+	}
+
+	return found;
+}
+
+int gecko_map_delete(gecko_map map, gecko_map_key key) {
+	size_t index;
+	int found = 0;
+
+	if (map->cap > 0) {
+		index = gecko_map_find(map, key, &found);
+	}
+
+	if (!found) {
+		return 0;
+	}
+
+	if (map->string_keys) {
+		free((void *)map->slots[index].key.str);
+	}
+
+	map->slots[index].state = GECKO_MAP_DELETED;
+	map->len--;
+	return 1;
+}
+
+int gecko_map_slot_used(gecko_map map, size_t index) {
+	return map->slots[index].state == GECKO_MAP_FULL;
+}
+
+void gecko_map_free(gecko_map map) {
+	size_t i;
+
+	if (map->string_keys) {
+		for (i = 0; i < map->cap; i++) {
+			if (map->slots[i].state == GECKO_MAP_FULL) {
+				free((void *)map->slots[i].key.str);
+			}
+		}
+	}
+
+	free(map->slots);
+	free(map->values);
+	map->slots = NULL;
+	map->values = NULL;
+	map->len = 0;
+	map->used = 0;
+	map->cap = 0;
+}
+`,
+}
@@ -0,0 +1,122 @@
+package libgecko
+
+// stringSource : Immutable strings that know their length. `data` is always
+// NUL terminated so it can be handed to C functions directly. Literals are
+// wrapped without copying, every other operation allocates a new string.
+var stringSource = &Source{
+	Name: "gecko_string",
+	Header: `#include <stddef.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+typedef struct {
+	char *data;
+	size_t len;
+} gecko_string;
+
+gecko_string gecko_str_lit(const char *str);
+gecko_string gecko_str_new(const char *data, size_t len);
+gecko_string gecko_str_concat(gecko_string a, gecko_string b);
+gecko_string gecko_str_format(const char *format, ...);
+int gecko_str_eq(gecko_string a, gecko_string b);
+int gecko_str_cmp(gecko_string a, gecko_string b);
+gecko_list gecko_str_args(int argc, char **argv);
+
+#ifdef __cplusplus
+}
+#endif
+
+#define GECKO_STR_CONST(lit) { (char *)(lit), sizeof(lit) - 1 }
+`,
+	Code: `#include <stdarg.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+static char *gecko_str_alloc(size_t len) {
+	char *data = (char *)malloc(len + 1);
+	if (data == NULL) {
+		fprintf(stderr, "gecko: out of memory\n");
+		abort();
+	}
+	data[len] = '\0';
+	return data;
+}
+
+gecko_string gecko_str_lit(const char *str) {
+	gecko_string s;
+	s.data = (char *)str;
+	s.len = strlen(str);
+	return s;
+}
+
+gecko_string gecko_str_new(const char *data, size_t len) {
+	gecko_string s;
+	s.data = gecko_str_alloc(len);
+	s.len = len;
+	memcpy(s.data, data, len);
+	return s;
+}
+
+gecko_string gecko_str_concat(gecko_string a, gecko_string b) {
+	gecko_string s;
+	s.len = a.len + b.len;
+	s.data = gecko_str_alloc(s.len);
+	memcpy(s.data, a.data, a.len);
+	memcpy(s.data + a.len, b.data, b.len);
+	return s;
+}
+
+gecko_string gecko_str_format(const char *format, ...) {
+	gecko_string s;
+	va_list args;
+	va_list copy;
+	int len;
+
+	va_start(args, format);
+	va_copy(copy, args);
+	len = vsnprintf(NULL, 0, format, copy);
+	va_end(copy);
+
+	if (len < 0) {
+		len = 0;
+	}
+
+	s.len = (size_t)len;
+	s.data = gecko_str_alloc(s.len);
+	vsnprintf(s.data, s.len + 1, format, args);
+	va_end(args);
+
+	return s;
+}
+
+int gecko_str_eq(gecko_string a, gecko_string b) {
+	return a.len == b.len && memcmp(a.data, b.data, a.len) == 0;
+}
+
+int gecko_str_cmp(gecko_string a, gecko_string b) {
+	size_t len = a.len < b.len ? a.len : b.len;
+	int r = memcmp(a.data, b.data, len);
+
+	if (r != 0) {
+		return r;
+	}
+
+	return a.len < b.len ? -1 : (a.len > b.len ? 1 : 0);
+}
+
+gecko_list gecko_str_args(int argc, char **argv) {
+	gecko_list args = gecko_list_new(sizeof(gecko_string));
+	int i;
+
+	for (i = 0; i < argc; i++) {
+		gecko_string arg = gecko_str_lit(argv[i]);
+		gecko_list_push(args, &arg);
+	}
+
+	return args;
+}
+`,
+}
@@ -68,8 +68,29 @@ func (l *Logger) DebugLog(params ...interface{}) {
 	}
 }
 
+// FatalError : The panic raised by Fatal. Code that embeds gecko recovers it, the CLI exits with it
+type FatalError struct {
+	Channel string
+	Message string
+}
+
+func (f *FatalError) Error() string {
+	return f.Channel + ": " + f.Message
+}
+
+// Fatal : Logs params and stops whatever gecko was doing by panicking with a *FatalError
 func (l *Logger) Fatal(params ...string) {
 	l.LogString(params...)
 
-	os.Exit(1)
+	panic(&FatalError{Channel: l.channel, Message: strings.Join(params, " ")})
+}
+
+// ExitOnFatal : Exits the process if it is panicking because of Fatal. Deferred by main
+func ExitOnFatal() {
+	if r := recover(); r != nil {
+		if _, ok := r.(*FatalError); ok {
+			os.Exit(1)
+		}
+		panic(r)
+	}
 }
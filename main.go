@@ -45,6 +45,8 @@ func main() {
 
 	//flags
 
+	defer logger.ExitOnFatal()
+
 	logger.SetDefaultChannel("Gecko")
 
 	cmd := &commander.Commander{
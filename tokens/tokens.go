@@ -123,6 +123,7 @@ type Unary struct {
 	Op      string   `  ( @( "!" | "-" | "+" )`
 	Unary   *Unary   `    @@ )`
 	Primary *Primary `| @@`
+	Cast    *TypeRef `  [ "as" @@ ]`
 }
 
 type Primary struct {
@@ -131,6 +132,7 @@ type Primary struct {
 	Bool          string      `| ( @"true" | @"false" )`
 	Nil           *bool       `| @"nil"`
 	String        string      `| @String`
+	Char          string      `| @Char`
 	Symbol        string      `| @Ident`
 	Number        string      `| @Number`
 	SubExpression *Expression `| "(" @@ ")" `
@@ -160,9 +162,10 @@ type Type struct {
 type Field struct {
 	baseToken
 	Visibility string   `[ @"private" | @"public" | @"protected" | @"external" ]`
+	Mutability string   `[ @"const" | @"let" ]`
 	Name       string   `@Ident`
-	Type       *TypeRef `":" @@`
-	Value      *Literal `[ "=" @@ ]`
+	Type       *TypeRef `":" ( @@`
+	Value      *Literal `  [ "=" @@ ] | "=" @@ )`
 }
 
 type Assignment struct {
@@ -205,11 +208,18 @@ type Argument struct {
 type TypeRef struct {
 	baseToken
 	Array       *TypeRef `(   "[" @@ "]"`
+	Map         *MapType `  | @@`
 	Type        string   `  | @Ident )`
 	NonNullable bool     `[ @"!" ]`
 	Pointer     bool     `[ @"*" ]`
 }
 
+type MapType struct {
+	baseToken
+	Key   *TypeRef `"map" "[" @@ "]"`
+	Value *TypeRef `@@`
+}
+
 type Literal struct {
 	baseToken
 	FuncCall   *FuncCall         `( @@`
@@ -217,10 +227,13 @@ type Literal struct {
 	Nil        *bool             ` | @"nil"`
 	Expression *Expression       ` | @@`
 	String     string            ` | @String`
+	Char       string            ` | @Char`
 	Symbol     string            ` | @Ident`
 	Number     string            ` | @Number`
-	Object     []*ObjectKeyValue ` | "{" [ @@ { "," @@ } ] "}"`
-	Array      []*Literal        ` | "[" [ @@ { "," @@ } ] "]" )`
+	Object     []*ObjectKeyValue ` | "{" [ @@ { "," @@ } ]`
+	Braces     bool              `   @"}"` // Set for object literals, it tells "{}" apart from other literals
+	Brackets   bool              ` | @"["` // Set for list literals, it tells "[]" apart from other literals
+	Array      []*Literal        `   [ @@ { "," @@ } ] "]" )`
 	ArrayIndex *Literal          `[ "[" @@ "]" ]`
 }
 
@@ -236,27 +249,23 @@ type Object struct {
 
 type ObjectKeyValue struct {
 	baseToken
-	Key   string   `@Ident ":"`
-	Value *Literal `@@`
+	Key    string   `( @Ident`
+	Number string   ` | @( "-"? Number ) ) ":"`
+	Value  *Literal `@@`
 }
 
 type Loop struct {
 	baseToken
-	For           string      `"for"`
-	ForOf         *ForOfLoop  `( @@`
-	ForIn         *ForInLoop  ` | @@`
-	ForExpression *Expression ` | @@ )`
-	Value         []*Entry    ` "{" @@* "}" `
-}
-
-type ForOfLoop struct {
-	baseToken
-	Variable    *Field   `@@ "of"`
-	SourceArray *Literal `@@`
+	For           string        `"for"`
+	Iterator      *IteratorLoop `( @@`
+	ForExpression *Expression   ` | @@ )`
+	Value         []*Entry      ` "{" @@* "}" `
 }
 
-type ForInLoop struct {
+// IteratorLoop : "for x: T of list" walks values, "for k: T in map" walks keys
+type IteratorLoop struct {
 	baseToken
-	Variable    *Field   `@@ "in"`
+	Variable    *Field   `@@`
+	Kind        string   `@( "of" | "in" )`
 	SourceArray *Literal `@@`
 }
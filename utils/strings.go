@@ -1,9 +1,113 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 )
 
+var charEscapes = map[byte]byte{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'\\': '\\',
+	'\'': '\'',
+	'"':  '"',
+}
+
 func ParseString(v string) string {
 	return strings.ReplaceAll(v[1:len(v)-1], "\\", "")
 }
+
+// ParseChar : Converts a character literal token like 'a' or '\n' into its byte value
+func ParseChar(v string) (byte, error) {
+	s := v[1 : len(v)-1]
+
+	if len(s) == 1 && s[0] != '\\' {
+		return s[0], nil
+	} else if len(s) == 2 && s[0] == '\\' {
+		if c, ok := charEscapes[s[1]]; ok {
+			return c, nil
+		}
+	} else if len(s) > 2 && s[0] == '\\' && s[1] == 'x' {
+		c, err := strconv.ParseUint(s[2:], 16, 8)
+		if err == nil {
+			return byte(c), nil
+		}
+	}
+
+	return 0, errors.New("invalid character literal " + v)
+}
+
+// FormatChar : Formats a byte as a C character literal
+func FormatChar(c byte) string {
+	for escape, value := range charEscapes {
+		if value == c && escape != '"' {
+			return "'\\" + string(escape) + "'"
+		}
+	}
+
+	if c < ' ' || c > '~' {
+		return fmt.Sprintf("'\\x%02x'", c)
+	}
+
+	return "'" + string(c) + "'"
+}
+
+// EditDistance : Returns the number of single character edits needed to turn a into b
+func EditDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// ClosestMatch : Returns the candidate that is closest to name, or "" if none of them are close
+// enough to be what was meant
+func ClosestMatch(name string, candidates []string) string {
+	// Names of one or two characters are a couple of edits away from any other short name
+	if len(name) <= 2 {
+		return ""
+	}
+
+	// A third of the name may be wrong
+	best := ""
+	bestDistance := len(name)/3 + 1
+
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue
+		}
+
+		distance := EditDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if distance < bestDistance || distance == bestDistance && len(best) > 0 && candidate < best {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+
+	return best
+}
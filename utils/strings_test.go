@@ -0,0 +1,47 @@
+package utils
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"count", "count", 0},
+		{"", "abc", 3},
+		{"countr", "counter", 1},
+		{"cuont", "count", 2},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, test := range tests {
+		if distance := EditDistance(test.a, test.b); distance != test.distance {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", test.a, test.b, distance, test.distance)
+		}
+	}
+}
+
+func TestClosestMatch(t *testing.T) {
+	candidates := []string{"counter", "count", "total", "printf", "x", "ab"}
+	tests := []struct {
+		name  string
+		match string
+	}{
+		{"countr", "count"},
+		{"countex", "counter"},
+		{"Total", "total"},
+		{"prntf", "printf"},
+		{"cuont", ""},
+		{"width", ""},
+		{"y", ""},
+		{"ac", ""},
+		{"count", ""},
+	}
+
+	for _, test := range tests {
+		if match := ClosestMatch(test.name, candidates); match != test.match {
+			t.Errorf("ClosestMatch(%q) = %q, want %q", test.name, match, test.match)
+		}
+	}
+}
@@ -47,3 +47,18 @@ func ResolveVariable(scope *ast.Ast, symbol string) *ast.Variable {
 
 	return variable
 }
+
+// SimilarSymbol : Returns the variable or method visible from scope whose name is closest to name
+func SimilarSymbol(scope *ast.Ast, name string) string {
+	candidates := []string{}
+	for s := scope; s != nil; s = s.Parent {
+		for variable := range s.Variables {
+			candidates = append(candidates, variable)
+		}
+		for method := range s.Methods {
+			candidates = append(candidates, method)
+		}
+	}
+
+	return ClosestMatch(name, candidates)
+}